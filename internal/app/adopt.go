@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/manifest"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/pterm/pterm"
+	"github.com/samber/lo"
+)
+
+// Unmanaged returns the packages installed on the system that are not in any
+// section of the manifest, neither as package nor as dependency
+func (a *App) Unmanaged(ctx context.Context, managerName shared.ManagerName) ([]shared.Package, error) {
+	manager, err := a.Managers.GetManager(managerName)
+	if err != nil {
+		return nil, err
+	}
+
+	installed, err := manager.ListInstalledPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, dependencies := manifest.All(a.Manifest.Pm(managerName))
+	packages = shared.UnpinAll(append(packages, dependencies...))
+
+	return lo.Filter(installed, func(pkg shared.Package, _ int) bool {
+		return pkg.FullName != "" && !lo.Contains(packages, pkg.FullName)
+	}), nil
+}
+
+// Adopt adds packages installed outside of packtrak to the manifest. Unless
+// all or assumeyes is set the user picks the packages to adopt for every
+// manager.
+func (a *App) Adopt(ctx context.Context, managerNames []shared.ManagerName, host bool, group string, all bool) error {
+	adopted := 0
+	for _, managerName := range managerNames {
+		manager, err := a.Managers.GetManager(managerName)
+		if err != nil {
+			return err
+		}
+
+		unmanaged, err := a.Unmanaged(ctx, managerName)
+		if err != nil {
+			return err
+		}
+		if len(unmanaged) == 0 {
+			continue
+		}
+
+		candidates := lo.Uniq(lo.Map(unmanaged, func(pkg shared.Package, _ int) string {
+			return pkg.FullName
+		}))
+
+		selected := candidates
+		if !all && !*config.AssumeYes {
+			if selected, err = selectPackages(fmt.Sprintf("Select %s packages to adopt", managerName), candidates); err != nil {
+				return err
+			}
+		}
+		if len(selected) == 0 {
+			continue
+		}
+
+		n, err := a.adoptPackages(ctx, manager, selected, host, group)
+		if err != nil {
+			return err
+		}
+		adopted += n
+	}
+
+	if adopted == 0 {
+		shared.PtermInstalled.Println("No unmanaged packages found")
+		return nil
+	}
+	return a.saveAdopted(adopted)
+}
+
+// adoptPackages adds pkgs to the manifest section of the manager without
+// saving it, and returns the number of packages added
+func (a *App) adoptPackages(ctx context.Context, manager managers.Manager, pkgs []string, host bool, group string) (int, error) {
+	toAdd, userWarnings, err := manager.AddPackages(ctx, pkgs)
+	if err != nil {
+		return 0, err
+	}
+	for _, uw := range userWarnings {
+		shared.PtermWarning.Println(uw)
+	}
+
+	for _, pkg := range toAdd {
+		shared.PtermInstalled.Printfln("%s %s", manager.Icon(), pkg)
+	}
+
+	if host {
+		err = a.Manifest.AddToHost(toAdd, manager.Name(), manifest.TypePackage)
+	} else if group != "" {
+		err = a.Manifest.AddToGroup(toAdd, group, manager.Name(), manifest.TypePackage)
+	} else {
+		err = a.Manifest.AddGlobal(manifest.TypePackage, manager.Name(), toAdd)
+	}
+	return len(toAdd), err
+}
+
+func (a *App) saveAdopted(adopted int) error {
+	if config.DryRun {
+		fmt.Printf("\n%d packages would be added to the manifest\n", adopted)
+		return nil
+	}
+
+	fmt.Printf("\n%d packages added to the manifest\n", adopted)
+	return a.Manifest.Save()
+}
+
+func selectPackages(text string, candidates []string) ([]string, error) {
+	return pterm.DefaultInteractiveMultiselect.
+		WithOptions(candidates).
+		WithMaxHeight(15).
+		Show(text)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/lucas-ingemar/packtrak/internal/config"
 	"github.com/lucas-ingemar/packtrak/internal/managers"
@@ -18,6 +19,8 @@ import (
 )
 
 type AppFace interface {
+	Adopt(ctx context.Context, managerNames []shared.ManagerName, host bool, group string, all bool) error
+	Drift(ctx context.Context, managerNames []shared.ManagerName, action DriftAction, host bool, group string) error
 	Install(ctx context.Context, apkgs []string, managerName shared.ManagerName, mType manifest.ManifestObjectType, host bool, group string) error
 	InstallValidArgsFunc(ctx context.Context, managerName shared.ManagerName, toComplete string, mType manifest.ManifestObjectType) (pkgs []string, err error)
 	ListStatus(ctx context.Context, managerNames []shared.ManagerName) (status.Status, error)
@@ -25,6 +28,12 @@ type AppFace interface {
 	RemoveValidArgsFunc(ctx context.Context, toComplete string, managerName shared.ManagerName, mType manifest.ManifestObjectType) ([]string, error)
 	Sync(ctx context.Context, managerNames []shared.ManagerName) (err error)
 	PrintPackageList(s status.Status) error
+	PrintHistory(ctx context.Context, managerName shared.ManagerName, since time.Time, output string) error
+	PrintSnapshots(output string) error
+	DiffSnapshot(ctx context.Context, name string) error
+	RestoreSnapshot(ctx context.Context, name string, sync bool) error
+	PrintPlan(ctx context.Context, s status.Status, managerNames []shared.ManagerName) error
+	PrintReport(s status.Status, managerNames []shared.ManagerName, output string) error
 	ListManagers() []shared.ManagerName
 	mustDoSudo(ctx context.Context, managers []shared.ManagerName, cmd shared.CommandName) (success bool)
 }
@@ -32,6 +41,7 @@ type AppFace interface {
 type App struct {
 	Managers managers.ManagerFactoryFace
 	Manifest manifest.ManifestFace
+	Lock     manifest.LockFace
 	State    state.StateFace
 
 	isSudo bool
@@ -87,10 +97,11 @@ func (a *App) ListManagers() []shared.ManagerName {
 	return a.Managers.ListManagers()
 }
 
-func NewApp(managers managers.ManagerFactoryFace, manifest manifest.ManifestFace, state state.StateFace) *App {
+func NewApp(managers managers.ManagerFactoryFace, manifest manifest.ManifestFace, lock manifest.LockFace, state state.StateFace) *App {
 	return &App{
 		Managers: managers,
 		Manifest: manifest,
+		Lock:     lock,
 		State:    state,
 	}
 }
@@ -0,0 +1,217 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/manifest"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/state"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/pterm/pterm"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// syncLog records the packages synced by all fake managers, in order
+type syncLog struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *syncLog) add(entry string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+func (l *syncLog) index(entry string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return lo.IndexOf(l.entries, entry)
+}
+
+// fakeManager keeps its installed packages in memory and syncs them
+// concurrently. Packages in failing fail to install, and the ones in pending
+// are left for later.
+type fakeManager struct {
+	name    shared.ManagerName
+	failing []string
+	pending []string
+	log     *syncLog
+
+	mu        sync.Mutex
+	installed map[string]bool
+	// caching keeps the first listing of the installed packages until a
+	// package is synced, like the command executors of e.g. dnf
+	caching bool
+	cache   map[string]bool
+}
+
+func newFakeManager(name shared.ManagerName, log *syncLog, installed ...string) *fakeManager {
+	m := &fakeManager{name: name, log: log, installed: map[string]bool{}}
+	for _, pkg := range installed {
+		m.installed[pkg] = true
+	}
+	return m
+}
+
+func (m *fakeManager) isInstalled(pkg string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.caching {
+		return m.installed[pkg]
+	}
+	if m.cache == nil {
+		m.cache = maps.Clone(m.installed)
+	}
+	return m.cache[pkg]
+}
+
+func (m *fakeManager) Name() shared.ManagerName        { return m.name }
+func (m *fakeManager) Icon() string                    { return "" }
+func (m *fakeManager) ShortDesc() string               { return "" }
+func (m *fakeManager) LongDesc() string                { return "" }
+func (m *fakeManager) NeedsSudo() []shared.CommandName { return nil }
+func (m *fakeManager) InitConfig()                     {}
+func (m *fakeManager) InitCheckCmd() error             { return nil }
+func (m *fakeManager) InitCheckConfig() error          { return nil }
+
+func (m *fakeManager) GetPackageNames(ctx context.Context, packages []string) []string {
+	return packages
+}
+
+func (m *fakeManager) GetDependencyNames(ctx context.Context, deps []string) []string {
+	return deps
+}
+
+func (m *fakeManager) InstallValidArgs(ctx context.Context, toComplete string, dependencies bool) ([]string, error) {
+	return nil, nil
+}
+
+func (m *fakeManager) AddPackages(ctx context.Context, pkgsToAdd []string) ([]string, []string, error) {
+	return pkgsToAdd, nil, nil
+}
+
+func (m *fakeManager) AddDependencies(ctx context.Context, depsToAdd []string) ([]string, []string, error) {
+	return depsToAdd, nil, nil
+}
+
+func (m *fakeManager) ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error) {
+	for _, dep := range deps {
+		depStatus.Synced = append(depStatus.Synced, shared.Dependency{Name: dep, FullName: dep})
+	}
+	return
+}
+
+func (m *fakeManager) ListPackages(ctx context.Context, packages []string, statePkgs []string) (pkgStatus status.PackageStatus, err error) {
+	for _, pkg := range packages {
+		if m.isInstalled(pkg) {
+			pkgStatus.Synced = append(pkgStatus.Synced, shared.Package{Name: pkg, FullName: pkg, Version: "1.0"})
+		} else {
+			// Like dnf, the version is only known once installed
+			pkgStatus.Missing = append(pkgStatus.Missing, shared.Package{Name: pkg, FullName: pkg})
+		}
+	}
+	for _, pkg := range statePkgs {
+		if !lo.Contains(packages, pkg) {
+			pkgStatus.Removed = append(pkgStatus.Removed, shared.Package{Name: pkg, FullName: pkg})
+		}
+	}
+	return
+}
+
+func (m *fakeManager) ListInstalledPackages(ctx context.Context) ([]shared.Package, error) {
+	return nil, nil
+}
+
+func (m *fakeManager) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRemove []string) ([]string, []string, error) {
+	return pkgsToRemove, nil, nil
+}
+
+func (m *fakeManager) RemoveDependencies(ctx context.Context, allDeps []string, depsToRemove []string) ([]string, []string, error) {
+	return depsToRemove, nil, nil
+}
+
+func (m *fakeManager) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) ([]shared.SyncFailure, []string, error) {
+	return nil, nil, nil
+}
+
+func (m *fakeManager) SyncPackages(ctx context.Context, pkgStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	for _, job := range newSyncJobs(m, m.name, pkgStatus) {
+		if err := m.SyncPackage(ctx, job.pkg, job.action); err != nil {
+			failures = append(failures, shared.SyncFailure{Manager: m.name, Name: job.pkg.Name, Action: job.action, Err: err})
+		}
+	}
+	return
+}
+
+func (m *fakeManager) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) ([]string, error) {
+	return nil, nil
+}
+
+func (m *fakeManager) PlanPackages(ctx context.Context, pkgStatus status.PackageStatus) ([]string, error) {
+	return nil, nil
+}
+
+func (m *fakeManager) CanSyncConcurrently() bool {
+	return true
+}
+
+func (m *fakeManager) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
+	if lo.Contains(m.failing, pkg.Name) {
+		return errors.New("failed")
+	}
+	if lo.Contains(m.pending, pkg.Name) {
+		return shared.ErrSyncPending
+	}
+	m.mu.Lock()
+	m.installed[pkg.Name] = action != shared.PtermSpinnerRemove
+	m.cache = nil
+	m.mu.Unlock()
+	m.log.add(string(m.name) + ":" + pkg.Name)
+	return nil
+}
+
+// newTestApp returns an app with the fake managers, the manifest and the
+// state in a temporary directory
+func newTestApp(t *testing.T, manifestYaml string, fakes ...managers.Manager) *App {
+	dir := t.TempDir()
+	config.DataDir = dir
+	config.StateFile = filepath.Join(dir, "state.db")
+	config.ManifestFile = filepath.Join(dir, "manifest.yaml")
+	config.LockFile = filepath.Join(dir, "manifest.lock")
+	config.ManifestGroupFiles = map[string]string{}
+	config.StateRotations = 0
+	assumeYes := true
+	config.AssumeYes = &assumeYes
+
+	registered := managers.ManagersRegistered
+	managers.ManagersRegistered = fakes
+	t.Cleanup(func() { managers.ManagersRegistered = registered })
+
+	pterm.DisableOutput()
+	t.Cleanup(pterm.EnableOutput)
+
+	require.NoError(t, os.WriteFile(config.ManifestFile, []byte(manifestYaml), 0644))
+	m, err := manifest.InitManifest()
+	require.NoError(t, err)
+	lock, err := manifest.InitLock()
+	require.NoError(t, err)
+
+	db, err := gorm.Open(sqlite.Open(config.StateFile), &gorm.Config{})
+	require.NoError(t, err)
+	s, err := state.NewState(db)
+	require.NoError(t, err)
+
+	return NewApp(managers.InitManagerFactory(fakes, false), m, lock, s)
+}
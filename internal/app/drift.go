@@ -0,0 +1,274 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/manifest"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/pterm/pterm"
+	"github.com/samber/lo"
+)
+
+// ErrDrift is returned by Drift with DriftCheck when drift was found
+var ErrDrift = errors.New("drift found")
+
+type DriftAction string
+
+const (
+	// DriftAsk asks for every manager what to do with the drift
+	DriftAsk    DriftAction = ""
+	DriftCheck  DriftAction = "check"
+	DriftAdopt  DriftAction = "adopt"
+	DriftRemove DriftAction = "remove"
+)
+
+const driftSkip = "skip"
+
+// driftReport holds what a manager has installed that is neither in the
+// manifest nor in the state
+type driftReport struct {
+	manager  managers.Manager
+	packages []string
+	files    []string
+}
+
+func (d driftReport) empty() bool {
+	return len(d.packages) == 0 && len(d.files) == 0
+}
+
+// Drift reports packages and files on the system that packtrak doesn't know
+// about, and adopts or removes them depending on action
+func (a *App) Drift(ctx context.Context, managerNames []shared.ManagerName, action DriftAction, host bool, group string) error {
+	reports := []driftReport{}
+	for _, managerName := range managerNames {
+		report, err := a.listDrift(ctx, managerName)
+		if err != nil {
+			return err
+		}
+		if !report.empty() {
+			reports = append(reports, report)
+		}
+	}
+
+	if len(reports) == 0 {
+		shared.PtermGreen.Println("No drift found")
+		return nil
+	}
+
+	printDrift(reports)
+
+	switch action {
+	case DriftCheck:
+		return ErrDrift
+	case DriftAsk:
+		if *config.AssumeYes {
+			return nil
+		}
+	case DriftRemove:
+		if !config.DryRun && !*config.AssumeYes && !confirm("Do you want to remove all drifted packages and files?") {
+			return nil
+		}
+	}
+
+	adopted := 0
+	for _, report := range reports {
+		reportAction := action
+		if reportAction == DriftAsk {
+			var err error
+			if reportAction, err = selectDriftAction(report); err != nil {
+				return err
+			}
+		}
+
+		switch reportAction {
+		case DriftAdopt:
+			pkgs, err := selectDrift(report, action == DriftAsk, reportAction, report.packages)
+			if err != nil {
+				return err
+			}
+			for _, file := range report.files {
+				shared.PtermWarning.Printfln("%s can't be adopted, it doesn't belong to a package", file)
+			}
+			if len(pkgs) == 0 {
+				continue
+			}
+			n, err := a.adoptPackages(ctx, report.manager, pkgs, host, group)
+			if err != nil {
+				return err
+			}
+			adopted += n
+		case DriftRemove:
+			pkgs, err := selectDrift(report, action == DriftAsk, reportAction, report.packages)
+			if err != nil {
+				return err
+			}
+			files, err := selectDrift(report, action == DriftAsk, reportAction, report.files)
+			if err != nil {
+				return err
+			}
+			if err = a.removeDrift(ctx, report.manager, pkgs, files); err != nil {
+				return err
+			}
+		}
+	}
+
+	if adopted > 0 {
+		return a.saveAdopted(adopted)
+	}
+	return nil
+}
+
+// listDrift returns the packages of the manager that are installed but
+// neither in the manifest nor in the state, together with stray files for
+// managers owning files
+func (a *App) listDrift(ctx context.Context, managerName shared.ManagerName) (report driftReport, err error) {
+	if report.manager, err = a.Managers.GetManager(managerName); err != nil {
+		return
+	}
+
+	unmanaged, err := a.Unmanaged(ctx, managerName)
+	if err != nil {
+		return
+	}
+
+	statePkgObjs, err := a.State.GetPackageState(ctx, managerName)
+	if err != nil {
+		return
+	}
+	statePkgs := shared.FullNames(statePkgObjs)
+	stateDeps, err := a.State.GetDependencyState(ctx, managerName)
+	if err != nil {
+		return
+	}
+
+	for _, pkg := range unmanaged {
+		if !lo.Contains(statePkgs, pkg.FullName) && !lo.Contains(stateDeps, pkg.FullName) {
+			report.packages = append(report.packages, pkg.FullName)
+		}
+	}
+	report.packages = lo.Uniq(report.packages)
+
+	if sfm, ok := report.manager.(managers.StrayFileManager); ok {
+		packages, _ := manifest.All(a.Manifest.Pm(managerName))
+		known := lo.Uniq(append(shared.UnpinAll(packages), statePkgs...))
+		if report.files, err = sfm.ListStrayFiles(ctx, known); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (a *App) removeDrift(ctx context.Context, manager managers.Manager, pkgs []string, files []string) error {
+	pkgStatus, err := a.withHooks(manager.Name(), status.PackageStatus{
+		Removed: lo.Map(pkgs, func(pkg string, _ int) shared.Package {
+			return shared.Package{Name: pkg, FullName: pkg}
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	if config.DryRun {
+		actions, err := manager.PlanPackages(ctx, pkgStatus)
+		if err != nil {
+			return err
+		}
+		actions = append(actions, hookActions(pkgStatus)...)
+		for _, file := range files {
+			actions = append(actions, fmt.Sprintf("rm %s", file))
+		}
+		for _, action := range actions {
+			fmt.Printf("%s %s\n", manager.Icon(), action)
+		}
+		return nil
+	}
+
+	if len(pkgs) > 0 {
+		if !a.mustDoSudo(ctx, []shared.ManagerName{manager.Name()}, shared.CommandRemove) {
+			return errors.New("sudo access not granted")
+		}
+		failures, userWarnings, err := manager.SyncPackages(ctx, pkgStatus)
+		if err != nil {
+			return err
+		}
+		if err = a.State.AddHistory(ctx, packageHistory(manager.Name(), pkgStatus, failures)); err != nil {
+			return err
+		}
+		for _, uw := range userWarnings {
+			shared.PtermWarning.Println(uw)
+		}
+		if len(failures) > 0 {
+			a.printSyncFailures(failures)
+			return fmt.Errorf("%w: %d of %d removals failed", ErrPartialSync, len(failures), len(pkgs))
+		}
+	}
+
+	if len(files) > 0 {
+		sfm, ok := manager.(managers.StrayFileManager)
+		if !ok {
+			return fmt.Errorf("%s can't remove files", manager.Name())
+		}
+		if err := sfm.RemoveStrayFiles(ctx, files); err != nil {
+			return err
+		}
+		for _, file := range files {
+			shared.PtermRemoved.Printfln("%s %s", manager.Icon(), file)
+		}
+	}
+	return nil
+}
+
+func printDrift(reports []driftReport) {
+	total := 0
+	fmt.Println("\nNot tracked by packtrak:")
+	for _, report := range reports {
+		for _, pkg := range report.packages {
+			shared.PtermUpdated.Printfln("%s %s", report.manager.Icon(), pkg)
+		}
+		for _, file := range report.files {
+			shared.PtermUpdated.Printfln("%s %s", report.manager.Icon(), file)
+		}
+		total += len(report.packages) + len(report.files)
+	}
+	fmt.Println("\n" + shared.PtermUpdated.Sprintf("%d drifted", total))
+}
+
+func selectDriftAction(report driftReport) (DriftAction, error) {
+	options := []string{string(DriftAdopt), string(DriftRemove), driftSkip}
+	if len(report.packages) == 0 {
+		options = []string{string(DriftRemove), driftSkip}
+	}
+	result, err := pterm.DefaultInteractiveSelect.
+		WithOptions(options).
+		WithDefaultOption(driftSkip).
+		Show(fmt.Sprintf("What do you want to do with the %s drift?", report.manager.Name()))
+	return DriftAction(result), err
+}
+
+// selectDrift lets the user pick among items when asking interactively.
+// Otherwise every item is selected.
+func selectDrift(report driftReport, ask bool, action DriftAction, items []string) ([]string, error) {
+	if !ask || len(items) == 0 {
+		return items, nil
+	}
+	return selectPackages(fmt.Sprintf("Select %s drift to %s", report.manager.Name(), action), items)
+}
+
+func confirm(text string) bool {
+	fmt.Println("")
+	result, _ := pterm.InteractiveContinuePrinter{
+		DefaultValueIndex: 0,
+		DefaultText:       text,
+		TextStyle:         &pterm.ThemeDefault.PrimaryStyle,
+		Options:           []string{"y", "n"},
+		OptionsStyle:      &pterm.ThemeDefault.SuccessMessageStyle,
+		SuffixStyle:       &pterm.ThemeDefault.SecondaryStyle,
+		Delimiter:         ": ",
+	}.Show()
+	return result == "y"
+}
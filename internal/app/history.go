@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/state"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/pterm/pterm"
+	"github.com/samber/lo"
+)
+
+const historyTimeFormat = "2006-01-02 15:04:05"
+
+// PrintHistory prints what syncs have installed, updated and removed since
+// the given time. An empty managerName prints all managers. The manager may be
+// disabled, or no longer exist as long as it has a history.
+func (a *App) PrintHistory(ctx context.Context, managerName shared.ManagerName, since time.Time, output string) error {
+	entries, err := a.State.GetHistory(ctx, managerName, since)
+	if err != nil {
+		return err
+	}
+
+	if managerName != "" && len(entries) == 0 && !lo.Contains(managers.RegisteredNames(), managerName) {
+		all, err := a.State.GetHistory(ctx, managerName, time.Time{})
+		if err != nil {
+			return err
+		}
+		if len(all) == 0 {
+			return fmt.Errorf("manager '%s' not found", managerName)
+		}
+	}
+
+	if output != "" {
+		return printOutput(entries, output)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No history found")
+		return nil
+	}
+
+	data := pterm.TableData{{"Time", "Manager", "Action", "Name", "Version", "Outcome"}}
+	for _, e := range entries {
+		version := e.NewVersion
+		if e.OldVersion != "" && e.NewVersion != "" {
+			version = fmt.Sprintf("%s -> %s", e.OldVersion, e.NewVersion)
+		} else if e.NewVersion == "" {
+			version = e.OldVersion
+		}
+
+		outcome := pterm.Green(e.Outcome)
+		if e.Outcome == state.HistoryFailed {
+			outcome = pterm.Red(fmt.Sprintf("%s: %s", e.Outcome, e.Error))
+		}
+
+		name := e.Name
+		if e.ObjectType == state.HistoryDependency {
+			name = fmt.Sprintf("%s (dependency)", name)
+		}
+
+		data = append(data, []string{
+			e.CreatedAt.Local().Format(historyTimeFormat),
+			e.Manager,
+			string(e.Action),
+			name,
+			version,
+			outcome,
+		})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
+
+// packageHistory returns a history entry for every package the sync touched.
+// Failures are matched on name and action, and pending packages are left out.
+func packageHistory(managerName shared.ManagerName, pkgStatus status.PackageStatus, failures []shared.SyncFailure) []state.HistoryEntry {
+	entries := []state.HistoryEntry{}
+	add := func(pkgs []shared.Package, action shared.PtermSpinnerStatus) {
+		for _, pkg := range pkgs {
+			if isPendingName(failures, pkg.Name) {
+				continue
+			}
+			entry := newHistoryEntry(managerName, state.HistoryPackage, pkg.Name, action, failures)
+			switch action {
+			case shared.PtermSpinnerInstall:
+				entry.NewVersion = pkg.LatestVersion
+			case shared.PtermSpinnerUpdate:
+				entry.OldVersion, entry.NewVersion = pkg.Version, pkg.LatestVersion
+			case shared.PtermSpinnerRemove:
+				entry.OldVersion = pkg.Version
+			}
+			entries = append(entries, entry)
+		}
+	}
+	add(pkgStatus.Missing, shared.PtermSpinnerInstall)
+	add(pkgStatus.Updated, shared.PtermSpinnerUpdate)
+	add(pkgStatus.Removed, shared.PtermSpinnerRemove)
+	return entries
+}
+
+// dependencyHistory returns a history entry for every dependency the sync touched
+func dependencyHistory(managerName shared.ManagerName, depStatus status.DependenciesStatus, failures []shared.SyncFailure) []state.HistoryEntry {
+	entries := []state.HistoryEntry{}
+	add := func(deps []shared.Dependency, action shared.PtermSpinnerStatus) {
+		for _, dep := range deps {
+			if isPendingName(failures, dep.Name) {
+				continue
+			}
+			entries = append(entries, newHistoryEntry(managerName, state.HistoryDependency, dep.Name, action, failures))
+		}
+	}
+	add(depStatus.Missing, shared.PtermSpinnerInstall)
+	add(depStatus.Updated, shared.PtermSpinnerUpdate)
+	add(depStatus.Removed, shared.PtermSpinnerRemove)
+	return entries
+}
+
+func newHistoryEntry(managerName shared.ManagerName, oType state.HistoryObjectType, name string, action shared.PtermSpinnerStatus, failures []shared.SyncFailure) state.HistoryEntry {
+	entry := state.HistoryEntry{
+		Manager:    string(managerName),
+		ObjectType: oType,
+		Name:       name,
+		Action:     action,
+		Outcome:    state.HistorySuccess,
+	}
+	failure, failed := lo.Find(failures, func(f shared.SyncFailure) bool {
+		return f.Manager == managerName && f.Name == name && f.Action == action
+	})
+	if failed {
+		entry.Outcome = state.HistoryFailed
+		if failure.Err != nil {
+			entry.Error = failure.Err.Error()
+		}
+	}
+	return entry
+}
@@ -17,7 +17,7 @@ func (a *App) Install(ctx context.Context, apkgs []string, managerName shared.Ma
 		return error
 	}
 
-	if !a.mustDoSudo(ctx, []shared.ManagerName{managerName}, shared.CommandInstall) {
+	if !config.DryRun && !a.mustDoSudo(ctx, []shared.ManagerName{managerName}, shared.CommandInstall) {
 		return errors.New("sudo access not granted")
 	}
 
@@ -62,15 +62,23 @@ func (a *App) Install(ctx context.Context, apkgs []string, managerName shared.Ma
 		}
 	} else {
 		if err = a.Manifest.AddGlobal(mType, managerName, toAdd); err != nil {
-			return nil
+			return err
 		}
 	}
 
-	if err = a.Sync(ctx, []shared.ManagerName{manager.Name()}); err != nil {
-		return err
+	syncErr := a.Sync(ctx, []shared.ManagerName{manager.Name()})
+	if syncErr != nil && !errors.Is(syncErr, ErrPartialSync) {
+		return syncErr
+	}
+
+	if config.DryRun {
+		return nil
 	}
 
-	return a.Manifest.Save(config.ManifestFile)
+	if err = a.Manifest.Save(); err != nil {
+		return err
+	}
+	return syncErr
 }
 func (a *App) InstallValidArgsFunc(ctx context.Context, managerName shared.ManagerName, toComplete string, mType manifest.ManifestObjectType) (pkgs []string, err error) {
 	manager, err := a.Managers.GetManager(managerName)
@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"time"
 
+	"github.com/lucas-ingemar/packtrak/internal/config"
 	"github.com/lucas-ingemar/packtrak/internal/managers"
 	"github.com/lucas-ingemar/packtrak/internal/manifest"
 	"github.com/lucas-ingemar/packtrak/internal/shared"
@@ -27,15 +27,13 @@ func (a *App) ListStatus(ctx context.Context, managerNames []shared.ManagerName)
 
 	g, ctx := errgroup.WithContext(ctx)
 
-	multi := pterm.DefaultMultiPrinter
-	statusObj := status.Status{}
+	statusObj := status.NewStatus()
+	// The managers are listed concurrently, but pterm printers are not safe to
+	// use from several goroutines, so the progress is printed by this one. The
+	// channel fits every event, so a manager never waits for the printing.
+	events := make(chan listEvent, 2*len(ms))
 
 	for _, manager := range ms {
-		spinnerDep, _ := pterm.DefaultSpinner.WithWriter(multi.NewWriter()).Start(fmt.Sprintf("Listing %s dependencies...", manager.Name()))
-		spinnerDep.SuccessPrinter = &shared.PtermInstalled
-		spinnerPkg, _ := pterm.DefaultSpinner.WithWriter(multi.NewWriter()).Start(fmt.Sprintf("Listing %s packages...", manager.Name()))
-		spinnerPkg.SuccessPrinter = &shared.PtermInstalled
-
 		func(manager managers.Manager) {
 			g.Go(func() error {
 				packages, dependencies, err := manifest.Filter(a.Manifest.Pm(manager.Name()))
@@ -46,6 +44,13 @@ func (a *App) ListStatus(ctx context.Context, managerNames []shared.ManagerName)
 				packages = lo.Uniq(packages)
 				dependencies = lo.Uniq(dependencies)
 
+				if pm, ok := manager.(managers.PinManager); ok && pm.SupportsPins() && config.Locked {
+					packages, err = a.Lock.Pin(manager.Name(), packages)
+					if err != nil {
+						return err
+					}
+				}
+
 				stateDeps, err := a.State.GetDependencyState(ctx, manager.Name())
 				if err != nil {
 					return err
@@ -57,29 +62,93 @@ func (a *App) ListStatus(ctx context.Context, managerNames []shared.ManagerName)
 				}
 
 				statusObj.AddDependencies(manager.Name(), depStatus)
-				spinnerDep.Success(fmt.Sprintf("%s dependencies listed", manager.Name()))
+				events <- listEvent{&shared.PtermInstalled, fmt.Sprintf("%s dependencies listed", manager.Name())}
 
 				statePkgs, err := a.State.GetPackageState(ctx, manager.Name())
 				if err != nil {
 					return err
 				}
 
-				pkgStatus, err := manager.ListPackages(ctx, packages, statePkgs)
+				pkgStatus, err := manager.ListPackages(ctx, packages, shared.FullNames(statePkgs))
 				if err != nil {
-					return err
+					if len(statePkgs) == 0 {
+						return err
+					}
+					statusObj.AddStale(manager.Name(), err)
+					statusObj.AddPackages(manager.Name(), statePackageStatus(packages, statePkgs))
+					events <- listEvent{&shared.PtermWarning, fmt.Sprintf("%s packages read from the state: %s", manager.Name(), err)}
+					return nil
 				}
 
-				statusObj.AddPackages(manager.Name(), pkgStatus)
-				spinnerPkg.Success(fmt.Sprintf("%s packages listed", manager.Name()))
-
-				time.Sleep(200 * time.Millisecond)
+				statusObj.AddPackages(manager.Name(), withStateInfo(pkgStatus, statePkgs))
+				events <- listEvent{&shared.PtermInstalled, fmt.Sprintf("%s packages listed", manager.Name())}
 				return nil
 			})
 		}(manager)
 	}
-	multi.Start()
-	err = g.Wait()
-	multi.Stop()
+	go func() {
+		err = g.Wait()
+		close(events)
+	}()
+	for e := range events {
+		e.printer.Println(e.text)
+	}
 
 	return statusObj, err
 }
+
+// listEvent is a line of progress printed while the managers are listed
+type listEvent struct {
+	printer *pterm.PrefixPrinter
+	text    string
+}
+
+// withStateInfo adds what the state recorded about the installed packages,
+// like when they were installed, to the listed packages
+func withStateInfo(pkgStatus status.PackageStatus, statePkgs []shared.Package) status.PackageStatus {
+	addInfo := func(pkgs []shared.Package) []shared.Package {
+		return lo.Map(pkgs, func(pkg shared.Package, _ int) shared.Package {
+			statePkg, found := lo.Find(statePkgs, func(sp shared.Package) bool {
+				return sp.FullName == shared.Unpin(pkg.FullName)
+			})
+			if !found {
+				return pkg
+			}
+			if pkg.Version == "" {
+				pkg.Version = statePkg.Version
+			}
+			if pkg.RepoUrl == "" {
+				pkg.RepoUrl = statePkg.RepoUrl
+			}
+			if pkg.Version == statePkg.Version {
+				pkg.InstalledAt = statePkg.InstalledAt
+			}
+			return pkg
+		})
+	}
+	pkgStatus.Synced = addInfo(pkgStatus.Synced)
+	pkgStatus.Updated = addInfo(pkgStatus.Updated)
+	pkgStatus.Removed = addInfo(pkgStatus.Removed)
+	return pkgStatus
+}
+
+// statePackageStatus returns the package status as recorded in the state, for
+// when the manager can't list the packages, e.g. when offline
+func statePackageStatus(packages []string, statePkgs []shared.Package) (pkgStatus status.PackageStatus) {
+	unpinned := shared.UnpinAll(packages)
+	stateNames := shared.FullNames(statePkgs)
+
+	for _, pkg := range statePkgs {
+		if lo.Contains(unpinned, pkg.FullName) {
+			pkgStatus.Synced = append(pkgStatus.Synced, pkg)
+		} else {
+			pkgStatus.Removed = append(pkgStatus.Removed, pkg)
+		}
+	}
+	for _, pkg := range lo.Uniq(unpinned) {
+		if !lo.Contains(stateNames, pkg) {
+			pkgStatus.Missing = append(pkgStatus.Missing, shared.Package{Name: pkg, FullName: pkg})
+		}
+	}
+	return
+}
@@ -0,0 +1,141 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/manifest"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/pterm/pterm"
+	"github.com/samber/lo"
+)
+
+// syncJob is the install, update or removal of a single package by a manager
+// syncing concurrently
+type syncJob struct {
+	manager managers.ConcurrentManager
+	name    shared.ManagerName
+	pkg     shared.Package
+	action  shared.PtermSpinnerStatus
+}
+
+// canSyncConcurrently tells if the packages of the manager are synced by the
+// worker pool, which needs more than one job
+func (a *App) canSyncConcurrently(managerName shared.ManagerName) bool {
+	if config.Jobs <= 1 {
+		return false
+	}
+	manager, err := a.Managers.GetManager(managerName)
+	if err != nil {
+		return false
+	}
+	cm, ok := manager.(managers.ConcurrentManager)
+	return ok && cm.CanSyncConcurrently()
+}
+
+// syncWaves groups the batches into waves synced one after the other. A
+// wave is either a single batch, or consecutive batches of managers syncing
+// concurrently where no batch requires packages of another.
+func (g requiresGraph) syncWaves(batches []syncBatch, concurrent func(shared.ManagerName) bool) [][]syncBatch {
+	waves := [][]syncBatch{}
+	for _, batch := range batches {
+		if len(waves) > 0 && concurrent(batch.manager) {
+			wave := waves[len(waves)-1]
+			if concurrent(wave[0].manager) && !g.requiresAny(batch, wave) {
+				waves[len(waves)-1] = append(wave, batch)
+				continue
+			}
+		}
+		waves = append(waves, []syncBatch{batch})
+	}
+	return waves
+}
+
+// requiresAny tells if a package of the batch requires a package of the wave,
+// or if the wave already holds a batch of the same manager
+func (g requiresGraph) requiresAny(batch syncBatch, wave []syncBatch) bool {
+	for _, other := range wave {
+		if other.manager == batch.manager {
+			return true
+		}
+		for _, pkg := range batch.packages {
+			if lo.ContainsBy(g[manifest.Requirement{Manager: batch.manager, Package: pkg}], func(req manifest.Requirement) bool {
+				return req.Manager == other.manager && lo.Contains(other.packages, req.Package)
+			}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newSyncJobs returns a job for every package to sync in the status
+func newSyncJobs(manager managers.ConcurrentManager, managerName shared.ManagerName, pkgStatus status.PackageStatus) (jobs []syncJob) {
+	add := func(pkgs []shared.Package, action shared.PtermSpinnerStatus) {
+		for _, pkg := range pkgs {
+			jobs = append(jobs, syncJob{manager: manager, name: managerName, pkg: pkg, action: action})
+		}
+	}
+	add(pkgStatus.Missing, shared.PtermSpinnerInstall)
+	add(pkgStatus.Updated, shared.PtermSpinnerUpdate)
+	add(pkgStatus.Removed, shared.PtermSpinnerRemove)
+	return
+}
+
+// runSyncJobs runs the jobs with at most config.Jobs at a time, showing a
+// spinner per package in a MultiPrinter. The failures are returned in the
+// order of the jobs.
+func runSyncJobs(ctx context.Context, jobs []syncJob) []shared.SyncFailure {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	// The writers are created up front, since the MultiPrinter reads them while
+	// it is running
+	writers := make([]io.Writer, len(jobs))
+	var multi *pterm.MultiPrinter
+	if pterm.Output {
+		printer := pterm.DefaultMultiPrinter
+		multi = &printer
+		for i, job := range jobs {
+			writers[i] = multi.NewWriter()
+			pterm.Fprint(writers[i], pterm.Gray(fmt.Sprintf("Waiting to %s %s", job.action, job.pkg.Name)))
+		}
+		_, _ = multi.Start()
+	}
+
+	errs := make([]error, len(jobs))
+	sem := make(chan struct{}, max(config.Jobs, 1))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = shared.PtermSpinnerTo(writers[i], job.action, job.pkg.Name, func() error {
+				return job.manager.SyncPackage(ctx, job.pkg, job.action)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if multi != nil {
+		_, _ = multi.Stop()
+	}
+
+	failures := []shared.SyncFailure{}
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, shared.SyncFailure{Manager: jobs[i].name, Name: jobs[i].pkg.Name, Action: jobs[i].action, Err: err})
+		}
+	}
+	return failures
+}
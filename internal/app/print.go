@@ -1,15 +1,47 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/lucas-ingemar/packtrak/internal/config"
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/lucas-ingemar/packtrak/internal/status"
+	"gopkg.in/yaml.v3"
 )
 
+const (
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+func (a *App) PrintReport(s status.Status, managerNames []shared.ManagerName, output string) error {
+	return printOutput(s.Report(managerNames), output)
+}
+
+func printOutput(v any, output string) error {
+	switch output {
+	case OutputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case OutputYAML:
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unknown output format '%s'", output)
+	}
+}
+
 func (a *App) PrintPackageList(s status.Status) error {
 	noSynced, noUpdated, noMissing, noRemoved := 0, 0, 0, 0
 
@@ -78,6 +110,70 @@ func (a *App) PrintPackageList(s status.Status) error {
 	return nil
 }
 
+func (a *App) PrintPlan(ctx context.Context, s status.Status, managerNames []shared.ManagerName) error {
+	graph, err := a.requiresGraph()
+	if err != nil {
+		return err
+	}
+	batches, err := graph.syncOrder(managerNames, s)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\nPlanned actions:")
+	noActions := 0
+	for _, batch := range batches {
+		m, err := a.Managers.GetManager(batch.manager)
+		if err != nil {
+			return err
+		}
+
+		depActions := []string{}
+		if batch.first {
+			if depActions, err = m.PlanDependencies(ctx, s.GetDependencies(m.Name())); err != nil {
+				return err
+			}
+		}
+
+		pkgStatus, err := a.withHooks(m.Name(), batchPackages(s.GetPackages(m.Name()), batch))
+		if err != nil {
+			return err
+		}
+
+		pkgActions, err := m.PlanPackages(ctx, pkgStatus)
+		if err != nil {
+			return err
+		}
+
+		for _, action := range slices.Concat(depActions, pkgActions, hookActions(pkgStatus)) {
+			shared.PtermBlue.Printfln("%s %s", m.Icon(), action)
+			noActions++
+		}
+	}
+
+	if noActions == 0 {
+		shared.PtermGreen.Printfln("Nothing to do")
+	}
+	return nil
+}
+
+func (a *App) printSyncFailures(failures []shared.SyncFailure) {
+	fmt.Println("\nFailed:")
+	data := [][]string{}
+	for _, f := range failures {
+		icon := ""
+		if m, err := a.Managers.GetManager(f.Manager); err == nil {
+			icon = m.Icon()
+		}
+		data = append(data, []string{
+			shared.PtermRemoved.Sprintf("%s %s", icon, f.Name),
+			shared.PtermRed.Sprint(f.Action),
+			strings.TrimSpace(f.Err.Error()),
+		})
+	}
+	shared.PtermTablePrinter.WithData(data).Render()
+}
+
 func (a *App) printPackagesEnhanced(s status.Status) (noSynced, noUpdated, noMissing, noRemoved int, err error) {
 	syncM, updatedM, missingM, removedM := [][]string{}, [][]string{}, [][]string{}, [][]string{}
 	for _, mName := range a.Managers.ListManagers() {
@@ -86,7 +182,7 @@ func (a *App) printPackagesEnhanced(s status.Status) (noSynced, noUpdated, noMis
 			return 0, 0, 0, 0, err
 		}
 		for _, pkg := range s.GetPackagesByStatus(m.Name(), status.StatusSynced) {
-			syncM = append(syncM, []string{shared.PtermInstalled.Sprintf("%s %s", m.Icon(), pkg.Name), shared.PtermGreen.Sprint(pkg.Version)})
+			syncM = append(syncM, []string{shared.PtermInstalled.Sprintf("%s %s", m.Icon(), pkg.Name), shared.PtermGreen.Sprint(installedVersion(pkg))})
 			noSynced++
 		}
 
@@ -144,3 +240,12 @@ func (a *App) printPackagesStandard(s status.Status) (noSynced, noUpdated, noMis
 	fmt.Print(removedStr)
 	return
 }
+
+// installedVersion returns the version together with the date it was
+// installed, if the state knows it
+func installedVersion(pkg shared.Package) string {
+	if pkg.InstalledAt == nil {
+		return pkg.Version
+	}
+	return fmt.Sprintf("%s installed on %s", pkg.Version, pkg.InstalledAt.Local().Format(time.DateOnly))
+}
@@ -43,7 +43,7 @@ func (a *App) Remove(ctx context.Context, apkgs []string, managerName shared.Man
 		return error
 	}
 
-	if !a.mustDoSudo(ctx, []shared.ManagerName{managerName}, shared.CommandRemove) {
+	if !config.DryRun && !a.mustDoSudo(ctx, []shared.ManagerName{managerName}, shared.CommandRemove) {
 		return errors.New("sudo access not granted")
 	}
 
@@ -99,7 +99,7 @@ func (a *App) Remove(ctx context.Context, apkgs []string, managerName shared.Man
 	}
 
 	if err = a.Manifest.RemoveGlobal(mType, managerName, toRemove); err != nil {
-		return nil
+		return err
 	}
 
 	for _, c := range pmManifest.Conditional {
@@ -108,15 +108,23 @@ func (a *App) Remove(ctx context.Context, apkgs []string, managerName shared.Man
 			return err
 		}
 		if match {
-			if err = a.Manifest.RemoveConditional(mType, managerName, c.Type, c.Value, toRemove); err != nil {
+			if err = a.Manifest.RemoveConditional(mType, managerName, c, toRemove); err != nil {
 				return err
 			}
 		}
 	}
 
-	if err = a.Sync(ctx, []shared.ManagerName{manager.Name()}); err != nil {
-		return err
+	syncErr := a.Sync(ctx, []shared.ManagerName{manager.Name()})
+	if syncErr != nil && !errors.Is(syncErr, ErrPartialSync) {
+		return syncErr
+	}
+
+	if config.DryRun {
+		return nil
 	}
 
-	return a.Manifest.Save(config.ManifestFile)
+	if err = a.Manifest.Save(); err != nil {
+		return err
+	}
+	return syncErr
 }
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/manifest"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/samber/lo"
+)
+
+// requiresGraph holds the packages, of any manager, that each package in the
+// manifest requires to be installed first
+type requiresGraph map[manifest.Requirement][]manifest.Requirement
+
+// syncBatch is a part of the changed packages of a manager, synced once the
+// batches before it are done. The first batch of a manager also syncs its
+// dependencies and removed packages, and the last one updates its state.
+type syncBatch struct {
+	manager  shared.ManagerName
+	packages []string
+	first    bool
+	last     bool
+}
+
+// requiresGraph reads the requirements of the enabled managers from the
+// manifest and makes sure they don't form a cycle
+func (a *App) requiresGraph() (requiresGraph, error) {
+	graph := requiresGraph{}
+	for _, managerName := range a.ListManagers() {
+		requires, err := manifest.FilterRequires(a.Manifest.Pm(managerName))
+		if err != nil {
+			return nil, err
+		}
+		for pkg, reqs := range requires {
+			node := manifest.Requirement{Manager: managerName, Package: pkg}
+			for _, req := range reqs {
+				if !lo.Contains(managers.RegisteredNames(), req.Manager) {
+					return nil, fmt.Errorf("%s requires %s of unknown manager '%s'", node, req, req.Manager)
+				}
+			}
+			graph[node] = reqs
+		}
+	}
+	return graph, graph.checkCycles()
+}
+
+func (g requiresGraph) checkCycles() error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	marks := map[manifest.Requirement]int{}
+
+	var visit func(node manifest.Requirement, path []manifest.Requirement) error
+	visit = func(node manifest.Requirement, path []manifest.Requirement) error {
+		switch marks[node] {
+		case visiting:
+			cycle := append(path[slices.Index(path, node):], node)
+			return fmt.Errorf("requirement cycle: %s", strings.Join(lo.Map(cycle, func(r manifest.Requirement, _ int) string {
+				return r.String()
+			}), " -> "))
+		case visited:
+			return nil
+		}
+		marks[node] = visiting
+		for _, req := range g[node] {
+			if err := visit(req, append(path, node)); err != nil {
+				return err
+			}
+		}
+		marks[node] = visited
+		return nil
+	}
+
+	nodes := lo.Keys(g)
+	slices.SortFunc(nodes, func(a, b manifest.Requirement) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	for _, node := range nodes {
+		if err := visit(node, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncOrder splits the packages to install or update into batches, so that
+// every package is synced after the packages it requires. Managers are synced
+// in the given order as far as the requirements allow, usually one batch each.
+func (g requiresGraph) syncOrder(managerNames []shared.ManagerName, s status.Status) ([]syncBatch, error) {
+	pending := map[shared.ManagerName][]string{}
+	for _, managerName := range managerNames {
+		pkgStatus := s.GetPackages(managerName)
+		for _, pkg := range slices.Concat(pkgStatus.Missing, pkgStatus.Updated) {
+			pending[managerName] = append(pending[managerName], shared.Unpin(pkg.FullName))
+		}
+	}
+	isPending := func(req manifest.Requirement) bool {
+		return lo.Contains(pending[req.Manager], req.Package)
+	}
+
+	batches := []syncBatch{}
+	started := map[shared.ManagerName]bool{}
+	unfinished := func(managerName shared.ManagerName) bool {
+		return !started[managerName] || len(pending[managerName]) > 0
+	}
+	for lo.SomeBy(managerNames, unfinished) {
+		added := false
+		for _, managerName := range lo.Filter(managerNames, func(m shared.ManagerName, _ int) bool { return unfinished(m) }) {
+			ready := lo.Filter(pending[managerName], func(pkg string, _ int) bool {
+				return !lo.SomeBy(g[manifest.Requirement{Manager: managerName, Package: pkg}], isPending)
+			})
+			if len(ready) == 0 && len(pending[managerName]) > 0 {
+				continue
+			}
+			batches = append(batches, syncBatch{manager: managerName, packages: ready, first: !started[managerName]})
+			started[managerName] = true
+			pending[managerName] = lo.Without(pending[managerName], ready...)
+			added = true
+			break
+		}
+		if !added {
+			return nil, errors.New("can't order the packages by their requirements")
+		}
+	}
+
+	last := map[shared.ManagerName]bool{}
+	for i := len(batches) - 1; i >= 0; i-- {
+		if !last[batches[i].manager] {
+			batches[i].last = true
+			last[batches[i].manager] = true
+		}
+	}
+	return batches, nil
+}
+
+// batchPackages returns the part of the package status synced in the batch
+func batchPackages(pkgStatus status.PackageStatus, batch syncBatch) status.PackageStatus {
+	inBatch := func(pkg shared.Package, _ int) bool {
+		return lo.Contains(batch.packages, shared.Unpin(pkg.FullName))
+	}
+	result := status.PackageStatus{
+		Missing: lo.Filter(pkgStatus.Missing, inBatch),
+		Updated: lo.Filter(pkgStatus.Updated, inBatch),
+	}
+	if batch.first {
+		result.Removed = pkgStatus.Removed
+	}
+	return result
+}
+
+// skipFailedRequirements leaves out the packages requiring a package that
+// failed to sync, and returns them as failures. The skipped packages are
+// added to failed, so that packages requiring them are skipped as well.
+func (g requiresGraph) skipFailedRequirements(managerName shared.ManagerName, pkgStatus status.PackageStatus, failed map[manifest.Requirement]bool) (status.PackageStatus, []shared.SyncFailure) {
+	failures := []shared.SyncFailure{}
+	skip := func(pkgs []shared.Package, action shared.PtermSpinnerStatus) []shared.Package {
+		return lo.Filter(pkgs, func(pkg shared.Package, _ int) bool {
+			node := manifest.Requirement{Manager: managerName, Package: shared.Unpin(pkg.FullName)}
+			req, found := lo.Find(g[node], func(req manifest.Requirement) bool {
+				return failed[req]
+			})
+			if found {
+				failed[node] = true
+				failures = append(failures, shared.SyncFailure{
+					Manager: managerName,
+					Name:    pkg.Name,
+					Action:  action,
+					Err:     fmt.Errorf("requires %s, which failed to sync", req),
+				})
+			}
+			return !found
+		})
+	}
+	pkgStatus.Missing = skip(pkgStatus.Missing, shared.PtermSpinnerInstall)
+	pkgStatus.Updated = skip(pkgStatus.Updated, shared.PtermSpinnerUpdate)
+	return pkgStatus, failures
+}
+
+// markFailed adds the packages of the status that are among the failures to
+// failed
+func markFailed(managerName shared.ManagerName, pkgStatus status.PackageStatus, failures []shared.SyncFailure, failed map[manifest.Requirement]bool) {
+	for _, pkg := range slices.Concat(pkgStatus.Missing, pkgStatus.Updated) {
+		if lo.ContainsBy(failures, func(f shared.SyncFailure) bool {
+			return f.Manager == managerName && f.Name == pkg.Name
+		}) {
+			failed[manifest.Requirement{Manager: managerName, Package: shared.Unpin(pkg.FullName)}] = true
+		}
+	}
+}
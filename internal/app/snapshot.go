@@ -0,0 +1,266 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/state"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/pterm/pterm"
+	"github.com/samber/lo"
+)
+
+// snapshotDiff holds what differs between the current state of a manager and
+// a snapshot. Missing is only in the snapshot, Removed only in the current
+// state.
+type snapshotDiff struct {
+	manager     shared.ManagerName
+	missingPkgs []string
+	removedPkgs []string
+	missingDeps []string
+	removedDeps []string
+}
+
+func (d snapshotDiff) empty() bool {
+	return len(d.missingPkgs)+len(d.removedPkgs)+len(d.missingDeps)+len(d.removedDeps) == 0
+}
+
+func (a *App) PrintSnapshots(output string) error {
+	snapshots, err := state.ListSnapshots()
+	if err != nil {
+		return err
+	}
+
+	if output != "" {
+		return printOutput(snapshots, output)
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots found")
+		return nil
+	}
+
+	data := pterm.TableData{{"Snapshot", "Time"}}
+	for _, s := range snapshots {
+		data = append(data, []string{s.Name, s.Time.Format(historyTimeFormat)})
+	}
+	return pterm.DefaultTable.WithHasHeader().WithData(data).Render()
+}
+
+// DiffSnapshot prints how the current state differs from the snapshot
+func (a *App) DiffSnapshot(ctx context.Context, name string) error {
+	_, diffs, err := a.diffSnapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	printSnapshotDiffs(diffs)
+	return nil
+}
+
+// RestoreSnapshot replaces the state with the one of the snapshot. The
+// current state is rotated first, so the restore itself can be undone. If
+// sync is set the packages and dependencies that differ are installed or
+// removed to match the snapshot.
+func (a *App) RestoreSnapshot(ctx context.Context, name string, sync bool) error {
+	ss, diffs, err := a.diffSnapshot(ctx, name)
+	if err != nil {
+		return err
+	}
+	printSnapshotDiffs(diffs)
+
+	if config.DryRun {
+		if sync {
+			return a.planSnapshotSync(ctx, diffs)
+		}
+		return nil
+	}
+
+	if !*config.AssumeYes && !confirm(fmt.Sprintf("Do you want to restore the state of %s?", name)) {
+		return nil
+	}
+
+	if err := state.Rotate(config.StateRotations); err != nil {
+		return err
+	}
+	if err := a.State.Restore(ctx, ss); err != nil {
+		return err
+	}
+	shared.PtermInstalled.Printfln("State restored from %s", name)
+
+	if sync {
+		if err := a.syncSnapshot(ctx, diffs); err != nil {
+			return err
+		}
+	}
+
+	if !lo.EveryBy(diffs, snapshotDiff.empty) {
+		shared.PtermWarning.Println("The manifest is unchanged. Revert it as well, or the next sync will go back to it.")
+	}
+	return nil
+}
+
+func (a *App) diffSnapshot(ctx context.Context, name string) (state.SnapshotState, []snapshotDiff, error) {
+	snapshot, err := state.FindSnapshot(name)
+	if err != nil {
+		return state.SnapshotState{}, nil, err
+	}
+
+	ss, err := state.LoadSnapshot(snapshot)
+	if err != nil {
+		return state.SnapshotState{}, nil, err
+	}
+
+	diffs := []snapshotDiff{}
+	for _, managerName := range a.ListManagers() {
+		pkgs, err := a.State.GetPackageState(ctx, managerName)
+		if err != nil {
+			return ss, nil, err
+		}
+		deps, err := a.State.GetDependencyState(ctx, managerName)
+		if err != nil {
+			return ss, nil, err
+		}
+
+		diff := snapshotDiff{manager: managerName}
+		diff.removedPkgs, diff.missingPkgs = lo.Difference(shared.FullNames(pkgs), ss.GetPackages(managerName))
+		diff.removedDeps, diff.missingDeps = lo.Difference(deps, ss.GetDependencies(managerName))
+		diffs = append(diffs, diff)
+	}
+	return ss, diffs, nil
+}
+
+func printSnapshotDiffs(diffs []snapshotDiff) {
+	if lo.EveryBy(diffs, snapshotDiff.empty) {
+		shared.PtermGreen.Println("No difference to the current state")
+		return
+	}
+
+	for _, diff := range diffs {
+		if diff.empty() {
+			continue
+		}
+		fmt.Printf("\n%s:\n", diff.manager)
+		for _, dep := range diff.missingDeps {
+			shared.PtermMissing.Printfln("%s (dependency)", dep)
+		}
+		for _, dep := range diff.removedDeps {
+			shared.PtermRemoved.Printfln("%s (dependency)", dep)
+		}
+		for _, pkg := range diff.missingPkgs {
+			shared.PtermMissing.Println(pkg)
+		}
+		for _, pkg := range diff.removedPkgs {
+			shared.PtermRemoved.Println(pkg)
+		}
+	}
+}
+
+// snapshotStatus returns what has to be installed and removed to get back to
+// the snapshot
+func (a *App) snapshotStatus(ctx context.Context, diff snapshotDiff) (status.PackageStatus, status.DependenciesStatus, error) {
+	manager, err := a.Managers.GetManager(diff.manager)
+	if err != nil {
+		return status.PackageStatus{}, status.DependenciesStatus{}, err
+	}
+
+	pkgStatus, err := manager.ListPackages(ctx, diff.missingPkgs, diff.removedPkgs)
+	if err != nil {
+		return status.PackageStatus{}, status.DependenciesStatus{}, err
+	}
+	depStatus, err := manager.ListDependencies(ctx, diff.missingDeps, diff.removedDeps)
+	if err != nil {
+		return status.PackageStatus{}, status.DependenciesStatus{}, err
+	}
+
+	pkgStatus, err = a.withHooks(diff.manager, status.PackageStatus{Missing: pkgStatus.Missing, Removed: pkgStatus.Removed})
+	return pkgStatus, status.DependenciesStatus{Missing: depStatus.Missing, Removed: depStatus.Removed}, err
+}
+
+func (a *App) planSnapshotSync(ctx context.Context, diffs []snapshotDiff) error {
+	for _, diff := range diffs {
+		if diff.empty() {
+			continue
+		}
+		manager, err := a.Managers.GetManager(diff.manager)
+		if err != nil {
+			return err
+		}
+		pkgStatus, depStatus, err := a.snapshotStatus(ctx, diff)
+		if err != nil {
+			return err
+		}
+
+		depActions, err := manager.PlanDependencies(ctx, depStatus)
+		if err != nil {
+			return err
+		}
+		pkgActions, err := manager.PlanPackages(ctx, pkgStatus)
+		if err != nil {
+			return err
+		}
+		for _, action := range slices.Concat(depActions, pkgActions, hookActions(pkgStatus)) {
+			fmt.Printf("%s %s\n", manager.Icon(), action)
+		}
+	}
+	return nil
+}
+
+func (a *App) syncSnapshot(ctx context.Context, diffs []snapshotDiff) error {
+	managerNames := lo.FilterMap(diffs, func(diff snapshotDiff, _ int) (shared.ManagerName, bool) {
+		return diff.manager, !diff.empty()
+	})
+	if !a.mustDoSudo(ctx, managerNames, shared.CommandSync) {
+		return errors.New("sudo access not granted")
+	}
+
+	failures := []shared.SyncFailure{}
+	total := 0
+	for _, diff := range diffs {
+		if diff.empty() {
+			continue
+		}
+		manager, err := a.Managers.GetManager(diff.manager)
+		if err != nil {
+			return err
+		}
+		pkgStatus, depStatus, err := a.snapshotStatus(ctx, diff)
+		if err != nil {
+			return err
+		}
+		total += len(pkgStatus.Missing) + len(pkgStatus.Removed) + len(depStatus.Missing) + len(depStatus.Removed)
+
+		f, uw, err := manager.SyncDependencies(ctx, depStatus)
+		if err != nil {
+			return err
+		}
+		for _, w := range uw {
+			shared.PtermWarning.Println(w)
+		}
+		if err = a.State.AddHistory(ctx, dependencyHistory(manager.Name(), depStatus, f)); err != nil {
+			return err
+		}
+		failures = append(failures, f...)
+
+		f, uw, err = manager.SyncPackages(ctx, pkgStatus)
+		if err != nil {
+			return err
+		}
+		for _, w := range uw {
+			shared.PtermWarning.Println(w)
+		}
+		if err = a.State.AddHistory(ctx, packageHistory(manager.Name(), pkgStatus, f)); err != nil {
+			return err
+		}
+		failures = append(failures, f...)
+	}
+
+	if len(failures) > 0 {
+		a.printSyncFailures(failures)
+		return fmt.Errorf("%w: %d of %d changes failed", ErrPartialSync, len(failures), total)
+	}
+	return nil
+}
@@ -4,20 +4,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/manifest"
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/lucas-ingemar/packtrak/internal/state"
+	"github.com/lucas-ingemar/packtrak/internal/status"
 	"github.com/pterm/pterm"
+	"github.com/samber/lo"
 )
 
+// ErrPartialSync is returned by Sync when the sync ran to completion but some
+// packages or dependencies failed to sync.
+var ErrPartialSync = errors.New("sync partially failed")
+
 func (a *App) Sync(ctx context.Context, managerNames []shared.ManagerName) (err error) {
 	ms, error := a.Managers.GetManagers(managerNames)
 	if error != nil {
 		return error
 	}
 
-	if !a.mustDoSudo(ctx, managerNames, shared.CommandSync) {
+	if !config.DryRun && !a.mustDoSudo(ctx, managerNames, shared.CommandSync) {
 		return errors.New("sudo access not granted")
 	}
 
@@ -25,6 +34,9 @@ func (a *App) Sync(ctx context.Context, managerNames []shared.ManagerName) (err
 	if err != nil {
 		return err
 	}
+	if err := statusObj.StaleErr(); err != nil {
+		return fmt.Errorf("can't sync without listing the packages: %w", err)
+	}
 
 	pkgsState := statusObj.GetUpdatedPackageState(managerNames)
 	depsState := statusObj.GetUpdatedDependenciesState(managerNames)
@@ -33,6 +45,19 @@ func (a *App) Sync(ctx context.Context, managerNames []shared.ManagerName) (err
 		return err
 	}
 
+	if config.DryRun {
+		return a.PrintPlan(ctx, statusObj, managerNames)
+	}
+
+	graph, err := a.requiresGraph()
+	if err != nil {
+		return err
+	}
+	batches, err := graph.syncOrder(managerNames, statusObj)
+	if err != nil {
+		return err
+	}
+
 	if statusObj.CountUpdatedPackages() == 0 && statusObj.CountUpdatedDependencies() == 0 {
 		tx := a.State.Begin(ctx)
 		defer func() { _ = tx.Rollback() }()
@@ -50,6 +75,9 @@ func (a *App) Sync(ctx context.Context, managerNames []shared.ManagerName) (err
 		if err := tx.Commit(); err != nil {
 			return err
 		}
+		if err := a.updateLock(managerNames, statusObj); err != nil {
+			return err
+		}
 		return state.Rotate(config.StateRotations)
 	}
 
@@ -69,43 +97,346 @@ func (a *App) Sync(ctx context.Context, managerNames []shared.ManagerName) (err
 		result = "y"
 	}
 
+	failures := []shared.SyncFailure{}
+	userWarnings := []string{}
+	// Changes the managers make later in the sync, recorded once they are done
+	pending := []shared.SyncFailure{}
+	// Installs whose version is only known when the packages are listed again
+	unversioned := []state.HistoryEntry{}
+
 	if result == "y" {
-		for _, manager := range ms {
-			tx := a.State.Begin(ctx)
-			defer func() { _ = tx.Rollback() }()
+		failed := map[manifest.Requirement]bool{}
+		for _, wave := range graph.syncWaves(batches, a.canSyncConcurrently) {
+			results := []batchResult{}
+			for _, batch := range wave {
+				manager, err := a.Managers.GetManager(batch.manager)
+				if err != nil {
+					return err
+				}
 
-			uw, err := manager.SyncDependencies(ctx, statusObj.GetDependencies(manager.Name()))
-			_ = uw
-			if err != nil {
-				return err
+				if batch.first {
+					f, uw, err := a.syncDependencies(ctx, manager, statusObj.GetDependencies(manager.Name()), depsState[manager.Name()])
+					if err != nil {
+						return err
+					}
+					pending = append(pending, lo.Filter(f, isPending)...)
+					failures = append(failures, lo.Reject(f, isPending)...)
+					userWarnings = append(userWarnings, uw...)
+				}
+
+				r := batchResult{manager: manager, batch: batch, batchStatus: batchPackages(statusObj.GetPackages(manager.Name()), batch)}
+				r.pkgStatus, r.failures = graph.skipFailedRequirements(manager.Name(), r.batchStatus, failed)
+				r.pkgStatus, err = a.withHooks(manager.Name(), a.withExpectedChecksums(manager.Name(), r.pkgStatus))
+				if err != nil {
+					return err
+				}
+				results = append(results, r)
 			}
-			err = tx.UpdateDependencyState(ctx, manager.Name(), depsState[manager.Name()])
-			if err != nil {
+
+			if err := a.syncWave(ctx, results); err != nil {
 				return err
 			}
 
-			if err := tx.Commit(); err != nil {
-				return err
+			for _, r := range results {
+				rFailures := lo.Reject(r.failures, isPending)
+				markFailed(r.manager.Name(), r.pkgStatus, rFailures, failed)
+				failures = append(failures, rFailures...)
+				userWarnings = append(userWarnings, r.userWarnings...)
+				for _, p := range lo.Filter(r.failures, isPending) {
+					userWarnings = append(userWarnings, fmt.Sprintf("%s package '%s' was not synced (%s)", p.Manager, p.Name, p.Err))
+				}
+
+				history, err := a.saveBatch(ctx, r, pkgsState[r.manager.Name()])
+				if err != nil {
+					return err
+				}
+				unversioned = append(unversioned, lo.Filter(history, func(e state.HistoryEntry, _ int) bool {
+					return e.Action == shared.PtermSpinnerInstall && e.Outcome == state.HistorySuccess && e.NewVersion == ""
+				})...)
 			}
+		}
+	}
 
-			tx = a.State.Begin(ctx)
-			defer func() { _ = tx.Rollback() }()
+	synced := statusObj
+	if result == "y" && (len(failures) == 0 || len(unversioned) > 0) {
+		synced, err = a.relistStatus(ctx, managerNames)
+		if err != nil {
+			return err
+		}
+		if err := a.setInstalledVersions(ctx, synced, unversioned); err != nil {
+			return err
+		}
+		pending, err = a.settlePending(ctx, synced, depsState, pending)
+		if err != nil {
+			return err
+		}
+	}
 
-			uw, err = manager.SyncPackages(ctx, statusObj.GetPackages(manager.Name()))
-			_ = uw
-			if err != nil {
-				return err
+	for _, p := range pending {
+		userWarnings = append(userWarnings, fmt.Sprintf("%s dependency '%s' was not synced (%s)", p.Manager, p.Name, p.Err))
+	}
+
+	if len(userWarnings) > 0 {
+		fmt.Println("")
+	}
+	for _, uw := range userWarnings {
+		shared.PtermWarning.Println(uw)
+	}
+
+	if err := state.Rotate(config.StateRotations); err != nil {
+		return err
+	}
+
+	if len(failures) > 0 {
+		a.printSyncFailures(failures)
+		return fmt.Errorf("%w: %d of %d changes failed", ErrPartialSync, len(failures), statusObj.CountUpdatedPackages()+statusObj.CountUpdatedDependencies())
+	}
+
+	if result == "y" {
+		return a.updateLock(managerNames, synced)
+	}
+	return nil
+}
+
+// batchResult holds a batch of a manager while it is synced. batchStatus is
+// what the batch should sync, and pkgStatus what is left of it after skipping
+// packages whose requirements failed.
+type batchResult struct {
+	manager      managers.Manager
+	batch        syncBatch
+	batchStatus  status.PackageStatus
+	pkgStatus    status.PackageStatus
+	failures     []shared.SyncFailure
+	userWarnings []string
+}
+
+func (a *App) syncDependencies(ctx context.Context, manager managers.Manager, depStatus status.DependenciesStatus, depsState []shared.Dependency) ([]shared.SyncFailure, []string, error) {
+	tx := a.State.Begin(ctx)
+	defer func() { _ = tx.Rollback() }()
+
+	failures, userWarnings, err := manager.SyncDependencies(ctx, depStatus)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Pending dependencies are left out until they are synced, see settlePending
+	depsState = lo.Reject(depsState, func(dep shared.Dependency, _ int) bool {
+		return isPendingName(failures, dep.Name)
+	})
+
+	err = tx.UpdateDependencyState(ctx, manager.Name(), depsState)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = tx.AddHistory(ctx, dependencyHistory(manager.Name(), depStatus, failures))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return failures, userWarnings, tx.Commit()
+}
+
+// settlePending records the pending dependencies that are synced in the
+// relisted status, and returns the ones that are still pending
+func (a *App) settlePending(ctx context.Context, statusObj status.Status, depsState map[shared.ManagerName][]shared.Dependency, pending []shared.SyncFailure) ([]shared.SyncFailure, error) {
+	if len(pending) == 0 {
+		return pending, nil
+	}
+
+	tx := a.State.Begin(ctx)
+	defer func() { _ = tx.Rollback() }()
+
+	left := []shared.SyncFailure{}
+	for managerName, mPending := range lo.GroupBy(pending, func(p shared.SyncFailure) shared.ManagerName { return p.Manager }) {
+		synced := statusObj.GetDependencies(managerName).Synced
+		mState := lo.Reject(depsState[managerName], func(dep shared.Dependency, _ int) bool {
+			return isPendingName(mPending, dep.Name)
+		})
+		history := []state.HistoryEntry{}
+		for _, p := range mPending {
+			dep, found := lo.Find(synced, func(dep shared.Dependency) bool { return dep.Name == p.Name })
+			if !found {
+				left = append(left, p)
+				continue
 			}
-			err = tx.UpdatePackageState(ctx, manager.Name(), pkgsState[manager.Name()])
-			if err != nil {
-				return err
+			mState = append(mState, dep)
+			history = append(history, newHistoryEntry(managerName, state.HistoryDependency, dep.Name, p.Action, nil))
+		}
+		if len(history) == 0 {
+			continue
+		}
+
+		if err := tx.UpdateDependencyState(ctx, managerName, mState); err != nil {
+			return nil, err
+		}
+		if err := tx.AddHistory(ctx, history); err != nil {
+			return nil, err
+		}
+	}
+	return left, tx.Commit()
+}
+
+func isPending(f shared.SyncFailure, _ int) bool {
+	return errors.Is(f.Err, shared.ErrSyncPending)
+}
+
+func isPendingName(failures []shared.SyncFailure, name string) bool {
+	return lo.ContainsBy(failures, func(f shared.SyncFailure) bool {
+		return f.Name == name && isPending(f, 0)
+	})
+}
+
+// saveBatch writes the history of a synced batch, and the package state of the
+// manager after its last batch
+func (a *App) saveBatch(ctx context.Context, r batchResult, pkgsState []shared.Package) ([]state.HistoryEntry, error) {
+	tx := a.State.Begin(ctx)
+	defer func() { _ = tx.Rollback() }()
+
+	if r.batch.last {
+		err := tx.UpdatePackageState(ctx, r.manager.Name(), pkgsState)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	history := packageHistory(r.manager.Name(), r.batchStatus, r.failures)
+	err := tx.AddHistory(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, tx.Commit()
+}
+
+// syncWave syncs the packages of the batches in the wave. Managers syncing
+// concurrently share one pool of jobs, the others sync their batch with
+// SyncPackages.
+func (a *App) syncWave(ctx context.Context, results []batchResult) error {
+	if len(results) == 1 && !a.canSyncConcurrently(results[0].manager.Name()) {
+		r := &results[0]
+		f, uw, err := r.manager.SyncPackages(ctx, r.pkgStatus)
+		if err != nil {
+			return err
+		}
+		r.failures = append(r.failures, f...)
+		r.userWarnings = uw
+		return nil
+	}
+
+	jobs := []syncJob{}
+	for _, r := range results {
+		jobs = append(jobs, newSyncJobs(r.manager.(managers.ConcurrentManager), r.manager.Name(), r.pkgStatus)...)
+	}
+	for _, f := range runSyncJobs(ctx, jobs) {
+		for i := range results {
+			if results[i].manager.Name() == f.Manager {
+				results[i].failures = append(results[i].failures, f)
 			}
+		}
+	}
+	return nil
+}
 
-			if err := tx.Commit(); err != nil {
-				return err
+// withExpectedChecksums sets the checksum of the packages about to be installed
+// to the one in the lock file when syncing --locked, so that the manager can
+// verify what it downloads. Otherwise the checksum of the installed version
+// is cleared.
+func (a *App) withExpectedChecksums(managerName shared.ManagerName, pkgStatus status.PackageStatus) status.PackageStatus {
+	addChecksums := func(pkgs []shared.Package) []shared.Package {
+		return lo.Map(pkgs, func(pkg shared.Package, _ int) shared.Package {
+			pkg.Checksum = ""
+			if locked, found := a.Lock.Get(managerName, pkg.FullName); found && config.Locked {
+				pkg.Checksum = locked.Checksum
+			}
+			return pkg
+		})
+	}
+	pkgStatus.Missing = addChecksums(pkgStatus.Missing)
+	pkgStatus.Updated = addChecksums(pkgStatus.Updated)
+	return pkgStatus
+}
+
+// withHooks sets the hooks of the packages, from the manifest entry and the
+// manager config. A hook in the manifest replaces the one in the config.
+func (a *App) withHooks(managerName shared.ManagerName, pkgStatus status.PackageStatus) (status.PackageStatus, error) {
+	configHooks, err := managers.ConfigHooks(managerName)
+	if err != nil {
+		return pkgStatus, err
+	}
+	manifestHooks, err := manifest.FilterHooks(a.Manifest.Pm(managerName))
+	if err != nil {
+		return pkgStatus, err
+	}
+
+	addHooks := func(pkgs []shared.Package) []shared.Package {
+		return lo.Map(pkgs, func(pkg shared.Package, _ int) shared.Package {
+			pkg.Hooks = configHooks.Merge(manifestHooks[shared.Unpin(pkg.FullName)])
+			return pkg
+		})
+	}
+	pkgStatus.Missing = addHooks(pkgStatus.Missing)
+	pkgStatus.Updated = addHooks(pkgStatus.Updated)
+	pkgStatus.Removed = addHooks(pkgStatus.Removed)
+	return pkgStatus, nil
+}
+
+// hookActions describes the hooks a sync of the packages would run
+func hookActions(pkgStatus status.PackageStatus) (actions []string) {
+	add := func(pkgs []shared.Package, action shared.PtermSpinnerStatus) {
+		for _, pkg := range pkgs {
+			for _, stage := range []shared.HookStage{shared.HookPre, shared.HookPost} {
+				if script := pkg.Hooks.Get(stage, action); script != "" {
+					actions = append(actions, fmt.Sprintf("%s_%s %s: %s", stage, action, pkg.Name, script))
+				}
 			}
 		}
 	}
+	add(pkgStatus.Missing, shared.PtermSpinnerInstall)
+	add(pkgStatus.Updated, shared.PtermSpinnerUpdate)
+	add(pkgStatus.Removed, shared.PtermSpinnerRemove)
+	return
+}
+
+// relistStatus lists the status again after a sync, to get the installed
+// versions. Managers that cache their listing must clear it when syncing, or
+// the installs are still missing here.
+func (a *App) relistStatus(ctx context.Context, managerNames []shared.ManagerName) (status.Status, error) {
+	// Output is restored as it was, it is off for e.g. '--output json'
+	output := pterm.Output
+	pterm.DisableOutput()
+	defer func() { pterm.Output = output }()
+	return a.ListStatus(ctx, managerNames)
+}
 
-	return state.Rotate(config.StateRotations)
+// setInstalledVersions sets the version of the installs in the history that
+// the managers only know after installing, e.g. dnf and flatpak
+func (a *App) setInstalledVersions(ctx context.Context, statusObj status.Status, entries []state.HistoryEntry) error {
+	for _, e := range entries {
+		managerName := shared.ManagerName(e.Manager)
+		pkg, found := lo.Find(slices.Concat(
+			statusObj.GetPackagesByStatus(managerName, status.StatusSynced),
+			statusObj.GetPackagesByStatus(managerName, status.StatusUpdated),
+		), func(pkg shared.Package) bool {
+			return pkg.Name == e.Name
+		})
+		if !found || pkg.Version == "" {
+			continue
+		}
+		if err := a.State.SetHistoryVersion(ctx, e.ID, pkg.Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateLock records the installed package versions in the lock file
+func (a *App) updateLock(managerNames []shared.ManagerName, statusObj status.Status) error {
+	for _, managerName := range managerNames {
+		a.Lock.Update(managerName, slices.Concat(
+			statusObj.GetPackagesByStatus(managerName, status.StatusSynced),
+			statusObj.GetPackagesByStatus(managerName, status.StatusUpdated),
+		))
+	}
+	return a.Lock.Save()
 }
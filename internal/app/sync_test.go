@@ -0,0 +1,287 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/state"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSyncManifest = `one:
+  global:
+    dependencies: [dep]
+    packages: [a, b, c]
+two:
+  global:
+    dependencies: []
+    packages:
+      - d
+      - name: e
+        requires: three:g
+three:
+  global:
+    dependencies: []
+    packages: [f, g]
+four:
+  global:
+    dependencies: []
+    packages: [h]
+five:
+  global:
+    dependencies: []
+    packages: [i, j]
+_version: v1.0.0
+`
+
+func newTestManagers(log *syncLog) []managers.Manager {
+	return []managers.Manager{
+		newFakeManager("one", log, "a"),
+		newFakeManager("two", log),
+		newFakeManager("three", log),
+		newFakeManager("four", log, "h"),
+		newFakeManager("five", log),
+	}
+}
+
+func TestListStatus(t *testing.T) {
+	fakes := newTestManagers(&syncLog{})
+	a := newTestApp(t, testSyncManifest, fakes...)
+
+	s, err := a.ListStatus(context.Background(), a.ListManagers())
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(s.GetPackages("one").Synced))
+	assert.Equal(t, 2, len(s.GetPackages("one").Missing))
+	assert.Equal(t, 1, len(s.GetDependencies("one").Synced))
+	assert.Equal(t, 2, len(s.GetPackages("five").Missing))
+	assert.Equal(t, 8, s.CountUpdatedPackages())
+	assert.Nil(t, s.StaleErr())
+}
+
+// TestListStatusWithOutput lists the managers concurrently with the progress
+// printed, for 'go test -race'
+func TestListStatusWithOutput(t *testing.T) {
+	a := newTestApp(t, testSyncManifest, newTestManagers(&syncLog{})...)
+	pterm.EnableOutput()
+	pterm.SetDefaultOutput(io.Discard)
+	t.Cleanup(func() { pterm.SetDefaultOutput(os.Stdout) })
+
+	s, err := a.ListStatus(context.Background(), a.ListManagers())
+	require.NoError(t, err)
+	assert.Equal(t, 8, s.CountUpdatedPackages())
+}
+
+func TestSyncConcurrently(t *testing.T) {
+	log := &syncLog{}
+	fakes := newTestManagers(log)
+	fakes[2].(*fakeManager).failing = []string{"f"}
+	a := newTestApp(t, testSyncManifest, fakes...)
+	ctx := context.Background()
+
+	jobs := config.Jobs
+	config.Jobs = 4
+	t.Cleanup(func() { config.Jobs = jobs })
+
+	err := a.Sync(ctx, a.ListManagers())
+	assert.ErrorIs(t, err, ErrPartialSync, "f should fail")
+
+	for _, pkg := range []string{"one:b", "one:c", "two:d", "two:e", "three:g", "five:i", "five:j"} {
+		assert.NotEqual(t, -1, log.index(pkg), "%s should be synced", pkg)
+	}
+	assert.Equal(t, -1, log.index("three:f"))
+	assert.Less(t, log.index("three:g"), log.index("two:e"), "e requires g")
+
+	pkgs, err := a.State.GetPackageState(ctx, "two")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"d", "e"}, shared.FullNames(pkgs))
+
+	s, err := a.ListStatus(ctx, a.ListManagers())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"f"}, shared.FullNames(s.GetPackages("three").Missing), "only f should be left")
+
+	history, err := a.State.GetHistory(ctx, "three", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, state.HistoryFailed, history[0].Outcome)
+	assert.Empty(t, history[0].NewVersion)
+	assert.Equal(t, "1.0", history[1].NewVersion, "installs should get the version installed")
+}
+
+func TestSyncRelistsCachingManagers(t *testing.T) {
+	fakes := newTestManagers(&syncLog{})
+	for _, fake := range fakes {
+		fake.(*fakeManager).caching = true
+	}
+	a := newTestApp(t, testSyncManifest, fakes...)
+	ctx := context.Background()
+
+	require.NoError(t, a.Sync(ctx, a.ListManagers()))
+
+	history, err := a.State.GetHistory(ctx, "two", time.Time{})
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	for _, h := range history {
+		assert.Equal(t, "1.0", h.NewVersion, "%s should get the version installed", h.Name)
+	}
+	for _, pkg := range []string{"d", "e"} {
+		_, found := a.Lock.Get("two", pkg)
+		assert.True(t, found, "%s should be locked", pkg)
+	}
+}
+
+func TestSyncSkipsFailedRequirements(t *testing.T) {
+	log := &syncLog{}
+	fakes := newTestManagers(log)
+	fakes[2].(*fakeManager).failing = []string{"g"}
+	a := newTestApp(t, testSyncManifest, fakes...)
+
+	err := a.Sync(context.Background(), a.ListManagers())
+	assert.ErrorIs(t, err, ErrPartialSync)
+	assert.Equal(t, -1, log.index("two:e"), "e requires g, which failed")
+	assert.NotEqual(t, -1, log.index("two:d"))
+}
+
+func TestSyncPendingPackages(t *testing.T) {
+	fakes := newTestManagers(&syncLog{})
+	fakes[2].(*fakeManager).pending = []string{"g"}
+	a := newTestApp(t, testSyncManifest, fakes...)
+	ctx := context.Background()
+
+	err := a.Sync(ctx, a.ListManagers())
+	require.NoError(t, err, "pending packages are not failures")
+
+	history, err := a.State.GetHistory(ctx, "three", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, history, 1, "g should not be recorded")
+	assert.Equal(t, "f", history[0].Name)
+}
+
+// pinningFakeManager is a fake manager that supports pins
+type pinningFakeManager struct {
+	*fakeManager
+}
+
+func (m pinningFakeManager) SupportsPins() bool {
+	return true
+}
+
+func TestListStatusLocked(t *testing.T) {
+	log := &syncLog{}
+	a := newTestApp(t, testSyncManifest, newFakeManager("one", log, "a"), pinningFakeManager{newFakeManager("two", log)})
+	ctx := context.Background()
+
+	locked := config.Locked
+	config.Locked = true
+	t.Cleanup(func() { config.Locked = locked })
+
+	_, err := a.ListStatus(ctx, []shared.ManagerName{"two"})
+	assert.ErrorContains(t, err, "'d' is not locked")
+
+	s, err := a.ListStatus(ctx, []shared.ManagerName{"one"})
+	require.NoError(t, err, "managers without pins should not be locked")
+	assert.Equal(t, []string{"a"}, shared.FullNames(s.GetPackages("one").Synced))
+	assert.Equal(t, []string{"b", "c"}, shared.FullNames(s.GetPackages("one").Missing))
+}
+
+// injectingFakeManager has dependencies like 'pkg:plugin', which can only be
+// injected into an installed package. The ones for packages that are not
+// installed yet are injected once the package is installed, like pipx.
+type injectingFakeManager struct {
+	*fakeManager
+	injected map[string]bool
+	later    []string
+}
+
+func (m *injectingFakeManager) ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error) {
+	for _, dep := range deps {
+		if m.injected[dep] {
+			depStatus.Synced = append(depStatus.Synced, shared.Dependency{Name: dep, FullName: dep})
+		} else {
+			depStatus.Missing = append(depStatus.Missing, shared.Dependency{Name: dep, FullName: dep})
+		}
+	}
+	return
+}
+
+func (m *injectingFakeManager) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	for _, dep := range depStatus.Missing {
+		pkg, _, _ := strings.Cut(dep.Name, ":")
+		if !m.isInstalled(pkg) {
+			m.later = append(m.later, dep.Name)
+			failures = append(failures, shared.SyncFailure{
+				Manager: m.name,
+				Name:    dep.Name,
+				Action:  shared.PtermSpinnerInstall,
+				Err:     fmt.Errorf("%w: injected once '%s' is installed", shared.ErrSyncPending, pkg),
+			})
+			continue
+		}
+		m.injected[dep.Name] = true
+	}
+	return
+}
+
+func (m *injectingFakeManager) SyncPackages(ctx context.Context, pkgStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	for _, pkg := range pkgStatus.Missing {
+		if err := m.SyncPackage(ctx, pkg, shared.PtermSpinnerInstall); err != nil {
+			failures = append(failures, shared.SyncFailure{Manager: m.name, Name: pkg.Name, Action: shared.PtermSpinnerInstall, Err: err})
+		}
+	}
+	return
+}
+
+func (m *injectingFakeManager) CanSyncConcurrently() bool {
+	return false
+}
+
+func (m *injectingFakeManager) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
+	if err := m.fakeManager.SyncPackage(ctx, pkg, action); err != nil {
+		return err
+	}
+	for _, dep := range m.later {
+		if strings.HasPrefix(dep, pkg.Name+":") {
+			m.injected[dep] = true
+		}
+	}
+	return nil
+}
+
+func TestSyncPendingDependencies(t *testing.T) {
+	manager := &injectingFakeManager{fakeManager: newFakeManager("one", &syncLog{}), injected: map[string]bool{}}
+	a := newTestApp(t, `one:
+  global:
+    dependencies: ["a:plugin", "b:plugin"]
+    packages: [a]
+_version: v1.0.0
+`, manager)
+	ctx := context.Background()
+
+	err := a.Sync(ctx, a.ListManagers())
+	require.NoError(t, err, "pending dependencies are not failures")
+
+	deps, err := a.State.GetDependencyState(ctx, "one")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a:plugin"}, deps, "b is never installed, so b:plugin is still pending")
+
+	history, err := a.State.GetHistory(ctx, "one", time.Time{})
+	require.NoError(t, err)
+	deps = []string{}
+	for _, e := range history {
+		if e.ObjectType == state.HistoryDependency {
+			deps = append(deps, e.Name)
+			assert.Equal(t, state.HistorySuccess, e.Outcome)
+		}
+	}
+	assert.Equal(t, []string{"a:plugin"}, deps, "only the injected dependency should be recorded")
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lucas-ingemar/packtrak/internal/app"
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func initAdopt(a app.AppFace) {
+	adoptCmd := &cobra.Command{
+		Use:   "adopt [manager...]",
+		Short: "Add packages installed outside of packtrak to the manifest",
+		ValidArgs: lo.Map(a.ListManagers(), func(m shared.ManagerName, _ int) string {
+			return string(m)
+		}),
+		Args: cobra.OnlyValidArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			managerNames := a.ListManagers()
+			if len(args) > 0 {
+				managerNames = lo.Map(lo.Uniq(args), func(arg string, _ int) shared.ManagerName {
+					return shared.ManagerName(arg)
+				})
+			}
+
+			group := cmd.Flag("group").Value.String()
+			host := cmd.Flag("host").Value.String() == "true"
+			all := cmd.Flag("all").Value.String() == "true"
+			if host && group != "" {
+				exitOnError(fmt.Errorf("--host and --group can't be combined"), "initAdopt")
+			}
+
+			if err := a.Adopt(cmd.Context(), managerNames, host, group, all); err != nil {
+				exitOnError(err, "initAdopt")
+			}
+		},
+	}
+	adoptCmd.Flags().Bool("host", false, "Adopt only for the current host")
+	adoptCmd.Flags().String("group", "", "Adopt only for specified group")
+	adoptCmd.Flags().Bool("all", false, "Adopt every unmanaged package without asking")
+	adoptCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Print the packages that would be adopted without changing the manifest")
+	rootCmd.AddCommand(adoptCmd)
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/lucas-ingemar/packtrak/internal/app"
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func initDrift(a app.AppFace) {
+	driftCmd := &cobra.Command{
+		Use:   "drift [manager...]",
+		Short: "Report packages installed outside of packtrak",
+		Long:  "Report packages and files on the system that are neither in the manifest nor in the state, and adopt or remove them",
+		ValidArgs: lo.Map(a.ListManagers(), func(m shared.ManagerName, _ int) string {
+			return string(m)
+		}),
+		Args: cobra.OnlyValidArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			managerNames := a.ListManagers()
+			if len(args) > 0 {
+				managerNames = lo.Map(lo.Uniq(args), func(arg string, _ int) shared.ManagerName {
+					return shared.ManagerName(arg)
+				})
+			}
+
+			group := cmd.Flag("group").Value.String()
+			host := cmd.Flag("host").Value.String() == "true"
+			if host && group != "" {
+				exitOnError(fmt.Errorf("--host and --group can't be combined"), "initDrift")
+			}
+
+			actions := lo.Filter([]app.DriftAction{app.DriftCheck, app.DriftAdopt, app.DriftRemove}, func(action app.DriftAction, _ int) bool {
+				return cmd.Flag(string(action)).Value.String() == "true"
+			})
+			if len(actions) > 1 {
+				exitOnError(fmt.Errorf("only one of --check, --adopt and --remove can be given"), "initDrift")
+			}
+			action := app.DriftAsk
+			if len(actions) == 1 {
+				action = actions[0]
+			}
+
+			if err := a.Drift(cmd.Context(), managerNames, action, host, group); err != nil {
+				exitOnError(err, "initDrift")
+			}
+		},
+	}
+	driftCmd.Flags().Bool(string(app.DriftCheck), false, "Only report, and exit with code 3 if drift was found")
+	driftCmd.Flags().Bool(string(app.DriftAdopt), false, "Adopt all drifted packages without asking")
+	driftCmd.Flags().Bool(string(app.DriftRemove), false, "Remove all drifted packages and files")
+	driftCmd.Flags().Bool("host", false, "Adopt only for the current host")
+	driftCmd.Flags().String("group", "", "Adopt only for specified group")
+	driftCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Print what would be adopted or removed without changing anything")
+	rootCmd.AddCommand(driftCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lucas-ingemar/packtrak/internal/app"
+	"github.com/lucas-ingemar/packtrak/internal/managers"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func initHistory(a app.AppFace) {
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show what syncs have installed, updated and removed",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			managerName := shared.ManagerName(cmd.Flag("manager").Value.String())
+
+			since, err := parseSince(cmd.Flag("since").Value.String(), time.Now())
+			if err != nil {
+				exitOnError(err, "initHistory")
+			}
+
+			output := cmd.Flag("output").Value.String()
+			if err := a.PrintHistory(cmd.Context(), managerName, since, output); err != nil {
+				exitOnError(err, "initHistory")
+			}
+		},
+	}
+	historyCmd.Flags().String("manager", "", "Only show the history of this manager")
+	historyCmd.Flags().String("since", "", "Only show entries since a date (2006-01-02), a time (2006-01-02T15:04:05) or a duration ago (12h, 7d)")
+	_ = historyCmd.RegisterFlagCompletionFunc("manager", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		// Disabled managers have a history too
+		return lo.Map(managers.RegisteredNames(), func(m shared.ManagerName, _ int) string {
+			return string(m)
+		}), cobra.ShellCompDirectiveNoFileComp
+	})
+	addOutputFlag(historyCmd)
+	rootCmd.AddCommand(historyCmd)
+}
+
+// parseSince parses a date, a local time or a duration back from now. Besides
+// the units of time.ParseDuration, d is accepted for days.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+
+	if days, found := strings.CutSuffix(since, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.DateOnly, "2006-01-02T15:04:05", "2006-01-02 15:04:05", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, since, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since '%s'", since)
+}
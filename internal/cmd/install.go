@@ -2,9 +2,9 @@ package cmd
 
 import (
 	"github.com/lucas-ingemar/packtrak/internal/app"
+	"github.com/lucas-ingemar/packtrak/internal/config"
 	"github.com/lucas-ingemar/packtrak/internal/manifest"
 	"github.com/lucas-ingemar/packtrak/internal/shared"
-	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
@@ -20,6 +20,7 @@ func initInstall(a app.AppFace) {
 		installCmd.PersistentFlags().BoolP("dependency", "d", false, "Install dependency")
 		installCmd.PersistentFlags().Bool("host", false, "Install only for the current host")
 		installCmd.PersistentFlags().String("group", "", "Install only for specified group")
+		installCmd.PersistentFlags().BoolVar(&config.DryRun, "dry-run", false, "Print the planned actions without changing the system, manifest or state")
 		PmCmds[m].AddCommand(installCmd)
 	}
 }
@@ -55,7 +56,7 @@ func generateInstallCmd(a app.AppFace, managerName shared.ManagerName) func(cmd
 		host := cmd.Flag("host").Value.String() == "true"
 
 		if err := a.Install(cmd.Context(), args, managerName, mType, host, group); err != nil {
-			log.Fatal().Err(err).Msg("generateInstallCmd")
+			exitOnError(err, "generateInstallCmd")
 		}
 	}
 }
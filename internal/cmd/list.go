@@ -5,37 +5,61 @@ import (
 
 	"github.com/lucas-ingemar/packtrak/internal/app"
 	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/pterm/pterm"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 )
 
 func initList(a app.AppFace) {
 	for _, m := range a.ListManagers() {
-		PmCmds[m].AddCommand(&cobra.Command{
+		listCmd := &cobra.Command{
 			Use:   "list",
 			Short: fmt.Sprintf("List status of %s packages", m),
 			Args:  cobra.NoArgs,
 			Run:   generateListCmd(a, []shared.ManagerName{m}),
-		})
+		}
+		addOutputFlag(listCmd)
+		PmCmds[m].AddCommand(listCmd)
 	}
 
 	var listGlobalCmd = &cobra.Command{
 		Use:   "list",
-		Short: "List status of dnf packages",
+		Short: "List status of all packages",
 		Args:  cobra.NoArgs,
 		Run:   generateListCmd(a, a.ListManagers()),
 	}
+	addOutputFlag(listGlobalCmd)
 	rootCmd.AddCommand(listGlobalCmd)
 }
 
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "", fmt.Sprintf("Output format: %s or %s", app.OutputJSON, app.OutputYAML))
+	_ = cmd.RegisterFlagCompletionFunc("output", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{app.OutputJSON, app.OutputYAML}, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
 func generateListCmd(a app.AppFace, pms []shared.ManagerName) func(cmd *cobra.Command, args []string) {
 	return func(cmd *cobra.Command, _ []string) {
+		output := cmd.Flag("output").Value.String()
+		if output != "" {
+			if output != app.OutputJSON && output != app.OutputYAML {
+				log.Fatal().Msgf("unknown output format '%s'", output)
+			}
+			// Spinners and warnings would end up in the serialized output
+			pterm.DisableOutput()
+		}
+
 		status, err := a.ListStatus(cmd.Context(), pms)
 		if err != nil {
 			log.Fatal().Err(err).Msg("generateListCmd")
 		}
 
-		err = a.PrintPackageList(status)
+		if output != "" {
+			err = a.PrintReport(status, pms, output)
+		} else {
+			err = a.PrintPackageList(status)
+		}
 		if err != nil {
 			log.Fatal().Err(err).Msg("generateListCmd")
 		}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"github.com/lucas-ingemar/packtrak/internal/app"
+	"github.com/lucas-ingemar/packtrak/internal/config"
 	"github.com/lucas-ingemar/packtrak/internal/manifest"
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/rs/zerolog/log"
@@ -18,6 +19,7 @@ func initRemove(a app.AppFace) {
 			Run:               generateRemoveCmd(a, managerName),
 		}
 		removeCmd.PersistentFlags().BoolP("dependency", "d", false, "Remove dependency")
+		removeCmd.PersistentFlags().BoolVar(&config.DryRun, "dry-run", false, "Print the planned actions without changing the system, manifest or state")
 		PmCmds[managerName].AddCommand(removeCmd)
 	}
 }
@@ -50,7 +52,7 @@ func generateRemoveCmd(a app.AppFace, managerName shared.ManagerName) func(cmd *
 		}
 
 		if err := a.Remove(cmd.Context(), args, managerName, mType); err != nil {
-			log.Fatal().Err(err).Msg("generateRemoveCmd")
+			exitOnError(err, "generateRemoveCmd")
 		}
 	}
 }
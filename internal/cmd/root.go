@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -18,6 +19,12 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	ExitFatal          = 1
+	ExitPartialFailure = 2
+	ExitDrift          = 3
+)
+
 var PmCmds = map[shared.ManagerName]*cobra.Command{}
 
 var rootCmd = &cobra.Command{
@@ -37,12 +44,27 @@ func Execute() {
 	}
 }
 
+// exitOnError exits with ExitPartialFailure if some packages failed to sync,
+// ExitDrift if drift --check found drift, and with ExitFatal for any other
+// error.
+func exitOnError(err error, msg string) {
+	if errors.Is(err, app.ErrDrift) {
+		os.Exit(ExitDrift)
+	}
+	if errors.Is(err, app.ErrPartialSync) {
+		shared.PtermRemoved.Println(err.Error())
+		os.Exit(ExitPartialFailure)
+	}
+	log.Fatal().Err(err).Msg(msg)
+}
+
 func InitCmd() {
 	if shared.IsSudo() {
 		shared.PtermWarning.Println("This command can't be run under sudo. You will be prompted later if sudo is needed.")
 		os.Exit(1)
 	}
 
+	managers.RegisterExternalManagers()
 	managers.InitManagerConfig()
 	config.Refresh()
 	mf := managers.InitManagerFactory(managers.ManagersRegistered, true)
@@ -78,6 +100,15 @@ func InitCmd() {
 		log.Fatal().Err(err).Msg("InitCmd")
 	}
 
+	for _, section := range m.UnknownSections(managers.RegisteredNames()) {
+		shared.PtermWarning.Printfln("Manifest section '%s' does not match any manager and will be ignored", section)
+	}
+
+	lock, err := manifest.InitLock()
+	if err != nil {
+		log.Fatal().Err(err).Msg("InitCmd")
+	}
+
 	db, err := gorm.Open(sqlite.Open(config.StateFile), &gorm.Config{
 		// FIXME
 		// Logger: newLogger,
@@ -91,7 +122,11 @@ func InitCmd() {
 		log.Fatal().Err(err).Msg("InitCmd")
 	}
 
-	a := app.NewApp(mf, &m, s)
+	a := app.NewApp(mf, m, lock, s)
+	initAdopt(a)
+	initDrift(a)
+	initHistory(a)
+	initState(a)
 	initInstall(a)
 	initList(a)
 	initRemove(a)
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"github.com/lucas-ingemar/packtrak/internal/app"
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/state"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func initState(a app.AppFace) {
+	stateCmd := &cobra.Command{
+		Use:   "state",
+		Short: "Inspect and restore snapshots of the state",
+		Long:  "Every sync keeps a snapshot of the state database. List them, compare them with the current state, or restore one to undo a sync.",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List state snapshots",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			if err := a.PrintSnapshots(cmd.Flag("output").Value.String()); err != nil {
+				exitOnError(err, "initState")
+			}
+		},
+	}
+	addOutputFlag(listCmd)
+
+	diffCmd := &cobra.Command{
+		Use:               "diff <snapshot>",
+		Short:             "Compare a snapshot with the current state",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: snapshotValidArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := a.DiffSnapshot(cmd.Context(), args[0]); err != nil {
+				exitOnError(err, "initState")
+			}
+		},
+	}
+
+	restoreCmd := &cobra.Command{
+		Use:               "restore <snapshot>",
+		Short:             "Replace the current state with a snapshot",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: snapshotValidArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			sync := cmd.Flag("sync").Value.String() == "true"
+			if err := a.RestoreSnapshot(cmd.Context(), args[0], sync); err != nil {
+				exitOnError(err, "initState")
+			}
+		},
+	}
+	restoreCmd.Flags().Bool("sync", false, "Also install and remove packages to match the snapshot")
+	restoreCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Print the difference, and with --sync the planned actions, without changing anything")
+
+	stateCmd.AddCommand(listCmd, diffCmd, restoreCmd)
+	rootCmd.AddCommand(stateCmd)
+}
+
+func snapshotValidArgs(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	snapshots, err := state.ListSnapshots()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return lo.Map(snapshots, func(s state.Snapshot, _ int) string {
+		return s.Name
+	}), cobra.ShellCompDirectiveNoFileComp
+}
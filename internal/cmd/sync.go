@@ -2,7 +2,7 @@ package cmd
 
 import (
 	"github.com/lucas-ingemar/packtrak/internal/app"
-	"github.com/rs/zerolog/log"
+	"github.com/lucas-ingemar/packtrak/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -14,9 +14,12 @@ func initSync(a app.AppFace) {
 		Run: func(cmd *cobra.Command, _ []string) {
 			err := a.Sync(cmd.Context(), a.ListManagers())
 			if err != nil {
-				log.Fatal().Err(err).Msg("initSync")
+				exitOnError(err, "initSync")
 			}
 		},
 	}
+	syncCmd.Flags().BoolVar(&config.Locked, "locked", false, "Install the versions recorded in manifest.lock instead of resolving the latest")
+	syncCmd.Flags().IntVarP(&config.Jobs, "jobs", "j", config.Jobs, "Number of packages to sync at the same time, for managers that don't need sudo")
+	syncCmd.Flags().BoolVar(&config.DryRun, "dry-run", false, "Print the planned actions without changing the system, manifest or state")
 	rootCmd.AddCommand(syncCmd)
 }
@@ -19,6 +19,7 @@ var (
 	CacheDir     string
 	ConfigFile   string
 	ManifestFile string
+	LockFile     string
 	StateFile    string
 
 	ConfigFileExists bool
@@ -26,17 +27,23 @@ var (
 	Version string
 	RepoUrl string
 
-	CompactPrint   bool
-	Groups         []string
-	StateRotations int
+	CompactPrint       bool
+	Groups             []string
+	ManifestGroupFiles map[string]string
+	StateRotations     int
+	Jobs               int
 
 	AssumeYes *bool
+	DryRun    bool
+	Locked    bool
 )
 
 const (
 	keyCompactPrint   = "compact_print"
 	keyGroups         = "groups"
+	keyManifestGroups = "manifest_group_files"
 	keyStateRotations = "state_rotations"
+	keyJobs           = "jobs"
 	keyVersion        = "_version"
 )
 
@@ -44,9 +51,10 @@ func Refresh() {
 	viper.SetEnvPrefix("packtrak")
 	viper.AutomaticEnv()
 
-	ConfigDir = filepath.Join(xdg.ConfigHome, "packtrak")
+	ConfigDir = DefaultConfigDir()
 	ConfigFile = filepath.Join(ConfigDir, "config.yaml")
 	ManifestFile = filepath.Join(ConfigDir, "manifest.yaml")
+	LockFile = filepath.Join(ConfigDir, "manifest.lock")
 
 	CacheDir = filepath.Join(xdg.CacheHome, "packtrak")
 
@@ -70,7 +78,9 @@ func Refresh() {
 	StateFile = filepath.Join(DataDir, "state.db")
 
 	Groups = getViperStringSliceWithDefault(keyGroups, []string{})
+	ManifestGroupFiles = getViperStringMapStringWithDefault(keyManifestGroups, map[string]string{})
 	StateRotations = getViperIntWithDefault(keyStateRotations, 3)
+	Jobs = getViperIntWithDefault(keyJobs, 1)
 
 	CompactPrint = getViperBoolWithDefault(keyCompactPrint, false)
 
@@ -94,12 +104,21 @@ func Refresh() {
 	}
 }
 
+func DefaultConfigDir() string {
+	return filepath.Join(xdg.ConfigHome, "packtrak")
+}
+
 func CheckConfig() {
 	if StateRotations > 10 {
 		viper.Set(keyStateRotations, 10)
 		shared.PtermWarning.Printfln("'%s' (%d) has a limit of 10. Value set to 10.", keyStateRotations, StateRotations)
 	}
 
+	if Jobs < 1 {
+		shared.PtermWarning.Printfln("'%s' (%d) must be at least 1. Value set to 1.", keyJobs, Jobs)
+		Jobs = 1
+	}
+
 }
 
 func getViperBoolWithDefault(key string, defaultValue bool) bool {
@@ -122,6 +141,11 @@ func getViperStringSliceWithDefault(key string, defaultValue []string) []string
 	return viper.GetStringSlice(key)
 }
 
+func getViperStringMapStringWithDefault(key string, defaultValue map[string]string) map[string]string {
+	viper.SetDefault(key, defaultValue)
+	return viper.GetStringMapString(key)
+}
+
 func configFileExists() bool {
 	_, err := os.Stat(ConfigFile)
 	return !os.IsNotExist(err)
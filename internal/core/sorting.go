@@ -19,7 +19,7 @@ func FilterIncomingObjects(pkgs []string, pmManifest manifest.PmManifest, mType
 		} else {
 			objs = pkgs
 		}
-		if lo.Contains(objs, arg) {
+		if lo.Contains(shared.UnpinAll(objs), shared.Unpin(arg)) {
 			shared.PtermWarning.Printfln("'%s' is already present in manifest", arg)
 			continue
 		}
@@ -0,0 +1,383 @@
+package apt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/samber/lo"
+)
+
+func New() *Apt {
+	return &Apt{
+		&commandExecutor{},
+	}
+}
+
+const Name shared.ManagerName = "apt"
+
+type Apt struct {
+	CommandExecutorFace
+}
+
+func (a *Apt) Name() shared.ManagerName {
+	return Name
+}
+
+func (a *Apt) Icon() string {
+	return ""
+}
+
+func (a *Apt) ShortDesc() string {
+	return "A package manager for Debian-based Linux distributions"
+}
+
+func (a *Apt) LongDesc() string {
+	return "APT is the package manager of Debian, Ubuntu and their derivatives. Packages are installed and removed with apt-get, and PPAs and repository files can be added as dependencies."
+}
+
+func (a *Apt) NeedsSudo() []shared.CommandName {
+	return []shared.CommandName{shared.CommandInstall, shared.CommandRemove, shared.CommandSync}
+}
+
+func (a *Apt) SupportsPins() bool {
+	return true
+}
+
+func (a *Apt) InitCheckCmd() error {
+	_, err := exec.LookPath("apt-get")
+	if err != nil {
+		return errors.New("'apt-get' command not found on the computer")
+	}
+	return nil
+}
+
+func (a *Apt) InitConfig() {
+}
+
+func (a *Apt) InitCheckConfig() error {
+	return nil
+}
+
+func (a *Apt) GetPackageNames(ctx context.Context, packages []string) []string {
+	return shared.UnpinAll(packages)
+}
+
+func (a *Apt) GetDependencyNames(ctx context.Context, deps []string) []string {
+	fixedDeps := []string{}
+	for _, d := range deps {
+		fixedDeps = append(fixedDeps, strings.SplitN(d, ":", 2)[1])
+	}
+	return fixedDeps
+}
+
+func (a *Apt) AddPackages(ctx context.Context, pkgsToAdd []string) (packagesUpdated []string, userWarnings []string, err error) {
+	for _, pkg := range pkgsToAdd {
+		isSysPkg, err := a.isSystemPackage(ctx, shared.Unpin(pkg))
+		if err != nil {
+			return packagesUpdated, []string{}, err
+		}
+
+		if !isSysPkg {
+			packagesUpdated = append(packagesUpdated, pkg)
+		} else {
+			userWarnings = append(userWarnings, fmt.Sprintf("'%s' is a system package and cannot be managed", pkg))
+		}
+	}
+	return packagesUpdated, userWarnings, nil
+}
+
+func (a *Apt) AddDependencies(ctx context.Context, depsToAdd []string) (depsUpdated []string, userWarnings []string, err error) {
+	for _, dep := range depsToAdd {
+		if strings.HasPrefix(dep, "ppa:") || strings.HasPrefix(dep, "cm:") {
+			depsUpdated = append(depsUpdated, dep)
+		} else {
+			userWarnings = append(userWarnings, fmt.Sprintf("Dependency '%s' has an incorrect format", dep))
+		}
+	}
+	return
+}
+
+func (a *Apt) InstallValidArgs(ctx context.Context, toComplete string, dependencies bool) ([]string, error) {
+	if dependencies {
+		return []string{}, nil
+	}
+
+	res, err := shared.Command(ctx, "apt-cache", []string{"pkgnames", toComplete}, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Fields(res), nil
+}
+
+func (a *Apt) ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error) {
+	installedPpas, err := a.ListPpas(ctx)
+	if err != nil {
+		return status.DependenciesStatus{}, err
+	}
+
+	installedCms, err := a.ListCm(ctx)
+	if err != nil {
+		return status.DependenciesStatus{}, err
+	}
+
+	isInstalled := func(dep shared.Dependency) bool {
+		if strings.HasPrefix(dep.FullName, "ppa:") {
+			return lo.Contains(installedPpas, dep.Name)
+		}
+		return lo.ContainsBy(installedCms, func(aptDep string) bool {
+			return strings.HasSuffix(dep.Name, aptDep)
+		})
+	}
+
+	for _, dep := range a.parseDeps(deps) {
+		if isInstalled(dep) {
+			depStatus.Synced = append(depStatus.Synced, dep)
+		} else {
+			depStatus.Missing = append(depStatus.Missing, dep)
+		}
+	}
+
+	for _, dep := range a.parseDeps(stateDeps) {
+		if isInstalled(dep) && !lo.Contains(deps, dep.FullName) {
+			depStatus.Removed = append(depStatus.Removed, dep)
+		}
+	}
+
+	return
+}
+
+func (a *Apt) ListPackages(ctx context.Context, packages []string, statePkgs []string) (packageStatus status.PackageStatus, err error) {
+	aptList, aptVersions, err := a.ListInstalledPkgs(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range packages {
+		pkg, pin := shared.SplitPin(entry)
+		idx := lo.IndexOf(aptList, pkg)
+		if idx == -1 {
+			packageStatus.Missing = append(packageStatus.Missing, shared.Package{Name: pkg, FullName: pkg, Pin: pin})
+			continue
+		}
+
+		iPkg := shared.Package{Name: pkg, FullName: pkg, Version: aptVersions[idx], Pin: pin}
+		if pin != "" && !matchAptPin(pin, iPkg.Version) {
+			iPkg.LatestVersion = pin
+			packageStatus.Updated = append(packageStatus.Updated, iPkg)
+		} else {
+			packageStatus.Synced = append(packageStatus.Synced, iPkg)
+		}
+	}
+
+	unpinned := shared.UnpinAll(packages)
+	for _, pkg := range statePkgs {
+		if lo.Contains(aptList, pkg) && !lo.Contains(unpinned, pkg) {
+			packageStatus.Removed = append(packageStatus.Removed, shared.Package{Name: pkg, FullName: pkg})
+		}
+	}
+
+	return
+}
+
+func (a *Apt) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	userPkgs, err := a.ListUserInstalledPkgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range userPkgs {
+		packages = append(packages, shared.Package{Name: pkg, FullName: pkg})
+	}
+	return
+}
+
+func (a *Apt) RemovePackages(ctx context.Context, allPkgs []string, pkgs []string) (packagesToRemove []string, userWarnings []string, err error) {
+	for _, pkg := range pkgs {
+		var isSysPkg bool
+		isSysPkg, err = a.isSystemPackage(ctx, pkg)
+		if err != nil {
+			return
+		}
+
+		if isSysPkg {
+			userWarnings = append(userWarnings, fmt.Sprintf("'%s' is a system package and cannot be managed", pkg))
+			continue
+		}
+		packagesToRemove = append(packagesToRemove, pkg)
+	}
+
+	return
+}
+
+func (a *Apt) RemoveDependencies(ctx context.Context, allDeps []string, depsToRemove []string) (depsUpdated []string, userWarnings []string, err error) {
+	for _, rDep := range depsToRemove {
+		for _, aDep := range allDeps {
+			if strings.SplitN(aDep, ":", 2)[1] == rDep {
+				depsUpdated = append(depsUpdated, aDep)
+			}
+		}
+	}
+	return
+}
+
+// SyncDependencies adds and removes PPAs and repository files. The package
+// lists are downloaded again after a repository file changed, add-apt-repository
+// does that by itself.
+func (a *Apt) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	cmsChanged := false
+	sync := func(deps []shared.Dependency, action shared.PtermSpinnerStatus, ppaFn, cmFn func(ctx context.Context, name string) error) {
+		for _, dep := range deps {
+			fn := cmFn
+			if strings.HasPrefix(dep.FullName, "ppa:") {
+				fn = ppaFn
+			}
+			err := shared.PtermSpinner(action, dep.Name, func() error {
+				return fn(ctx, dep.Name)
+			})
+			if err != nil {
+				failures = append(failures, shared.SyncFailure{Manager: Name, Name: dep.Name, Action: action, Err: err})
+				continue
+			}
+			if strings.HasPrefix(dep.FullName, "cm:") {
+				cmsChanged = true
+			}
+		}
+	}
+	sync(depStatus.Missing, shared.PtermSpinnerInstall, a.InstallPpa, a.InstallCm)
+	sync(depStatus.Removed, shared.PtermSpinnerRemove, a.RemovePpa, a.RemoveCm)
+
+	if cmsChanged {
+		if err := a.UpdateIndex(ctx); err != nil {
+			userWarnings = append(userWarnings, fmt.Sprintf("Could not update the package lists: %s", err))
+		}
+	}
+	return
+}
+
+// SyncPackages installs and removes packages in one apt-get run each, so a
+// failing run is reported as a failure for every package in it. Updated
+// packages are the ones not matching their pin, apt-get installs the pinned
+// version in place of the installed one. A pinned system package fails, since
+// installing it would mark it as manually installed.
+func (a *Apt) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = append(failures, shared.SyncTransaction(ctx, Name, a.filterSystemPackages(ctx, packageStatus.Missing), shared.PtermSpinnerInstall, a.InstallPkg)...)
+	updated := a.filterSystemPackages(ctx, packageStatus.Updated)
+	for _, pkg := range packageStatus.Updated {
+		if !lo.Contains(updated, pkg) {
+			failures = append(failures, shared.SyncFailure{Manager: Name, Name: pkg.Name, Action: shared.PtermSpinnerUpdate, Err: fmt.Errorf("'%s' is a system package and cannot be managed", pkg.FullName)})
+		}
+	}
+	failures = append(failures, shared.SyncTransaction(ctx, Name, updated, shared.PtermSpinnerUpdate, a.InstallPkg)...)
+	failures = append(failures, shared.SyncTransaction(ctx, Name, a.filterSystemPackages(ctx, packageStatus.Removed), shared.PtermSpinnerRemove, a.RemovePkg)...)
+	return
+}
+
+func (a *Apt) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	cmsChanged := false
+	for _, dep := range depStatus.Missing {
+		if strings.HasPrefix(dep.FullName, "ppa:") {
+			actions = append(actions, shared.CommandString("sudo", ppaArgs("add", dep.Name)))
+		} else if strings.HasPrefix(dep.FullName, "cm:") {
+			repoFileName, err := cmRepoFileName(dep.Name)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, fmt.Sprintf("download %s to %s", dep.Name, repoFileName))
+			cmsChanged = true
+		}
+	}
+
+	for _, dep := range depStatus.Removed {
+		if strings.HasPrefix(dep.FullName, "ppa:") {
+			actions = append(actions, shared.CommandString("sudo", ppaArgs("remove", dep.Name)))
+		} else if strings.HasPrefix(dep.FullName, "cm:") {
+			repoFileName, err := cmRepoFileName(dep.Name)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, shared.CommandString("sudo", []string{"rm", repoFileName}))
+			cmsChanged = true
+		}
+	}
+
+	if cmsChanged {
+		actions = append(actions, shared.CommandString("sudo", []string{"apt-get", "update"}))
+	}
+	return
+}
+
+func (a *Apt) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	if pkgs := a.filterSystemPackages(ctx, packageStatus.Missing); len(pkgs) > 0 {
+		actions = append(actions, shared.CommandString("sudo", pkgArgs("install", pkgs)))
+	}
+
+	if pkgs := a.filterSystemPackages(ctx, packageStatus.Updated); len(pkgs) > 0 {
+		actions = append(actions, shared.CommandString("sudo", pkgArgs("install", pkgs)))
+	}
+
+	if pkgs := a.filterSystemPackages(ctx, packageStatus.Removed); len(pkgs) > 0 {
+		actions = append(actions, shared.CommandString("sudo", pkgArgs("remove", pkgs)))
+	}
+	return
+}
+
+// matchAptPin matches the pin against the installed version, with or without
+// the epoch and Debian revision, e.g. '1:14.1.0-1ubuntu1'
+func matchAptPin(pin string, version string) bool {
+	if _, v, found := strings.Cut(version, ":"); found {
+		version = v
+	}
+	v := version
+	if idx := strings.LastIndex(version, "-"); idx > 0 {
+		v = version[:idx]
+	}
+	return shared.MatchPin(pin, version) || shared.MatchPin(pin, v)
+}
+
+func (a *Apt) filterSystemPackages(ctx context.Context, pkgs []shared.Package) []shared.Package {
+	return lo.Filter(pkgs, func(item shared.Package, _ int) bool {
+		isSysPkg, err := a.isSystemPackage(ctx, item.FullName)
+		if err != nil || isSysPkg {
+			return false
+		}
+		return true
+	})
+}
+
+// isSystemPackage reports if the package is installed, but not marked as
+// manually installed, i.e. it was pulled in by another package
+func (a *Apt) isSystemPackage(ctx context.Context, pkg string) (bool, error) {
+	allPkgs, _, err := a.ListInstalledPkgs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	userPkgs, err := a.ListUserInstalledPkgs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if lo.Contains(allPkgs, pkg) && !lo.Contains(userPkgs, pkg) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (a *Apt) parseDeps(deps []string) (parsed []shared.Dependency) {
+	for _, dep := range deps {
+		sDep := strings.SplitN(dep, ":", 2)
+		switch sDep[0] {
+		case "ppa", "cm":
+			parsed = append(parsed, shared.Dependency{Name: sDep[1], FullName: dep})
+		default:
+			shared.PtermWarning.Printfln("Dependency has bad format: %s. Ignoring...", dep)
+		}
+	}
+	return
+}
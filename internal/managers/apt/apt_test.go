@@ -0,0 +1,157 @@
+package apt
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDpkgQuery(t *testing.T) {
+	pkgs, versions := parseDpkgQuery("ii  ripgrep 14.1.0-1\nrc  vim 2:9.1.0016-1ubuntu7\nii  zsh 5.9-6ubuntu2\n\n")
+	assert.Equal(t, []string{"ripgrep", "zsh"}, pkgs, "removed packages should be left out")
+	assert.Equal(t, []string{"14.1.0-1", "5.9-6ubuntu2"}, versions)
+}
+
+func TestParsePpas(t *testing.T) {
+	ppas := parsePpas(`deb https://ppa.launchpadcontent.net/neovim-ppa/unstable/ubuntu/ noble main
+# deb-src https://ppa.launchpadcontent.net/old/ppa/ubuntu/ noble main
+URIs: http://ppa.launchpad.net/git-core/ppa/ubuntu
+`)
+	assert.Equal(t, []string{"neovim-ppa/unstable", "git-core/ppa"}, ppas)
+}
+
+func TestAptPins(t *testing.T) {
+	assert.True(t, matchAptPin("9.1.x", "2:9.1.0016-1ubuntu7"))
+	assert.True(t, matchAptPin("9.1.0016-1ubuntu7", "2:9.1.0016-1ubuntu7"))
+	assert.False(t, matchAptPin("9.0.x", "2:9.1.0016-1ubuntu7"))
+
+	assumeYes := true
+	config.AssumeYes = &assumeYes
+	assert.Equal(t, []string{"apt-get", "install", "--yes", "--allow-downgrades", "ripgrep=14.*", "zsh"},
+		pkgArgs("install", []shared.Package{{FullName: "ripgrep", Pin: "14.x"}, {FullName: "zsh"}}))
+
+	_, err := cmRepoFileName("https://example.com/repo.gpg")
+	assert.NotNil(t, err, "only .list and .sources files should be accepted")
+	name, err := cmRepoFileName("https://example.com/apt/hashicorp.sources")
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, "/etc/apt/sources.list.d/packtrak_hashicorp.sources", name)
+}
+
+// fakeExecutor has ripgrep installed by the user, and libpcre2 as a dependency
+// of it, and two PPAs and a repository file added. It records the packages it
+// is asked to install, and the repository changes.
+type fakeExecutor struct {
+	commandExecutor
+	installed [][]string
+	commands  []string
+}
+
+func (f *fakeExecutor) ListPpas(ctx context.Context) ([]string, error) {
+	return []string{"neovim-ppa/unstable", "old/ppa"}, nil
+}
+
+func (f *fakeExecutor) ListCm(ctx context.Context) ([]string, error) {
+	return []string{"hashicorp.sources"}, nil
+}
+
+func (f *fakeExecutor) InstallPpa(ctx context.Context, ppa string) error {
+	f.commands = append(f.commands, "add "+ppa)
+	return nil
+}
+
+func (f *fakeExecutor) RemovePpa(ctx context.Context, ppa string) error {
+	f.commands = append(f.commands, "remove "+ppa)
+	return nil
+}
+
+func (f *fakeExecutor) InstallCm(ctx context.Context, cm string) error {
+	f.commands = append(f.commands, "download "+cm)
+	return nil
+}
+
+func (f *fakeExecutor) RemoveCm(ctx context.Context, cm string) error {
+	f.commands = append(f.commands, "delete "+cm)
+	return nil
+}
+
+func (f *fakeExecutor) UpdateIndex(ctx context.Context) error {
+	f.commands = append(f.commands, "update")
+	return nil
+}
+
+func (f *fakeExecutor) ListInstalledPkgs(ctx context.Context) ([]string, []string, error) {
+	return []string{"libpcre2", "ripgrep"}, []string{"10.42-4", "14.1.0-1"}, nil
+}
+
+func (f *fakeExecutor) ListUserInstalledPkgs(ctx context.Context) ([]string, error) {
+	return []string{"ripgrep"}, nil
+}
+
+func (f *fakeExecutor) InstallPkg(ctx context.Context, pkgs []shared.Package) error {
+	f.installed = append(f.installed, shared.FullNames(pkgs))
+	return nil
+}
+
+func TestSyncSystemPackages(t *testing.T) {
+	executor := &fakeExecutor{}
+	a := &Apt{executor}
+	ctx := context.Background()
+
+	failures, _, err := a.SyncPackages(ctx, status.PackageStatus{
+		Updated: []shared.Package{
+			{Name: "ripgrep", FullName: "ripgrep", Pin: "14.0.x"},
+			{Name: "libpcre2", FullName: "libpcre2", Pin: "10.40.x"},
+		},
+	})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, [][]string{{"ripgrep"}}, executor.installed)
+	assert.Len(t, failures, 1)
+	assert.Equal(t, "libpcre2", failures[0].Name, "a pinned system package should fail")
+}
+
+func TestSyncDependencies(t *testing.T) {
+	pterm.DisableOutput()
+	t.Cleanup(pterm.EnableOutput)
+
+	executor := &fakeExecutor{}
+	a := &Apt{executor}
+	ctx := context.Background()
+
+	depStatus, err := a.ListDependencies(ctx, []string{
+		"ppa:neovim-ppa/unstable",
+		"cm:https://download.docker.com/linux/ubuntu/docker.list",
+	}, []string{"ppa:old/ppa", "ppa:gone/ppa", "cm:https://apt.releases.hashicorp.com/hashicorp.sources"})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"ppa:neovim-ppa/unstable"}, fullNames(depStatus.Synced))
+	assert.Equal(t, []string{"cm:https://download.docker.com/linux/ubuntu/docker.list"}, fullNames(depStatus.Missing))
+	assert.Equal(t, []string{"ppa:old/ppa", "cm:https://apt.releases.hashicorp.com/hashicorp.sources"}, fullNames(depStatus.Removed), "gone/ppa is already removed")
+
+	failures, _, err := a.SyncDependencies(ctx, depStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Empty(t, failures)
+	assert.Equal(t, []string{
+		"download https://download.docker.com/linux/ubuntu/docker.list",
+		"remove old/ppa",
+		"delete https://apt.releases.hashicorp.com/hashicorp.sources",
+		"update",
+	}, executor.commands, "the package lists should be updated once after the repository files changed")
+
+	executor.commands = nil
+	depStatus.Missing = nil
+	depStatus.Removed = depStatus.Removed[:1]
+	_, _, err = a.SyncDependencies(ctx, depStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"remove old/ppa"}, executor.commands, "add-apt-repository updates the package lists by itself")
+}
+
+func fullNames(deps []shared.Dependency) (names []string) {
+	for _, dep := range deps {
+		names = append(names, dep.FullName)
+	}
+	return
+}
@@ -0,0 +1,348 @@
+package apt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/alexellis/go-execute/v2"
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+)
+
+type CommandExecutorFace interface {
+	InstallPkg(ctx context.Context, pkgs []shared.Package) error
+	RemovePkg(ctx context.Context, pkgs []shared.Package) error
+	ListInstalledPkgs(ctx context.Context) ([]string, []string, error)
+	ListUserInstalledPkgs(ctx context.Context) ([]string, error)
+	UpdateIndex(ctx context.Context) error
+	InstallCm(ctx context.Context, cm string) error
+	RemoveCm(ctx context.Context, cm string) error
+	ListCm(ctx context.Context) (packages []string, err error)
+	InstallPpa(ctx context.Context, ppa string) error
+	RemovePpa(ctx context.Context, ppa string) error
+	ListPpas(ctx context.Context) ([]string, error)
+}
+
+type commandExecutor struct {
+	cacheAllInstalled         []string
+	cacheAllInstalledVersions []string
+	cacheUserInstalled        []string
+	cachePpas                 []string
+}
+
+const (
+	sourcesFolder = "/etc/apt/sources.list.d"
+	// apt ignores files in sources.list.d with other characters than letters,
+	// digits, '_', '-' and '.', so the prefix can't be '_packtrak:' as for dnf
+	repoFilePrefix = "packtrak_"
+)
+
+var rPpa = regexp.MustCompile(`ppa\.launchpad(?:content)?\.net/([^/\s]+)/([^/\s]+)`)
+
+func pkgArgs(action string, pkgs []shared.Package) []string {
+	cmds := []string{"apt-get", action}
+	if *config.AssumeYes {
+		cmds = append(cmds, "--yes")
+	}
+	// A pin may be older than the installed version
+	if lo.ContainsBy(pkgs, func(pkg shared.Package) bool { return pkg.Pin != "" }) {
+		cmds = append(cmds, "--allow-downgrades")
+	}
+
+	for _, pkg := range pkgs {
+		cmds = append(cmds, pkgSpec(pkg))
+	}
+	return cmds
+}
+
+// pkgSpec returns the package as given to apt-get, with the pinned version if
+// any
+func pkgSpec(pkg shared.Package) string {
+	if pkg.Pin == "" {
+		return pkg.FullName
+	}
+	return pkg.FullName + "=" + shared.PinGlob(pkg.Pin)
+}
+
+func ppaArgs(action string, ppa string) []string {
+	cmds := []string{"add-apt-repository", "--yes"}
+	if action == "remove" {
+		cmds = append(cmds, "--remove")
+	}
+	return append(cmds, "ppa:"+ppa)
+}
+
+// clearCache forgets the listed packages and PPAs, it is called when the
+// system is changed so that they are listed again
+func (a *commandExecutor) clearCache() {
+	a.cacheAllInstalled = nil
+	a.cacheAllInstalledVersions = nil
+	a.cacheUserInstalled = nil
+	a.cachePpas = nil
+}
+
+func cmRepoFileName(cm string) (string, error) {
+	u, err := url.ParseRequestURI(cm)
+	if err != nil {
+		return "", fmt.Errorf("not an url: %s, %s", cm, err)
+	}
+	base := path.Base(u.Path)
+	if !strings.HasSuffix(base, ".list") && !strings.HasSuffix(base, ".sources") {
+		return "", fmt.Errorf("not a .list or .sources file: %s", cm)
+	}
+	return path.Join(sourcesFolder, fmt.Sprintf("%s%s", repoFilePrefix, base)), nil
+}
+
+func (a *commandExecutor) InstallPkg(ctx context.Context, pkgs []shared.Package) error {
+	if len(pkgs) == 0 {
+		return errors.New("no packages provided")
+	}
+	defer a.clearCache()
+
+	cmd := execute.ExecTask{
+		Command:     "sudo",
+		Args:        pkgArgs("install", pkgs),
+		StreamStdio: true,
+		Stdin:       os.Stdin,
+	}
+
+	res, err := cmd.Execute(ctx)
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		return errors.New("Non-zero exit code: " + res.Stderr)
+	}
+
+	return nil
+}
+
+func (a *commandExecutor) RemovePkg(ctx context.Context, pkgs []shared.Package) error {
+	if len(pkgs) == 0 {
+		return errors.New("no packages provided")
+	}
+	defer a.clearCache()
+
+	cmd := execute.ExecTask{
+		Command:     "sudo",
+		Args:        pkgArgs("remove", pkgs),
+		StreamStdio: true,
+		Stdin:       os.Stdin,
+	}
+
+	res, err := cmd.Execute(ctx)
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		return errors.New("Non-zero exit code: " + res.Stderr)
+	}
+
+	return nil
+}
+
+// UpdateIndex downloads the package lists, e.g. after a repository is added
+func (a *commandExecutor) UpdateIndex(ctx context.Context) error {
+	_, err := shared.Command(ctx, "sudo", []string{"apt-get", "update"}, true, os.Stdin)
+	return err
+}
+
+func (a *commandExecutor) InstallCm(ctx context.Context, cm string) error {
+	repoFileName, err := cmRepoFileName(cm)
+	if err != nil {
+		return err
+	}
+	cacheRepoFileName := path.Join(config.CacheDir, path.Base(repoFileName))
+
+	res, err := http.Get(cm)
+	if err != nil {
+		return fmt.Errorf("error making http request: %s", err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("client: could not read response body: %s", err)
+	}
+
+	err = os.WriteFile(cacheRepoFileName, resBody, 0644)
+	if err != nil {
+		return fmt.Errorf("apt repo: could not write file %s: %s", repoFileName, err)
+	}
+
+	_, err = shared.Command(ctx, "sudo", []string{"chown", "root:root", cacheRepoFileName}, false, nil)
+	if err != nil {
+		return fmt.Errorf("could not chown %s: %s", cacheRepoFileName, err)
+	}
+
+	_, err = shared.Command(ctx, "sudo", []string{"mv", cacheRepoFileName, repoFileName}, false, nil)
+	if err != nil {
+		return fmt.Errorf("could not move %s: %s", repoFileName, err)
+	}
+
+	return nil
+}
+
+func (a *commandExecutor) ListCm(ctx context.Context) (packages []string, err error) {
+	cms, err := os.ReadDir(sourcesFolder)
+	if err != nil {
+		return []string{}, err
+	}
+
+	for _, e := range cms {
+		if e.IsDir() {
+			continue
+		}
+		if strings.HasPrefix(e.Name(), repoFilePrefix) {
+			packages = append(packages, strings.TrimPrefix(e.Name(), repoFilePrefix))
+		}
+	}
+	return
+}
+
+func (a *commandExecutor) RemoveCm(ctx context.Context, cm string) error {
+	repoFileName, err := cmRepoFileName(cm)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stat(repoFileName)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("remove cm: %s, file does not exist", cm)
+	}
+
+	_, err = shared.Command(ctx, "sudo", []string{"rm", repoFileName}, false, nil)
+	return err
+}
+
+func (a *commandExecutor) InstallPpa(ctx context.Context, ppa string) error {
+	defer a.clearCache()
+	_, err := shared.Command(ctx, "sudo", ppaArgs("add", ppa), false, nil)
+	return err
+}
+
+func (a *commandExecutor) RemovePpa(ctx context.Context, ppa string) error {
+	defer a.clearCache()
+	_, err := shared.Command(ctx, "sudo", ppaArgs("remove", ppa), false, nil)
+	return err
+}
+
+// ListPpas returns the PPAs, as 'user/ppa', found in the sources of apt
+func (a *commandExecutor) ListPpas(ctx context.Context) ([]string, error) {
+	if len(a.cachePpas) > 0 {
+		return a.cachePpas, nil
+	}
+
+	files, err := os.ReadDir(sourcesFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range files {
+		if e.IsDir() || !(strings.HasSuffix(e.Name(), ".list") || strings.HasSuffix(e.Name(), ".sources")) {
+			continue
+		}
+		content, err := os.ReadFile(path.Join(sourcesFolder, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		a.cachePpas = lo.Uniq(append(a.cachePpas, parsePpas(string(content))...))
+	}
+	return a.cachePpas, nil
+}
+
+// parsePpas returns the PPAs in the lines of a sources file that are not
+// commented out
+func parsePpas(content string) (ppas []string) {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, match := range rPpa.FindAllStringSubmatch(line, -1) {
+			ppas = append(ppas, match[1]+"/"+match[2])
+		}
+	}
+	return
+}
+
+func (a *commandExecutor) ListInstalledPkgs(ctx context.Context) ([]string, []string, error) {
+	if len(a.cacheAllInstalled) > 0 && len(a.cacheAllInstalledVersions) > 0 {
+		return a.cacheAllInstalled, a.cacheAllInstalledVersions, nil
+	}
+
+	cmd := execute.ExecTask{
+		Command:     "dpkg-query",
+		Args:        []string{"--show", "--showformat", "${db:Status-Abbrev} ${Package} ${Version}\n"},
+		StreamStdio: false,
+	}
+
+	res, err := cmd.Execute(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, nil, errors.New("Non-zero exit code: " + res.Stderr)
+	}
+
+	a.cacheAllInstalled, a.cacheAllInstalledVersions = parseDpkgQuery(res.Stdout)
+	return a.cacheAllInstalled, a.cacheAllInstalledVersions, nil
+}
+
+// parseDpkgQuery returns the names and versions of the installed packages,
+// leaving out the ones that are removed but still have their config files
+func parseDpkgQuery(out string) (pkgs []string, versions []string) {
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 2 || parts[0] != "ii" {
+			continue
+		}
+		pkgs = append(pkgs, parts[1])
+		if len(parts) > 2 {
+			versions = append(versions, parts[2])
+		} else {
+			versions = append(versions, "")
+		}
+	}
+	return
+}
+
+func (a *commandExecutor) ListUserInstalledPkgs(ctx context.Context) ([]string, error) {
+	if len(a.cacheUserInstalled) > 0 {
+		return a.cacheUserInstalled, nil
+	}
+
+	cmd := execute.ExecTask{
+		Command:     "apt-mark",
+		Args:        []string{"showmanual"},
+		StreamStdio: false,
+	}
+
+	res, err := cmd.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, errors.New("Non-zero exit code: " + res.Stderr)
+	}
+
+	for _, pkg := range strings.Split(res.Stdout, "\n") {
+		// Packages of another architecture are listed as 'name:arch'
+		name, _, _ := strings.Cut(strings.TrimSpace(pkg), ":")
+		if name != "" {
+			a.cacheUserInstalled = append(a.cacheUserInstalled, name)
+		}
+	}
+
+	return a.cacheUserInstalled, nil
+}
@@ -0,0 +1,250 @@
+package cargo
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path"
+	"slices"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/samber/lo"
+)
+
+func New() *Cargo {
+	return &Cargo{
+		&commandExecutor{},
+	}
+}
+
+const Name shared.ManagerName = "cargo"
+
+type Cargo struct {
+	CommandExecutorFace
+}
+
+func (c *Cargo) Name() shared.ManagerName {
+	return Name
+}
+
+func (c *Cargo) Icon() string {
+	return ""
+}
+
+func (c *Cargo) ShortDesc() string {
+	return "Compile and install Rust binaries"
+}
+
+func (c *Cargo) LongDesc() string {
+	return "Compile and install Rust binaries from crates.io or git repositories with cargo install"
+}
+
+func (c *Cargo) NeedsSudo() []shared.CommandName {
+	return []shared.CommandName{}
+}
+
+func (c *Cargo) SupportsPins() bool {
+	return true
+}
+
+func (c *Cargo) InitCheckCmd() error {
+	_, err := exec.LookPath("cargo")
+	if err != nil {
+		return errors.New("'cargo' command not found on the computer")
+	}
+	return nil
+}
+
+func (c *Cargo) InitCheckConfig() error {
+	return nil
+}
+
+func (c *Cargo) InitConfig() {
+}
+
+func (c *Cargo) GetPackageNames(ctx context.Context, packages []string) []string {
+	pkgNames := []string{}
+	for _, pkg := range packages {
+		pkgNames = append(pkgNames, parseEntry(shared.Unpin(pkg)).Name)
+	}
+	return pkgNames
+}
+
+func (c *Cargo) GetDependencyNames(ctx context.Context, deps []string) []string {
+	return []string{}
+}
+
+func (c *Cargo) AddPackages(ctx context.Context, pkgsToAdd []string) (packagesUpdated []string, userWarnings []string, err error) {
+	return pkgsToAdd, nil, nil
+}
+
+func (c *Cargo) AddDependencies(ctx context.Context, depsToAdd []string) (depsUpdated []string, userWarnings []string, err error) {
+	return
+}
+
+func (c *Cargo) InstallValidArgs(ctx context.Context, toComplete string, dependencies bool) ([]string, error) {
+	return []string{}, nil
+}
+
+func (c *Cargo) ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error) {
+	return
+}
+
+func (c *Cargo) ListPackages(ctx context.Context, packages []string, statePkgs []string) (packageStatus status.PackageStatus, err error) {
+	installed, err := c.ListInstalled(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, pkg := range packages {
+		pkgFullName, pin := shared.SplitPin(pkg)
+		mCrate := parseEntry(pkgFullName)
+		iCrate, found := lo.Find(installed, mCrate.matches)
+		if !found {
+			packageStatus.Missing = append(packageStatus.Missing, shared.Package{
+				Name:     mCrate.Name,
+				FullName: pkgFullName,
+				Pin:      pin,
+				RepoUrl:  mCrate.Git,
+			})
+			continue
+		}
+
+		iPkg := shared.Package{
+			Name:     iCrate.Name,
+			FullName: pkgFullName,
+			Version:  iCrate.installedVersion(),
+			Pin:      pin,
+			RepoUrl:  mCrate.Git,
+		}
+
+		if pin != "" {
+			iPkg.LatestVersion = pin
+		} else if mCrate.Git != "" {
+			iPkg.LatestVersion, err = c.LatestCommit(ctx, mCrate.Git)
+		} else {
+			iPkg.LatestVersion, err = c.LatestVersion(ctx, mCrate.Name)
+		}
+		if err != nil {
+			return packageStatus, err
+		}
+
+		// A crate is reinstalled when the features in the manifest change
+		upToDate := iPkg.Version == iPkg.LatestVersion || (pin != "" && shared.MatchPin(pin, iPkg.Version))
+		if upToDate && mCrate.sameFeatures(iCrate) {
+			iPkg.LatestVersion = ""
+			packageStatus.Synced = append(packageStatus.Synced, iPkg)
+		} else {
+			packageStatus.Updated = append(packageStatus.Updated, iPkg)
+		}
+	}
+
+	// An entry whose features changed is still the same crate, and is not removed
+	mCrates := lo.Map(shared.UnpinAll(packages), func(pkg string, _ int) crate { return parseEntry(pkg) })
+	for _, pkg := range statePkgs {
+		sCrate := parseEntry(pkg)
+		if lo.ContainsBy(mCrates, sCrate.sameCrate) {
+			continue
+		}
+		if iCrate, found := lo.Find(installed, sCrate.matches); found {
+			sCrate.Name = iCrate.Name
+		}
+		packageStatus.Removed = append(packageStatus.Removed, shared.Package{
+			Name:     sCrate.Name,
+			FullName: pkg,
+			RepoUrl:  sCrate.Git,
+		})
+	}
+
+	return
+}
+
+func (c *Cargo) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	installed, err := c.ListInstalled(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(installed, func(iCrate crate, _ int) shared.Package {
+		return shared.Package{
+			Name:     iCrate.Name,
+			FullName: iCrate.FullName(),
+			Version:  iCrate.installedVersion(),
+			RepoUrl:  iCrate.Git,
+		}
+	}), nil
+}
+
+func (c *Cargo) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRemove []string) (packagesToRemove []string, userWarnings []string, err error) {
+	for _, pR := range pkgsToRemove {
+		for _, pA := range shared.UnpinAll(allPkgs) {
+			if parseEntry(pA).Name == pR {
+				packagesToRemove = append(packagesToRemove, pA)
+			}
+		}
+	}
+	return
+}
+
+func (c *Cargo) RemoveDependencies(ctx context.Context, allDeps []string, depsToRemove []string) (depsUpdated []string, userWarnings []string, err error) {
+	return
+}
+
+func (c *Cargo) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	return
+}
+
+func (c *Cargo) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = shared.SyncEach(ctx, Name, packageStatus.Missing, packageStatus.Updated, packageStatus.Removed, c.SyncPackage)
+	return
+}
+
+func (c *Cargo) CanSyncConcurrently() bool {
+	return true
+}
+
+func (c *Cargo) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
+	return shared.RunWithHooks(ctx, Name, pkg, action, c.hookPath(ctx, pkg), func() error {
+		switch action {
+		case shared.PtermSpinnerInstall:
+			return c.Install(ctx, pkg)
+		case shared.PtermSpinnerUpdate:
+			return c.Install(ctx, pkg)
+		default:
+			return c.Remove(ctx, pkg)
+		}
+	})
+}
+
+// hookPath returns the first binary of the crate
+func (c *Cargo) hookPath(ctx context.Context, pkg shared.Package) func() string {
+	return func() string {
+		binPath, err := c.BinPath()
+		if err != nil {
+			return ""
+		}
+		installed, err := c.ListInstalled(ctx)
+		if err != nil {
+			return ""
+		}
+		iCrate, found := lo.Find(installed, parseEntry(pkg.FullName).matches)
+		if !found || len(iCrate.Bins) == 0 {
+			return ""
+		}
+		return path.Join(binPath, iCrate.Bins[0])
+	}
+}
+
+func (c *Cargo) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	return
+}
+
+func (c *Cargo) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	for _, pkg := range slices.Concat(packageStatus.Missing, packageStatus.Updated) {
+		actions = append(actions, shared.CommandString("cargo", installArgs(pkg)))
+	}
+	for _, pkg := range packageStatus.Removed {
+		actions = append(actions, shared.CommandString("cargo", []string{"uninstall", pkg.Name}))
+	}
+	return
+}
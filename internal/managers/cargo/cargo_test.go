@@ -0,0 +1,132 @@
+package cargo
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCrates2 = `{
+  "installs": {
+    "ripgrep 14.0.0 (registry+https://github.com/rust-lang/crates.io-index)": {"bins": ["rg"], "features": ["pcre2"]},
+    "bat 0.24.0 (registry+https://github.com/rust-lang/crates.io-index)": {"bins": ["bat"], "features": []},
+    "tool 0.1.0 (git+https://github.com/user/tool#1111111111111111111111111111111111111111)": {"bins": ["tool"], "features": []},
+    "cli 0.2.0 (git+https://github.com/user/workspace?tag=v0.2.0#2222222222222222222222222222222222222222)": {"bins": ["cli"], "features": []},
+    "local 0.1.0 (path+file:///home/user/local)": {"bins": ["local"], "features": []}
+  }
+}`
+
+// fakeExecutor has the crates above installed, with the binaries in binPath,
+// and records the crates it is asked to install and remove
+type fakeExecutor struct {
+	commandExecutor
+	binPath string
+	synced  []string
+}
+
+func (f *fakeExecutor) ListInstalled(ctx context.Context) ([]crate, error) {
+	return parseCrates2([]byte(testCrates2))
+}
+
+func (f *fakeExecutor) LatestVersion(ctx context.Context, name string) (string, error) {
+	return map[string]string{"ripgrep": "14.0.0", "bat": "0.24.0"}[name], nil
+}
+
+func (f *fakeExecutor) LatestCommit(ctx context.Context, gitUrl string) (string, error) {
+	return "1111111111111111111111111111111111111111", nil
+}
+
+func (f *fakeExecutor) BinPath() (string, error) {
+	return f.binPath, nil
+}
+
+func (f *fakeExecutor) Install(ctx context.Context, pkg shared.Package) error {
+	f.synced = append(f.synced, "install "+pkg.FullName)
+	return nil
+}
+
+func (f *fakeExecutor) Remove(ctx context.Context, pkg shared.Package) error {
+	f.synced = append(f.synced, "uninstall "+pkg.Name)
+	return nil
+}
+
+func TestParseEntry(t *testing.T) {
+	c := parseEntry("ripgrep[pcre2, simd]")
+	assert.Equal(t, "ripgrep", c.Name)
+	assert.Equal(t, []string{"pcre2", "simd"}, c.Features)
+	assert.Equal(t, "ripgrep[pcre2,simd]", c.FullName())
+
+	c = parseEntry("https://github.com/user/tool.git")
+	assert.Equal(t, "tool", c.Name)
+	assert.Equal(t, "https://github.com/user/tool.git", c.FullName())
+
+	c = parseEntry("https://github.com/user/workspace#cli[extra]")
+	assert.Equal(t, "cli", c.Name)
+	assert.Equal(t, "https://github.com/user/workspace", c.Git)
+	assert.Equal(t, "https://github.com/user/workspace#cli[extra]", c.FullName())
+	assert.Equal(t, "https://github.com/user/tool", parseEntry("https://github.com/user/tool#tool").FullName(), "a crate named after its repository needs no name")
+
+	assert.Equal(t, []string{"install", "--version", "14.1.*", "--features", "pcre2", "ripgrep"},
+		installArgs(shared.Package{FullName: "ripgrep[pcre2]", Pin: "14.1.x"}))
+	assert.Equal(t, []string{"install", "--git", "https://github.com/user/tool", "--tag", "v1.0.0"},
+		installArgs(shared.Package{FullName: "https://github.com/user/tool", Pin: "v1.0.0"}))
+	assert.Equal(t, []string{"install", "--git", "https://github.com/user/workspace", "--rev", "2222222", "cli"},
+		installArgs(shared.Package{FullName: "https://github.com/user/workspace#cli", Pin: "2222222"}))
+}
+
+func TestWorkspaceCrates(t *testing.T) {
+	installed, err := parseCrates2([]byte(testCrates2))
+	assert.Nil(t, err, "should be no error")
+	cli := installed[2]
+	assert.Equal(t, "cli", cli.Name)
+
+	assert.True(t, parseEntry("https://github.com/user/workspace").matches(cli), "a repository without a crate name matches any crate from it")
+	assert.True(t, parseEntry("https://github.com/user/workspace.git#cli").matches(cli))
+	assert.False(t, parseEntry("https://github.com/user/workspace#server").matches(cli), "another crate in the repository")
+	assert.False(t, parseEntry("cli").matches(cli), "a crate from crates.io")
+	assert.False(t, parseEntry("https://github.com/user/cli").matches(cli), "a repository named like the crate")
+}
+
+func TestSyncPackages(t *testing.T) {
+	pterm.DisableOutput()
+	t.Cleanup(pterm.EnableOutput)
+
+	executor := &fakeExecutor{binPath: t.TempDir()}
+	c := &Cargo{executor}
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "out")
+
+	pkgStatus, err := c.ListPackages(ctx, []string{
+		"ripgrep[pcre2]",
+		"bat[paging]",
+		"eza",
+	}, []string{"ripgrep", "https://github.com/user/workspace", "https://github.com/user/tool.git"})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"ripgrep[pcre2]"}, shared.FullNames(pkgStatus.Synced))
+	assert.Equal(t, []string{"bat[paging]"}, shared.FullNames(pkgStatus.Updated), "other features should reinstall the crate")
+	assert.Equal(t, []string{"cli", "tool"}, names(pkgStatus.Removed), "removed repositories should remove the crate installed from them, and ripgrep only got features")
+
+	pkgStatus.Missing[0].Hooks = shared.Hooks{PreInstall: "exit 1"}
+	pkgStatus.Removed[0].Hooks = shared.Hooks{PostRemove: "echo $PACKTRAK_PACKAGE $PACKTRAK_PATH > " + out}
+	failures, _, err := c.SyncPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Len(t, failures, 1)
+	assert.Equal(t, "eza", failures[0].Name, "a failing pre hook should skip the install")
+	assert.Equal(t, []string{"install bat[paging]", "uninstall cli", "uninstall tool"}, executor.synced)
+
+	b, _ := os.ReadFile(out)
+	assert.Equal(t, "cli "+path.Join(executor.binPath, "cli")+"\n", string(b), "hooks should get the binary of the crate")
+}
+
+func names(pkgs []shared.Package) (names []string) {
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name)
+	}
+	return
+}
@@ -0,0 +1,173 @@
+package cargo
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"golang.org/x/mod/semver"
+)
+
+type CommandExecutorFace interface {
+	Install(ctx context.Context, pkg shared.Package) error
+	Remove(ctx context.Context, pkg shared.Package) error
+	ListInstalled(ctx context.Context) (crates []crate, err error)
+	LatestVersion(ctx context.Context, name string) (version string, err error)
+	LatestCommit(ctx context.Context, gitUrl string) (commit string, err error)
+	BinPath() (binPath string, err error)
+}
+
+type commandExecutor struct {
+}
+
+var rCommit = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+func installArgs(pkg shared.Package) []string {
+	c := parseEntry(pkg.FullName)
+	args := []string{"install"}
+	if c.Git != "" {
+		args = append(args, "--git", c.Git)
+		if pkg.Pin != "" {
+			// A commit, e.g. from the lock file, is passed as a rev
+			if rCommit.MatchString(pkg.Pin) {
+				args = append(args, "--rev", pkg.Pin)
+			} else {
+				args = append(args, "--tag", pkg.Pin)
+			}
+		}
+	} else if pkg.Pin != "" {
+		// cargo install takes a version without an operator as the exact version,
+		// and a requirement like 14.1.* as the newest matching version
+		args = append(args, "--version", shared.PinGlob(pkg.Pin))
+	}
+	if len(c.Features) > 0 {
+		args = append(args, "--features", strings.Join(c.Features, ","))
+	}
+	if c.Git == "" || !c.hasDefaultName() {
+		args = append(args, c.Name)
+	}
+	return args
+}
+
+func (c *commandExecutor) Install(ctx context.Context, pkg shared.Package) error {
+	_, err := shared.Command(ctx, "cargo", installArgs(pkg), false, nil)
+	return err
+}
+
+func (c *commandExecutor) Remove(ctx context.Context, pkg shared.Package) error {
+	_, err := shared.Command(ctx, "cargo", []string{"uninstall", pkg.Name}, false, nil)
+	return err
+}
+
+func (c *commandExecutor) ListInstalled(ctx context.Context) (crates []crate, err error) {
+	home := cargoHome()
+	if home == "" {
+		return nil, errors.New("CARGO_HOME not found")
+	}
+	data, err := os.ReadFile(path.Join(home, ".crates2.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseCrates2(data)
+}
+
+// LatestVersion returns the newest stable version of the crate that is not
+// yanked, from the sparse index of crates.io
+func (c *commandExecutor) LatestVersion(ctx context.Context, name string) (version string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://index.crates.io/"+indexPath(name), nil)
+	if err != nil {
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not find crate %s in the crates.io index: %s", name, resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		entry := struct {
+			Vers   string `json:"vers"`
+			Yanked bool   `json:"yanked"`
+		}{}
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return "", err
+		}
+		if entry.Yanked || semver.Prerelease("v"+entry.Vers) != "" {
+			continue
+		}
+		if version == "" || semver.Compare("v"+entry.Vers, "v"+version) > 0 {
+			version = entry.Vers
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", err
+	}
+	if version == "" {
+		return "", fmt.Errorf("no stable version of crate %s found", name)
+	}
+	return
+}
+
+// indexPath returns the path of a crate in the index, e.g. 'ri/pg/ripgrep'
+func indexPath(name string) string {
+	name = strings.ToLower(name)
+	switch len(name) {
+	case 1:
+		return "1/" + name
+	case 2:
+		return "2/" + name
+	case 3:
+		return "3/" + name[:1] + "/" + name
+	default:
+		return name[:2] + "/" + name[2:4] + "/" + name
+	}
+}
+
+// LatestCommit returns the commit of HEAD in the remote repository
+func (c *commandExecutor) LatestCommit(ctx context.Context, gitUrl string) (commit string, err error) {
+	out, err := shared.Command(ctx, "git", []string{"ls-remote", gitUrl, "HEAD"}, false, nil)
+	if err != nil {
+		return
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("no HEAD found in %s", gitUrl)
+	}
+	return fields[0], nil
+}
+
+func (c *commandExecutor) BinPath() (binPath string, err error) {
+	home := cargoHome()
+	if home == "" {
+		return "", errors.New("CARGO_HOME not found")
+	}
+	return path.Join(home, "bin"), nil
+}
+
+// cargoHome returns CARGO_HOME, or ~/.cargo if it isn't set
+func cargoHome() string {
+	if home := os.Getenv("CARGO_HOME"); home != "" {
+		return home
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return path.Join(userHome, ".cargo")
+}
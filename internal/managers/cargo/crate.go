@@ -0,0 +1,183 @@
+package cargo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A manifest entry is a crate from crates.io, e.g. 'ripgrep', or the url of a
+// git repository, e.g. 'https://github.com/user/tool'. '#crate' after the url
+// picks a crate in a repository with several, and '[feature,...]' at the end
+// enables features, e.g. 'ripgrep[pcre2]'.
+
+// crate is a crate as written in the manifest, or as installed by cargo
+type crate struct {
+	Name     string
+	Git      string
+	Features []string
+
+	// Only set for installed crates
+	Version string
+	Tag     string
+	Rev     string
+	Commit  string
+	Bins    []string
+}
+
+// parseEntry parses a manifest entry without its pin
+func parseEntry(entry string) crate {
+	c := crate{}
+	if idx := strings.Index(entry, "["); idx > 0 && strings.HasSuffix(entry, "]") {
+		for _, feature := range strings.Split(entry[idx+1:len(entry)-1], ",") {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				c.Features = append(c.Features, feature)
+			}
+		}
+		entry = entry[:idx]
+	}
+
+	if !strings.Contains(entry, "://") {
+		c.Name = entry
+		return c
+	}
+
+	c.Git, c.Name, _ = strings.Cut(entry, "#")
+	if c.Name == "" {
+		c.Name = repoName(c.Git)
+	}
+	return c
+}
+
+// repoName returns the last element of a git url, which is used as the crate
+// name unless another one is given
+func repoName(gitUrl string) string {
+	return strings.TrimSuffix(path.Base(strings.TrimSuffix(gitUrl, "/")), ".git")
+}
+
+// hasDefaultName reports if the crate is named after its git repository
+func (c crate) hasDefaultName() bool {
+	return c.Git != "" && c.Name == repoName(c.Git)
+}
+
+// FullName returns the crate as it is written in the manifest
+func (c crate) FullName() string {
+	fullName := c.Name
+	if c.Git != "" {
+		fullName = c.Git
+		if !c.hasDefaultName() {
+			fullName += "#" + c.Name
+		}
+	}
+	if len(c.Features) > 0 {
+		fullName += "[" + strings.Join(c.Features, ",") + "]"
+	}
+	return fullName
+}
+
+// matches reports if the installed crate comes from the same place as the
+// crate in the manifest, regardless of version and features. A git repository
+// without a crate name matches any crate installed from it.
+func (c crate) matches(installed crate) bool {
+	if normalizeGitUrl(c.Git) != normalizeGitUrl(installed.Git) {
+		return false
+	}
+	return c.Name == installed.Name || c.hasDefaultName()
+}
+
+// sameCrate reports if both entries are the same crate, regardless of features
+func (c crate) sameCrate(other crate) bool {
+	return c.Name == other.Name && normalizeGitUrl(c.Git) == normalizeGitUrl(other.Git)
+}
+
+// sameFeatures reports if both crates enable the same features
+func (c crate) sameFeatures(other crate) bool {
+	a := slices.Clone(c.Features)
+	b := slices.Clone(other.Features)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func normalizeGitUrl(u string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(u, "/"), ".git")
+}
+
+// installedVersion returns the version compared to pins and to the latest
+// version: the crate version for crates.io, and the tag, rev or commit for git
+func (c crate) installedVersion() string {
+	if c.Git == "" {
+		return c.Version
+	}
+	return firstNonEmpty(c.Tag, c.Rev, c.Commit)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// crates2 is the file cargo keeps track of installed crates in
+type crates2 struct {
+	Installs map[string]struct {
+		Bins     []string `json:"bins"`
+		Features []string `json:"features"`
+	} `json:"installs"`
+}
+
+var rCrates2Key = regexp.MustCompile(`^(\S+) (\S+) \((\S+)\)$`)
+
+// parseCrates2 returns the crates in a .crates2.json file. Crates installed
+// from a local path are left out, since they can't be written in the manifest.
+func parseCrates2(data []byte) (crates []crate, err error) {
+	c2 := crates2{}
+	if err = json.Unmarshal(data, &c2); err != nil {
+		return nil, err
+	}
+
+	for key, install := range c2.Installs {
+		matches := rCrates2Key.FindStringSubmatch(key)
+		if matches == nil {
+			return nil, fmt.Errorf("could not parse installed crate '%s'", key)
+		}
+
+		c := crate{
+			Name:     matches[1],
+			Version:  matches[2],
+			Features: install.Features,
+			Bins:     install.Bins,
+		}
+
+		kind, source, _ := strings.Cut(matches[3], "+")
+		switch kind {
+		case "registry", "sparse":
+		case "git":
+			u, err := url.Parse(source)
+			if err != nil {
+				return nil, err
+			}
+			c.Commit = u.Fragment
+			c.Tag = u.Query().Get("tag")
+			c.Rev = u.Query().Get("rev")
+			u.Fragment = ""
+			u.RawQuery = ""
+			c.Git = u.String()
+		default:
+			continue
+		}
+		crates = append(crates, c)
+	}
+
+	slices.SortFunc(crates, func(a, b crate) int {
+		return strings.Compare(a.FullName(), b.FullName())
+	})
+	return
+}
@@ -37,34 +37,65 @@ type commandExecutor struct {
 	cacheCoprs                []string
 }
 
-func (d *commandExecutor) yumRepoFolder() string {
-	return "/etc/yum.repos.d"
-}
+const (
+	yumRepoFolder  = "/etc/yum.repos.d"
+	repoFilePrefix = "_packtrak:"
+)
+
+func pkgArgs(action string, pkgs []shared.Package) []string {
+	cmds := []string{"dnf", "--color=always", action}
+	if *config.AssumeYes {
+		cmds = append(cmds, "--assumeyes")
+	}
 
-func (d *commandExecutor) repoFilePrefix() string {
-	return "_packtrak:"
+	for _, pkg := range pkgs {
+		cmds = append(cmds, pkgSpec(pkg))
+	}
+	return cmds
 }
 
-func (d *commandExecutor) InstallPkg(ctx context.Context, pkgs []shared.Package) error {
-	if len(pkgs) == 0 {
-		return errors.New("no packages provided")
+// pkgSpec returns the package as given to dnf, with the pinned version if any
+func pkgSpec(pkg shared.Package) string {
+	if pkg.Pin == "" {
+		return pkg.FullName
 	}
+	return pkg.FullName + "-" + shared.PinGlob(pkg.Pin)
+}
 
-	cmds := []string{"dnf", "--color=always", "install"}
+func coprArgs(action string, copr string) []string {
+	cmds := []string{"dnf", "copr", action}
 	if *config.AssumeYes {
 		cmds = append(cmds, "--assumeyes")
 	}
+	return append(cmds, copr)
+}
 
-	pkgNames := []string{}
-	for _, pkg := range pkgs {
-		pkgNames = append(pkgNames, pkg.FullName)
+// clearCache forgets the listed packages and coprs, it is called when the
+// system is changed so that they are listed again
+func (d *commandExecutor) clearCache() {
+	d.cacheAllInstalled = nil
+	d.cacheAllInstalledVersions = nil
+	d.cacheUserInstalled = nil
+	d.cacheCoprs = nil
+}
+
+func cmRepoFileName(cm string) (string, error) {
+	u, err := url.ParseRequestURI(cm)
+	if err != nil {
+		return "", fmt.Errorf("not an url: %s, %s", cm, err)
 	}
+	return path.Join(yumRepoFolder, fmt.Sprintf("%s%s", repoFilePrefix, path.Base(u.Path))), nil
+}
 
-	cmds = append(cmds, pkgNames...)
+func (d *commandExecutor) InstallPkg(ctx context.Context, pkgs []shared.Package) error {
+	if len(pkgs) == 0 {
+		return errors.New("no packages provided")
+	}
+	defer d.clearCache()
 
 	cmd := execute.ExecTask{
 		Command:     "sudo",
-		Args:        cmds,
+		Args:        pkgArgs("install", pkgs),
 		StreamStdio: true,
 		Stdin:       os.Stdin,
 	}
@@ -85,22 +116,11 @@ func (d *commandExecutor) RemovePkg(ctx context.Context, pkgs []shared.Package)
 	if len(pkgs) == 0 {
 		return errors.New("no packages provided")
 	}
-
-	cmds := []string{"dnf", "--color=always", "remove"}
-	if *config.AssumeYes {
-		cmds = append(cmds, "--assumeyes")
-	}
-
-	pkgNames := []string{}
-	for _, pkg := range pkgs {
-		pkgNames = append(pkgNames, pkg.FullName)
-	}
-
-	cmds = append(cmds, pkgNames...)
+	defer d.clearCache()
 
 	cmd := execute.ExecTask{
 		Command:     "sudo",
-		Args:        cmds,
+		Args:        pkgArgs("remove", pkgs),
 		StreamStdio: true,
 		Stdin:       os.Stdin,
 	}
@@ -118,13 +138,11 @@ func (d *commandExecutor) RemovePkg(ctx context.Context, pkgs []shared.Package)
 }
 
 func (d *commandExecutor) InstallCm(ctx context.Context, cms string) error {
-	u, err := url.ParseRequestURI(cms)
+	repoFileName, err := cmRepoFileName(cms)
 	if err != nil {
-		return fmt.Errorf("not an url: %s, %s", cms, err)
+		return err
 	}
-
-	repoFileName := path.Join(d.yumRepoFolder(), fmt.Sprintf("%s%s", d.repoFilePrefix(), path.Base(u.Path)))
-	cacheRepoFileName := path.Join(config.CacheDir, fmt.Sprintf("%s%s", d.repoFilePrefix(), path.Base(u.Path)))
+	cacheRepoFileName := path.Join(config.CacheDir, path.Base(repoFileName))
 
 	res, err := http.Get(cms)
 	if err != nil {
@@ -155,7 +173,7 @@ func (d *commandExecutor) InstallCm(ctx context.Context, cms string) error {
 }
 
 func (d *commandExecutor) ListCm(ctx context.Context) (packages []string, err error) {
-	cms, err := os.ReadDir("/etc/yum.repos.d/")
+	cms, err := os.ReadDir(yumRepoFolder)
 	if err != nil {
 		return []string{}, err
 	}
@@ -164,21 +182,19 @@ func (d *commandExecutor) ListCm(ctx context.Context) (packages []string, err er
 		if e.IsDir() {
 			continue
 		}
-		if strings.HasPrefix(e.Name(), d.repoFilePrefix()) {
-			packages = append(packages, strings.ReplaceAll(e.Name(), d.repoFilePrefix(), ""))
+		if strings.HasPrefix(e.Name(), repoFilePrefix) {
+			packages = append(packages, strings.ReplaceAll(e.Name(), repoFilePrefix, ""))
 		}
 	}
 	return
 }
 
 func (d *commandExecutor) RemoveCm(ctx context.Context, cm string) error {
-	u, err := url.ParseRequestURI(cm)
+	repoFileName, err := cmRepoFileName(cm)
 	if err != nil {
-		return fmt.Errorf("not an url: %s, %s", cm, err)
+		return err
 	}
 
-	repoFileName := path.Join(d.yumRepoFolder(), fmt.Sprintf("%s%s", d.repoFilePrefix(), path.Base(u.Path)))
-
 	_, err = os.Stat(repoFileName)
 	if os.IsNotExist(err) {
 		return fmt.Errorf("remove cm: %s, file does not exist", cm)
@@ -189,23 +205,14 @@ func (d *commandExecutor) RemoveCm(ctx context.Context, cm string) error {
 }
 
 func (d *commandExecutor) InstallCopr(ctx context.Context, copr string) error {
-	cmds := []string{"dnf", "copr", "enable"}
-	if *config.AssumeYes {
-		cmds = append(cmds, "--assumeyes")
-	}
-	cmds = append(cmds, copr)
-
-	_, err := shared.Command(ctx, "sudo", cmds, true, os.Stdin)
+	defer d.clearCache()
+	_, err := shared.Command(ctx, "sudo", coprArgs("enable", copr), true, os.Stdin)
 	return err
 }
 
 func (d *commandExecutor) RemoveCopr(ctx context.Context, copr string) error {
-	cmds := []string{"dnf", "copr", "remove"}
-	if *config.AssumeYes {
-		cmds = append(cmds, "--assumeyes")
-	}
-	cmds = append(cmds, copr)
-	_, err := shared.Command(ctx, "sudo", cmds, false, nil)
+	defer d.clearCache()
+	_, err := shared.Command(ctx, "sudo", coprArgs("remove", copr), false, nil)
 	return err
 }
 
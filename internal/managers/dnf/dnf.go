@@ -10,7 +10,6 @@ import (
 
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/lucas-ingemar/packtrak/internal/status"
-	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 )
 
@@ -46,6 +45,10 @@ func (d *Dnf) NeedsSudo() []shared.CommandName {
 	return []shared.CommandName{shared.CommandInstall, shared.CommandRemove, shared.CommandSync}
 }
 
+func (d *Dnf) SupportsPins() bool {
+	return true
+}
+
 func (d *Dnf) InitCheckCmd() error {
 	_, err := exec.LookPath("dnf")
 	if err != nil {
@@ -62,7 +65,7 @@ func (d *Dnf) InitCheckConfig() error {
 }
 
 func (d *Dnf) GetPackageNames(ctx context.Context, packages []string) []string {
-	return packages
+	return shared.UnpinAll(packages)
 }
 
 func (d *Dnf) GetDependencyNames(ctx context.Context, deps []string) []string {
@@ -75,7 +78,7 @@ func (d *Dnf) GetDependencyNames(ctx context.Context, deps []string) []string {
 
 func (d *Dnf) AddPackages(ctx context.Context, pkgsToAdd []string) (packagesUpdated []string, userWarnings []string, err error) {
 	for _, pkg := range pkgsToAdd {
-		isSysPkg, err := d.isSystemPackage(ctx, pkg)
+		isSysPkg, err := d.isSystemPackage(ctx, shared.Unpin(pkg))
 		if err != nil {
 			return packagesUpdated, []string{}, err
 		}
@@ -203,24 +206,32 @@ func (d *Dnf) ListPackages(ctx context.Context, packages []string, statePkgs []s
 		return
 	}
 
-	for _, pkg := range packages {
+	for _, entry := range packages {
+		pkg, pin := shared.SplitPin(entry)
 		pkgFound := false
 		for idx, dnfPkg := range dnfList {
 			if dnfPkg == pkg {
-				packageStatus.Synced = append(packageStatus.Synced, shared.Package{Name: pkg, FullName: pkg, Version: dnfVersions[idx]})
+				iPkg := shared.Package{Name: pkg, FullName: pkg, Version: dnfVersions[idx], Pin: pin}
+				if pin != "" && !matchDnfPin(pin, iPkg.Version) {
+					iPkg.LatestVersion = pin
+					packageStatus.Updated = append(packageStatus.Updated, iPkg)
+				} else {
+					packageStatus.Synced = append(packageStatus.Synced, iPkg)
+				}
 				pkgFound = true
 				break
 			}
 		}
 		if !pkgFound {
-			packageStatus.Missing = append(packageStatus.Missing, shared.Package{Name: pkg, FullName: pkg})
+			packageStatus.Missing = append(packageStatus.Missing, shared.Package{Name: pkg, FullName: pkg, Pin: pin})
 		}
 	}
 
+	unpinned := shared.UnpinAll(packages)
 	for _, pkg := range statePkgs {
 		for _, dnfPkg := range dnfList {
 			if dnfPkg == pkg {
-				if !lo.Contains(packages, pkg) {
+				if !lo.Contains(unpinned, pkg) {
 					packageStatus.Removed = append(packageStatus.Removed, shared.Package{Name: pkg, FullName: pkg})
 				}
 				break
@@ -231,6 +242,20 @@ func (d *Dnf) ListPackages(ctx context.Context, packages []string, statePkgs []s
 	return
 }
 
+func (d *Dnf) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	userPkgs, err := d.ListUserInstalledPkgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range userPkgs {
+		if pkg == "" {
+			continue
+		}
+		packages = append(packages, shared.Package{Name: pkg, FullName: pkg})
+	}
+	return
+}
+
 func (d *Dnf) RemovePackages(ctx context.Context, allPkgs []string, pkgs []string) (packagesToRemove []string, userWarnings []string, err error) {
 	for _, pkg := range pkgs {
 		var isSysPkg bool
@@ -261,7 +286,7 @@ func (d *Dnf) RemoveDependencies(ctx context.Context, allDeps []string, depsToRe
 	return
 }
 
-func (d *Dnf) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (userWarnings []string, err error) {
+func (d *Dnf) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
 	if len(depStatus.Missing) > 0 {
 		fmt.Println("")
 		mCoprs := []string{}
@@ -277,8 +302,8 @@ func (d *Dnf) SyncDependencies(ctx context.Context, depStatus status.Dependencie
 		for _, copr := range mCoprs {
 			err := d.InstallCopr(ctx, copr)
 			if err != nil {
-				shared.PtermRemoved.Println(fmt.Sprintf(shared.PtermSpinnerStatusMsgs[shared.PtermSpinnerInstall].Fail, copr))
-				// return nil, err
+				shared.PtermRemoved.Println(fmt.Sprintf(shared.PtermSpinnerStatusMsgs[shared.PtermSpinnerInstall].Fail, copr, err.Error()))
+				failures = append(failures, shared.SyncFailure{Manager: Name, Name: copr, Action: shared.PtermSpinnerInstall, Err: err})
 			} else {
 				shared.PtermInstalled.Println(fmt.Sprintf(shared.PtermSpinnerStatusMsgs[shared.PtermSpinnerInstall].Success, copr))
 			}
@@ -288,7 +313,7 @@ func (d *Dnf) SyncDependencies(ctx context.Context, depStatus status.Dependencie
 				return d.InstallCm(ctx, cm)
 			})
 			if err != nil {
-				log.Err(err).Str("manager", string(Name)).Str("dependency", cm)
+				failures = append(failures, shared.SyncFailure{Manager: Name, Name: cm, Action: shared.PtermSpinnerInstall, Err: err})
 				err = nil
 			}
 		}
@@ -301,7 +326,7 @@ func (d *Dnf) SyncDependencies(ctx context.Context, depStatus status.Dependencie
 				return d.RemoveCopr(ctx, dep.Name)
 			})
 			if err != nil {
-				log.Err(err).Str("manager", string(Name)).Str("dependency", dep.Name)
+				failures = append(failures, shared.SyncFailure{Manager: Name, Name: dep.Name, Action: shared.PtermSpinnerRemove, Err: err})
 				err = nil
 			}
 		} else if strings.HasPrefix(dep.FullName, "cm:") {
@@ -309,7 +334,7 @@ func (d *Dnf) SyncDependencies(ctx context.Context, depStatus status.Dependencie
 				return d.RemoveCm(ctx, dep.Name)
 			})
 			if err != nil {
-				log.Err(err).Str("manager", string(Name)).Str("dependency", dep.Name)
+				failures = append(failures, shared.SyncFailure{Manager: Name, Name: dep.Name, Action: shared.PtermSpinnerRemove, Err: err})
 				err = nil
 			}
 		}
@@ -318,39 +343,84 @@ func (d *Dnf) SyncDependencies(ctx context.Context, depStatus status.Dependencie
 	return
 }
 
-func (d *Dnf) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (userWarnings []string, err error) {
-	if len(packageStatus.Missing) > 0 {
-		filteredPkgsInstall := lo.Filter(packageStatus.Missing, func(item shared.Package, _ int) bool {
-			isSysPkg, err := d.isSystemPackage(ctx, item.FullName)
-			if err != nil || isSysPkg {
-				return false
-			}
-			return true
-		})
-
-		fmt.Println("")
-		err := d.InstallPkg(ctx, filteredPkgsInstall)
-		if err != nil {
-			return nil, err
+// SyncPackages installs and removes packages in one dnf transaction each, so a
+// failing transaction is reported as a failure for every package in it.
+// Updated packages are the ones not matching their pin, dnf installs the
+// pinned version in place of the installed one. A pinned system package fails,
+// since installing it would take it over from the package that needs it.
+func (d *Dnf) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = append(failures, shared.SyncTransaction(ctx, Name, d.filterSystemPackages(ctx, packageStatus.Missing), shared.PtermSpinnerInstall, d.InstallPkg)...)
+	updated := d.filterSystemPackages(ctx, packageStatus.Updated)
+	for _, pkg := range packageStatus.Updated {
+		if !lo.Contains(updated, pkg) {
+			failures = append(failures, shared.SyncFailure{Manager: Name, Name: pkg.Name, Action: shared.PtermSpinnerUpdate, Err: fmt.Errorf("'%s' is a system package and cannot be managed", pkg.FullName)})
 		}
 	}
+	failures = append(failures, shared.SyncTransaction(ctx, Name, updated, shared.PtermSpinnerUpdate, d.InstallPkg)...)
+	failures = append(failures, shared.SyncTransaction(ctx, Name, d.filterSystemPackages(ctx, packageStatus.Removed), shared.PtermSpinnerRemove, d.RemovePkg)...)
+	return
+}
 
-	if len(packageStatus.Removed) > 0 {
-		filteredPkgsRemove := lo.Filter(packageStatus.Removed, func(item shared.Package, _ int) bool {
-			isSysPkg, err := d.isSystemPackage(ctx, item.FullName)
-			if err != nil || isSysPkg {
-				return false
+func (d *Dnf) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	for _, dep := range depStatus.Missing {
+		if strings.HasPrefix(dep.FullName, "copr:") {
+			actions = append(actions, shared.CommandString("sudo", coprArgs("enable", dep.Name)))
+		} else if strings.HasPrefix(dep.FullName, "cm:") {
+			repoFileName, err := cmRepoFileName(dep.Name)
+			if err != nil {
+				return nil, err
 			}
-			return true
-		})
+			actions = append(actions, fmt.Sprintf("download %s to %s", dep.Name, repoFileName))
+		}
+	}
 
-		fmt.Println("")
-		err := d.RemovePkg(ctx, filteredPkgsRemove)
-		if err != nil {
-			return nil, err
+	for _, dep := range depStatus.Removed {
+		if strings.HasPrefix(dep.FullName, "copr:") {
+			actions = append(actions, shared.CommandString("sudo", coprArgs("remove", dep.Name)))
+		} else if strings.HasPrefix(dep.FullName, "cm:") {
+			repoFileName, err := cmRepoFileName(dep.Name)
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, shared.CommandString("sudo", []string{"rm", repoFileName}))
 		}
 	}
-	return nil, nil
+	return
+}
+
+func (d *Dnf) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	if pkgs := d.filterSystemPackages(ctx, packageStatus.Missing); len(pkgs) > 0 {
+		actions = append(actions, shared.CommandString("sudo", pkgArgs("install", pkgs)))
+	}
+
+	if pkgs := d.filterSystemPackages(ctx, packageStatus.Updated); len(pkgs) > 0 {
+		actions = append(actions, shared.CommandString("sudo", pkgArgs("install", pkgs)))
+	}
+
+	if pkgs := d.filterSystemPackages(ctx, packageStatus.Removed); len(pkgs) > 0 {
+		actions = append(actions, shared.CommandString("sudo", pkgArgs("remove", pkgs)))
+	}
+	return
+}
+
+// matchDnfPin matches the pin against the installed version, with or without
+// the epoch and release, e.g. '2:14.1.0-1.fc40'
+func matchDnfPin(pin string, version string) bool {
+	if _, v, found := strings.Cut(version, ":"); found {
+		version = v
+	}
+	v, _, _ := strings.Cut(version, "-")
+	return shared.MatchPin(pin, version) || shared.MatchPin(pin, v)
+}
+
+func (d *Dnf) filterSystemPackages(ctx context.Context, pkgs []shared.Package) []shared.Package {
+	return lo.Filter(pkgs, func(item shared.Package, _ int) bool {
+		isSysPkg, err := d.isSystemPackage(ctx, item.FullName)
+		if err != nil || isSysPkg {
+			return false
+		}
+		return true
+	})
 }
 
 func (d *Dnf) isSystemPackage(ctx context.Context, pkg string) (bool, error) {
@@ -0,0 +1,52 @@
+package external
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+)
+
+const ExecutablePrefix = "packtrak-manager-"
+
+// SearchDirs returns the directories searched for external managers. The
+// managers directory in the config dir takes precedence over PATH.
+func SearchDirs() []string {
+	dirs := []string{filepath.Join(config.DefaultConfigDir(), "managers")}
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// Discover finds executables named packtrak-manager-<name> in dirs. The first
+// executable found for a name wins, and names in reserved are skipped.
+func Discover(dirs []string, reserved []shared.ManagerName) (managers []*External) {
+	found := map[shared.ManagerName]bool{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasPrefix(e.Name(), ExecutablePrefix) {
+				continue
+			}
+
+			name := shared.ManagerName(strings.TrimPrefix(e.Name(), ExecutablePrefix))
+			if name == "" || found[name] || lo.Contains(reserved, name) {
+				continue
+			}
+
+			info, err := e.Info()
+			if err != nil || info.Mode()&0111 == 0 {
+				continue
+			}
+
+			found[name] = true
+			managers = append(managers, New(name, filepath.Join(dir, e.Name())))
+		}
+	}
+	return
+}
@@ -0,0 +1,226 @@
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/samber/lo"
+)
+
+// New returns a manager that delegates every call to the executable at path
+func New(name shared.ManagerName, path string) *External {
+	return &External{
+		CommandExecutorFace: commandExecutor{path: path},
+		name:                name,
+		path:                path,
+	}
+}
+
+type External struct {
+	CommandExecutorFace
+
+	name      shared.ManagerName
+	path      string
+	info      infoResponse
+	needsSudo []shared.CommandName
+}
+
+func (e *External) Name() shared.ManagerName {
+	return e.name
+}
+
+func (e *External) Icon() string {
+	if e.info.Icon == "" {
+		return ""
+	}
+	return e.info.Icon
+}
+
+func (e *External) ShortDesc() string {
+	if e.info.ShortDesc == "" {
+		return fmt.Sprintf("Manage %s packages", e.name)
+	}
+	return e.info.ShortDesc
+}
+
+func (e *External) LongDesc() string {
+	if e.info.LongDesc == "" {
+		return fmt.Sprintf("External manager provided by %s", e.path)
+	}
+	return e.info.LongDesc
+}
+
+func (e *External) NeedsSudo() []shared.CommandName {
+	return e.needsSudo
+}
+
+func (e *External) InitConfig() {
+}
+
+func (e *External) InitCheckCmd() error {
+	_, err := exec.LookPath(e.path)
+	if err != nil {
+		return fmt.Errorf("'%s' is not executable", e.path)
+	}
+	return nil
+}
+
+// InitCheckConfig asks the executable for its metadata, which is needed before
+// the cobra commands are created.
+func (e *External) InitCheckConfig() error {
+	ctx := context.Background()
+	if err := e.Call(ctx, methodInfo, struct{}{}, &e.info); err != nil {
+		return fmt.Errorf("%s: %s", methodInfo, err)
+	}
+
+	resp := needsSudoResponse{}
+	if err := e.Call(ctx, methodNeedsSudo, struct{}{}, &resp); err != nil {
+		return fmt.Errorf("%s: %s", methodNeedsSudo, err)
+	}
+	e.needsSudo = resp.Commands
+	return nil
+}
+
+func (e *External) GetPackageNames(ctx context.Context, packages []string) []string {
+	return e.names(ctx, typePackage, packages)
+}
+
+func (e *External) GetDependencyNames(ctx context.Context, deps []string) []string {
+	return e.names(ctx, typeDependency, deps)
+}
+
+func (e *External) InstallValidArgs(ctx context.Context, toComplete string, dependencies bool) ([]string, error) {
+	resp := validArgsResponse{}
+	err := e.Call(ctx, methodValidArgs, validArgsRequest{Type: typeOf(dependencies), ToComplete: toComplete}, &resp)
+	return resp.Args, err
+}
+
+func (e *External) AddPackages(ctx context.Context, pkgsToAdd []string) (packagesUpdated []string, userWarnings []string, err error) {
+	return e.add(ctx, typePackage, pkgsToAdd)
+}
+
+func (e *External) AddDependencies(ctx context.Context, depsToAdd []string) (depsUpdated []string, userWarnings []string, err error) {
+	return e.add(ctx, typeDependency, depsToAdd)
+}
+
+func (e *External) ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error) {
+	resp := dependenciesStatusResponse{}
+	err = e.Call(ctx, methodList, listRequest{Type: typeDependency, Objects: deps, State: stateDeps}, &resp)
+	return resp.DependenciesStatus, err
+}
+
+func (e *External) ListPackages(ctx context.Context, packages []string, statePkgs []string) (packageStatus status.PackageStatus, err error) {
+	resp := packagesStatusResponse{}
+	err = e.Call(ctx, methodList, listRequest{Type: typePackage, Objects: packages, State: statePkgs}, &resp)
+	return resp.PackageStatus, err
+}
+
+func (e *External) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	resp := installedResponse{}
+	err = e.Call(ctx, methodInstalled, struct{}{}, &resp)
+	return resp.Packages, err
+}
+
+func (e *External) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRemove []string) (packagesToRemove []string, userWarnings []string, err error) {
+	return e.remove(ctx, typePackage, allPkgs, pkgsToRemove)
+}
+
+func (e *External) RemoveDependencies(ctx context.Context, allDeps []string, depsToRemove []string) (depsUpdated []string, userWarnings []string, err error) {
+	return e.remove(ctx, typeDependency, allDeps, depsToRemove)
+}
+
+func (e *External) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	return e.sync(ctx, dependenciesSyncRequest{Type: typeDependency, Status: depStatus})
+}
+
+// SyncPackages runs the pre hooks, syncs the packages whose hooks succeeded in
+// one call, and then runs the post hooks of the packages that synced
+func (e *External) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	runHooks := func(stage shared.HookStage, pkgs []shared.Package, action shared.PtermSpinnerStatus) (ok []shared.Package) {
+		for _, pkg := range pkgs {
+			failed := lo.ContainsBy(failures, func(f shared.SyncFailure) bool {
+				return f.Name == pkg.Name && f.Action == action
+			})
+			if failed {
+				continue
+			}
+			if err := shared.RunHook(ctx, e.name, pkg, stage, action, ""); err != nil {
+				failures = append(failures, shared.SyncFailure{Manager: e.name, Name: pkg.Name, Action: action, Err: err})
+				continue
+			}
+			ok = append(ok, pkg)
+		}
+		return
+	}
+
+	toSync := status.PackageStatus{
+		Synced:  packageStatus.Synced,
+		Missing: runHooks(shared.HookPre, packageStatus.Missing, shared.PtermSpinnerInstall),
+		Updated: runHooks(shared.HookPre, packageStatus.Updated, shared.PtermSpinnerUpdate),
+		Removed: runHooks(shared.HookPre, packageStatus.Removed, shared.PtermSpinnerRemove),
+	}
+
+	syncFailures, userWarnings, err := e.sync(ctx, packagesSyncRequest{Type: typePackage, Status: toSync})
+	if err != nil {
+		return nil, nil, err
+	}
+	failures = append(failures, syncFailures...)
+
+	runHooks(shared.HookPost, toSync.Missing, shared.PtermSpinnerInstall)
+	runHooks(shared.HookPost, toSync.Updated, shared.PtermSpinnerUpdate)
+	runHooks(shared.HookPost, toSync.Removed, shared.PtermSpinnerRemove)
+	return failures, userWarnings, nil
+}
+
+func (e *External) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	resp := planResponse{}
+	err = e.Call(ctx, methodPlan, dependenciesSyncRequest{Type: typeDependency, Status: depStatus}, &resp)
+	return resp.Actions, err
+}
+
+func (e *External) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	resp := planResponse{}
+	err = e.Call(ctx, methodPlan, packagesSyncRequest{Type: typePackage, Status: packageStatus}, &resp)
+	return resp.Actions, err
+}
+
+func (e *External) names(ctx context.Context, oType objectType, objects []string) []string {
+	resp := namesResponse{}
+	if err := e.Call(ctx, methodNames, objectsRequest{Type: oType, Objects: objects}, &resp); err != nil {
+		return objects
+	}
+	return resp.Names
+}
+
+func (e *External) add(ctx context.Context, oType objectType, objects []string) ([]string, []string, error) {
+	resp := objectsResponse{}
+	err := e.Call(ctx, methodAdd, objectsRequest{Type: oType, Objects: objects}, &resp)
+	return resp.Objects, resp.UserWarnings, err
+}
+
+func (e *External) remove(ctx context.Context, oType objectType, all []string, objects []string) ([]string, []string, error) {
+	resp := objectsResponse{}
+	err := e.Call(ctx, methodRemove, removeRequest{Type: oType, All: all, Objects: objects}, &resp)
+	return resp.Objects, resp.UserWarnings, err
+}
+
+func (e *External) sync(ctx context.Context, req any) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	resp := syncResponse{}
+	if err = e.Call(ctx, methodSync, req, &resp); err != nil {
+		return nil, nil, err
+	}
+
+	for _, f := range resp.Failures {
+		failures = append(failures, shared.SyncFailure{
+			Manager: e.name,
+			Name:    f.Name,
+			Action:  f.Action,
+			Err:     errors.New(f.Error),
+		})
+	}
+	return failures, resp.UserWarnings, nil
+}
@@ -0,0 +1,78 @@
+package external
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/stretchr/testify/assert"
+)
+
+const fakeManager = `#!/bin/sh
+cat > /dev/null
+case "$1" in
+info) echo '{"icon": "x", "short_desc": "Fake manager"}' ;;
+needs-sudo) echo '{"commands": ["sync"]}' ;;
+list) echo '{"synced": [{"name": "a", "full_name": "fake:a", "version": "1.0"}], "missing": [{"name": "b", "full_name": "fake:b"}]}' ;;
+sync) echo '{"failures": [{"name": "b", "action": "install", "error": "not found"}], "user_warnings": ["careful"]}' ;;
+add) echo '{"error": "add is not supported"}' ;;
+*) exit 1 ;;
+esac
+`
+
+func writeFakeManager(t *testing.T, dir string, name string) {
+	err := os.WriteFile(filepath.Join(dir, ExecutablePrefix+name), []byte(fakeManager), 0755)
+	assert.Nil(t, err, "should be no error")
+}
+
+func TestDiscover(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	writeFakeManager(t, dir1, "fake")
+	writeFakeManager(t, dir1, "dnf")
+	writeFakeManager(t, dir2, "fake")
+	writeFakeManager(t, dir2, "other")
+	err := os.WriteFile(filepath.Join(dir2, ExecutablePrefix+"noexec"), []byte(fakeManager), 0644)
+	assert.Nil(t, err, "should be no error")
+
+	managers := Discover([]string{dir1, dir2, filepath.Join(dir1, "missing")}, []shared.ManagerName{"dnf"})
+	assert.Len(t, managers, 2, "reserved, duplicate and non-executable managers should be skipped")
+	assert.Equal(t, shared.ManagerName("fake"), managers[0].Name())
+	assert.Equal(t, filepath.Join(dir1, ExecutablePrefix+"fake"), managers[0].path, "first directory should win")
+	assert.Equal(t, shared.ManagerName("other"), managers[1].Name())
+}
+
+func TestProtocol(t *testing.T) {
+	dir := t.TempDir()
+	writeFakeManager(t, dir, "fake")
+	m := New("fake", filepath.Join(dir, ExecutablePrefix+"fake"))
+	ctx := context.Background()
+
+	assert.Nil(t, m.InitCheckCmd(), "should be no error")
+	assert.Nil(t, m.InitCheckConfig(), "should be no error")
+	assert.Equal(t, "x", m.Icon())
+	assert.Equal(t, "Fake manager", m.ShortDesc())
+	assert.Equal(t, []shared.CommandName{shared.CommandSync}, m.NeedsSudo())
+
+	pkgStatus, err := m.ListPackages(ctx, []string{"fake:a", "fake:b"}, nil)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []shared.Package{{Name: "a", FullName: "fake:a", Version: "1.0"}}, pkgStatus.Synced)
+	assert.Equal(t, []shared.Package{{Name: "b", FullName: "fake:b"}}, pkgStatus.Missing)
+
+	failures, userWarnings, err := m.SyncPackages(ctx, status.PackageStatus{Missing: pkgStatus.Missing})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"careful"}, userWarnings)
+	assert.Len(t, failures, 1)
+	assert.Equal(t, shared.ManagerName("fake"), failures[0].Manager)
+	assert.Equal(t, shared.PtermSpinnerInstall, failures[0].Action)
+	assert.EqualError(t, failures[0].Err, "not found")
+
+	_, _, err = m.AddPackages(ctx, []string{"fake:c"})
+	assert.EqualError(t, err, "add is not supported", "error field should be returned as error")
+
+	_, err = m.InstallValidArgs(ctx, "", false)
+	assert.NotNil(t, err, "non-zero exit code should be an error")
+	assert.Equal(t, []string{"fake:a"}, m.GetPackageNames(ctx, []string{"fake:a"}), "names should fall back to the input")
+}
@@ -0,0 +1,167 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+)
+
+// Methods of the protocol. The method is passed as the only argument to the
+// executable, the request is written as JSON to stdin and the response is read
+// as JSON from stdout. See docs/external-managers.md.
+const (
+	methodInfo      = "info"
+	methodNeedsSudo = "needs-sudo"
+	methodNames     = "names"
+	methodValidArgs = "valid-args"
+	methodAdd       = "add"
+	methodRemove    = "remove"
+	methodList      = "list"
+	methodSync      = "sync"
+	methodPlan      = "plan"
+	methodInstalled = "installed"
+)
+
+type objectType string
+
+const (
+	typePackage    objectType = "package"
+	typeDependency objectType = "dependency"
+)
+
+func typeOf(dependencies bool) objectType {
+	if dependencies {
+		return typeDependency
+	}
+	return typePackage
+}
+
+type CommandExecutorFace interface {
+	Call(ctx context.Context, method string, req any, resp any) error
+}
+
+type commandExecutor struct {
+	path string
+}
+
+func (c commandExecutor) Call(ctx context.Context, method string, req any, resp any) error {
+	in, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	out, err := shared.Command(ctx, c.path, []string{method}, false, bytes.NewReader(in))
+	if err != nil {
+		return err
+	}
+
+	var envelope response
+	if err = json.Unmarshal([]byte(out), &envelope); err != nil {
+		return fmt.Errorf("%s: invalid response: %s", method, err)
+	}
+	if envelope.Error != "" {
+		return errors.New(envelope.Error)
+	}
+
+	return json.Unmarshal([]byte(out), resp)
+}
+
+type response struct {
+	Error        string   `json:"error,omitempty"`
+	UserWarnings []string `json:"user_warnings,omitempty"`
+}
+
+type infoResponse struct {
+	response
+	Icon      string `json:"icon"`
+	ShortDesc string `json:"short_desc"`
+	LongDesc  string `json:"long_desc"`
+}
+
+type needsSudoResponse struct {
+	response
+	Commands []shared.CommandName `json:"commands"`
+}
+
+type objectsRequest struct {
+	Type    objectType `json:"type"`
+	Objects []string   `json:"objects"`
+}
+
+type namesResponse struct {
+	response
+	Names []string `json:"names"`
+}
+
+type validArgsRequest struct {
+	Type       objectType `json:"type"`
+	ToComplete string     `json:"to_complete"`
+}
+
+type validArgsResponse struct {
+	response
+	Args []string `json:"args"`
+}
+
+type removeRequest struct {
+	Type    objectType `json:"type"`
+	All     []string   `json:"all"`
+	Objects []string   `json:"objects"`
+}
+
+type objectsResponse struct {
+	response
+	Objects []string `json:"objects"`
+}
+
+type listRequest struct {
+	Type    objectType `json:"type"`
+	Objects []string   `json:"objects"`
+	State   []string   `json:"state"`
+}
+
+type packagesStatusResponse struct {
+	response
+	status.PackageStatus
+}
+
+type installedResponse struct {
+	response
+	Packages []shared.Package `json:"packages"`
+}
+
+type dependenciesStatusResponse struct {
+	response
+	status.DependenciesStatus
+}
+
+type packagesSyncRequest struct {
+	Type   objectType           `json:"type"`
+	Status status.PackageStatus `json:"status"`
+}
+
+type dependenciesSyncRequest struct {
+	Type   objectType                `json:"type"`
+	Status status.DependenciesStatus `json:"status"`
+}
+
+type syncFailure struct {
+	Name   string                    `json:"name"`
+	Action shared.PtermSpinnerStatus `json:"action"`
+	Error  string                    `json:"error"`
+}
+
+type syncResponse struct {
+	response
+	Failures []syncFailure `json:"failures"`
+}
+
+type planResponse struct {
+	response
+	Actions []string `json:"actions"`
+}
@@ -10,6 +10,7 @@ import (
 
 type CommandExecutorFace interface {
 	ListInstalledPkgs(ctx context.Context, userSpaceInstallation bool) ([]shared.Package, error)
+	ListInstalledApps(ctx context.Context, userSpaceInstallation bool) ([]shared.Package, error)
 	ListUpdateablePkgs(ctx context.Context, userSpaceInstallation bool) ([]shared.Package, error)
 	InstallPkg(ctx context.Context, pkg shared.Package, userSpaceInstallation bool) error
 	UpdatePkg(ctx context.Context, pkg shared.Package, userSpaceInstallation bool) error
@@ -19,77 +20,76 @@ type CommandExecutorFace interface {
 type commandExecutor struct {
 }
 
-func (ce commandExecutor) InstallPkg(ctx context.Context, pkg shared.Package, userSpaceInstallation bool) error {
-	spaceFlag := "--system"
+func spaceFlag(userSpaceInstallation bool) string {
 	if userSpaceInstallation {
-		spaceFlag = "--user"
+		return "--user"
 	}
+	return "--system"
+}
 
-	err := checkNameFormat(pkg.FullName)
-	if err != nil {
-		return err
+func installPkgArgs(pkg shared.Package, userSpaceInstallation bool) ([]string, error) {
+	if err := checkNameFormat(pkg.FullName); err != nil {
+		return nil, err
 	}
+	flags := []string{"install", spaceFlag(userSpaceInstallation), "--assumeyes"}
+	return append(flags, strings.Split(pkg.FullName, ":")...), nil
+}
 
-	flags := []string{"install", spaceFlag, "--assumeyes"}
-	flags = append(flags, strings.Split(pkg.FullName, ":")...)
-
-	_, err = shared.Command(ctx, "flatpak", flags, false, nil)
-	if err != nil {
-		return err
+func updatePkgArgs(pkg shared.Package, userSpaceInstallation bool) ([]string, error) {
+	if err := checkNameFormat(pkg.FullName); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return []string{"update", spaceFlag(userSpaceInstallation), "--assumeyes", strings.Split(pkg.FullName, ":")[1]}, nil
 }
 
-func (ce commandExecutor) UpdatePkg(ctx context.Context, pkg shared.Package, userSpaceInstallation bool) error {
-	spaceFlag := "--system"
-	if userSpaceInstallation {
-		spaceFlag = "--user"
+func removePkgArgs(pkg shared.Package, userSpaceInstallation bool) ([]string, error) {
+	if err := checkNameFormat(pkg.FullName); err != nil {
+		return nil, err
 	}
+	return []string{"uninstall", spaceFlag(userSpaceInstallation), "--assumeyes", strings.Split(pkg.FullName, ":")[1]}, nil
+}
 
-	err := checkNameFormat(pkg.FullName)
+func (ce commandExecutor) InstallPkg(ctx context.Context, pkg shared.Package, userSpaceInstallation bool) error {
+	flags, err := installPkgArgs(pkg, userSpaceInstallation)
 	if err != nil {
 		return err
 	}
 
-	flags := []string{"update", spaceFlag, "--assumeyes", strings.Split(pkg.FullName, ":")[1]}
-
 	_, err = shared.Command(ctx, "flatpak", flags, false, nil)
+	return err
+}
+
+func (ce commandExecutor) UpdatePkg(ctx context.Context, pkg shared.Package, userSpaceInstallation bool) error {
+	flags, err := updatePkgArgs(pkg, userSpaceInstallation)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = shared.Command(ctx, "flatpak", flags, false, nil)
+	return err
 }
 
 func (ce commandExecutor) RemovePkg(ctx context.Context, pkg shared.Package, userSpaceInstallation bool) error {
-	spaceFlag := "--system"
-	if userSpaceInstallation {
-		spaceFlag = "--user"
-	}
-
-	err := checkNameFormat(pkg.FullName)
+	flags, err := removePkgArgs(pkg, userSpaceInstallation)
 	if err != nil {
 		return err
 	}
 
-	flags := []string{"uninstall", spaceFlag, "--assumeyes", strings.Split(pkg.FullName, ":")[1]}
-
 	_, err = shared.Command(ctx, "flatpak", flags, false, nil)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return err
 }
 
 func (ce commandExecutor) ListInstalledPkgs(ctx context.Context, userSpaceInstallation bool) (pkgs []shared.Package, err error) {
-	spaceFlag := "--system"
-	if userSpaceInstallation {
-		spaceFlag = "--user"
-	}
+	return ce.listInstalled(ctx, []string{spaceFlag(userSpaceInstallation)})
+}
+
+// ListInstalledApps lists the installed applications, without runtimes
+func (ce commandExecutor) ListInstalledApps(ctx context.Context, userSpaceInstallation bool) (pkgs []shared.Package, err error) {
+	return ce.listInstalled(ctx, []string{"--app", spaceFlag(userSpaceInstallation)})
+}
 
-	stdout, err := shared.Command(ctx, "flatpak", []string{"list", "--columns=origin,application,version", spaceFlag}, false, nil)
+func (ce commandExecutor) listInstalled(ctx context.Context, flags []string) (pkgs []shared.Package, err error) {
+	stdout, err := shared.Command(ctx, "flatpak", append([]string{"list", "--columns=origin,application,version"}, flags...), false, nil)
 	if err != nil {
 		return
 	}
@@ -118,12 +118,7 @@ func (ce commandExecutor) ListInstalledPkgs(ctx context.Context, userSpaceInstal
 }
 
 func (ce commandExecutor) ListUpdateablePkgs(ctx context.Context, userSpaceInstallation bool) (pkgs []shared.Package, err error) {
-	spaceFlag := "--system"
-	if userSpaceInstallation {
-		spaceFlag = "--user"
-	}
-
-	stdout, err := shared.Command(ctx, "flatpak", []string{"remote-ls", "--updates", "--columns=origin,application", spaceFlag}, false, nil)
+	stdout, err := shared.Command(ctx, "flatpak", []string{"remote-ls", "--updates", "--columns=origin,application", spaceFlag(userSpaceInstallation)}, false, nil)
 	if err != nil {
 		return
 	}
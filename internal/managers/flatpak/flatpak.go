@@ -8,7 +8,6 @@ import (
 
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/lucas-ingemar/packtrak/internal/status"
-	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 	"github.com/spf13/viper"
 )
@@ -90,6 +89,10 @@ func (f *Flatpak) AddPackages(ctx context.Context, pkgsToAdd []string) (packages
 	return
 }
 
+func (f *Flatpak) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	return f.ListInstalledApps(ctx, f.userSpaceInstallation)
+}
+
 func (f *Flatpak) ListPackages(ctx context.Context, packages []string, statePkgs []string) (packageStatus status.PackageStatus, err error) {
 	installedPkgs, err := f.ListInstalledPkgs(ctx, f.userSpaceInstallation)
 	if err != nil {
@@ -159,35 +162,59 @@ func (f *Flatpak) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRe
 	return
 }
 
-func (f *Flatpak) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (userWarnings []string, err error) {
-	for _, pkg := range packageStatus.Missing {
-		err = shared.PtermSpinner(shared.PtermSpinnerInstall, pkg.Name, func() error {
+func (f *Flatpak) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = shared.SyncEach(ctx, Name, packageStatus.Missing, packageStatus.Updated, packageStatus.Removed, f.SyncPackage)
+	return
+}
+
+// CanSyncConcurrently is true for user installations, system installations
+// might ask for a password
+func (f *Flatpak) CanSyncConcurrently() bool {
+	return f.userSpaceInstallation
+}
+
+func (f *Flatpak) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
+	return shared.RunWithHooks(ctx, Name, pkg, action, noHookPath, func() error {
+		switch action {
+		case shared.PtermSpinnerInstall:
 			return f.InstallPkg(ctx, pkg, f.userSpaceInstallation)
-		})
+		case shared.PtermSpinnerUpdate:
+			return f.UpdatePkg(ctx, pkg, f.userSpaceInstallation)
+		default:
+			return f.RemovePkg(ctx, pkg, f.userSpaceInstallation)
+		}
+	})
+}
+
+// noHookPath is used since flatpak apps have no path of their own to run
+// hooks in
+func noHookPath() string {
+	return ""
+}
+
+func (f *Flatpak) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	for _, pkg := range packageStatus.Missing {
+		args, err := installPkgArgs(pkg, f.userSpaceInstallation)
 		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+			return nil, err
 		}
+		actions = append(actions, shared.CommandString("flatpak", args))
 	}
 
 	for _, pkg := range packageStatus.Updated {
-		err = shared.PtermSpinner(shared.PtermSpinnerUpdate, pkg.Name, func() error {
-			return f.UpdatePkg(ctx, pkg, f.userSpaceInstallation)
-		})
+		args, err := updatePkgArgs(pkg, f.userSpaceInstallation)
 		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+			return nil, err
 		}
+		actions = append(actions, shared.CommandString("flatpak", args))
 	}
 
 	for _, pkg := range packageStatus.Removed {
-		err = shared.PtermSpinner(shared.PtermSpinnerRemove, pkg.Name, func() error {
-			return f.RemovePkg(ctx, pkg, f.userSpaceInstallation)
-		})
+		args, err := removePkgArgs(pkg, f.userSpaceInstallation)
 		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+			return nil, err
 		}
+		actions = append(actions, shared.CommandString("flatpak", args))
 	}
 	return
 }
@@ -208,6 +235,10 @@ func (f *Flatpak) RemoveDependencies(ctx context.Context, allDeps []string, deps
 	return
 }
 
-func (f *Flatpak) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (userWarnings []string, err error) {
+func (f *Flatpak) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	return
+}
+
+func (f *Flatpak) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
 	return
 }
@@ -3,6 +3,7 @@ package git
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/url"
 	"os"
 	"path"
@@ -16,7 +17,7 @@ import (
 
 type CommandExecutorFace interface {
 	ListInstalledPkgs(ctx context.Context, manifestPackages []string, folderPath string, includeUnstableReleases bool) ([]shared.Package, error)
-	GetRemotePkgMeta(ctx context.Context, pkgUrl string, includeUnstableReleases, useHeadRelease bool) (shared.Package, error)
+	GetRemotePkgMeta(ctx context.Context, pkgUrl string, pin string, includeUnstableReleases, useHeadRelease bool) (shared.Package, error)
 	InstallPkg(ctx context.Context, pkg shared.Package, folderPath string) error
 	UpdatePkg(ctx context.Context, pkg shared.Package, folderPath string) error
 	RemovePkg(ctx context.Context, pkg shared.Package, folderPath string) error
@@ -28,8 +29,12 @@ type commandExecutor struct {
 	git system.Git
 }
 
+func repoPath(folderPath string, pkgName string) string {
+	return path.Join(folderPath, strings.ReplaceAll(pkgName, "/", "."))
+}
+
 func (c commandExecutor) InstallPkg(ctx context.Context, pkg shared.Package, folderPath string) error {
-	repoPath := path.Join(folderPath, strings.ReplaceAll(pkg.Name, "/", "."))
+	repoPath := repoPath(folderPath, pkg.Name)
 	err := c.git.Clone(ctx, pkg.FullName, repoPath)
 	if err != nil {
 		return err
@@ -38,7 +43,7 @@ func (c commandExecutor) InstallPkg(ctx context.Context, pkg shared.Package, fol
 }
 
 func (c commandExecutor) UpdatePkg(ctx context.Context, pkg shared.Package, folderPath string) error {
-	repoPath := path.Join(folderPath, strings.ReplaceAll(pkg.Name, "/", "."))
+	repoPath := repoPath(folderPath, pkg.Name)
 	err := c.git.Pull(ctx, repoPath)
 	if err != nil {
 		err = c.RemovePkg(ctx, pkg, folderPath)
@@ -59,7 +64,7 @@ func (c commandExecutor) UpdatePkg(ctx context.Context, pkg shared.Package, fold
 }
 
 func (c commandExecutor) RemovePkg(ctx context.Context, pkg shared.Package, folderPath string) error {
-	repoPath := path.Join(folderPath, strings.ReplaceAll(pkg.Name, "/", "."))
+	repoPath := repoPath(folderPath, pkg.Name)
 
 	filePath, err := os.Stat(repoPath)
 	if err != nil {
@@ -73,10 +78,13 @@ func (c commandExecutor) RemovePkg(ctx context.Context, pkg shared.Package, fold
 	return os.RemoveAll(repoPath)
 }
 
-func (c commandExecutor) GetRemotePkgMeta(ctx context.Context, pkgUrl string, includeUnstableReleases, useHeadRelease bool) (pkg shared.Package, err error) {
+// GetRemotePkgMeta resolves the version to check out: the newest tag matching
+// the pin if there is one, otherwise the newest tag or the latest commit.
+func (c commandExecutor) GetRemotePkgMeta(ctx context.Context, pkgUrl string, pin string, includeUnstableReleases, useHeadRelease bool) (pkg shared.Package, err error) {
 	pkg.Name = c.PkgNameFromUrl(pkgUrl)
 	pkg.RepoUrl = pkgUrl
 	pkg.FullName = pkgUrl
+	pkg.Pin = pin
 
 	tags, err := c.git.ListRemoteTags(ctx, pkgUrl)
 	if err != nil {
@@ -87,12 +95,29 @@ func (c commandExecutor) GetRemotePkgMeta(ctx context.Context, pkgUrl string, in
 		if item == "latest" {
 			return false
 		}
+		if pin != "" {
+			// A tag pinned exactly is used even if it is a pre-release
+			return shared.MatchPin(pin, item) && (includeUnstableReleases || !shared.IsPinPattern(pin) || !preReleaseTag(item))
+		}
 		if !includeUnstableReleases && preReleaseTag(item) {
 			return false
 		}
 		return true
 	})
 
+	if pin != "" {
+		if len(tags) > 0 {
+			pkg.LatestVersion = tags[0]
+			return
+		}
+		if shared.IsPinPattern(pin) {
+			return shared.Package{}, fmt.Errorf("no tag matching %s found for %s", pin, pkgUrl)
+		}
+		// Not a tag, e.g. a commit from the lock file
+		pkg.LatestVersion = pin
+		return
+	}
+
 	if !useHeadRelease && len(tags) > 0 {
 		pkg.LatestVersion = tags[0]
 		return
@@ -135,7 +160,7 @@ func (c commandExecutor) ListInstalledPkgs(ctx context.Context, manifestPackages
 			RepoUrl:       "",
 		}
 
-		if !lo.Contains(manifestPackages, remoteUrl+":latest") {
+		if !lo.Contains(shared.UnpinAll(manifestPackages), remoteUrl+":latest") {
 			tag, err := c.git.GetCurrentTag(ctx, repoPath)
 			if err == nil {
 				pkg.Version = tag
@@ -156,7 +181,7 @@ func (c commandExecutor) ListInstalledPkgs(ctx context.Context, manifestPackages
 }
 
 func (c commandExecutor) GetBasicPkgInfo(ctx context.Context, pkgNickname string, folderPath string) (shared.Package, error) {
-	repoPath := path.Join(folderPath, strings.ReplaceAll(pkgNickname, "/", "."))
+	repoPath := repoPath(folderPath, pkgNickname)
 	remoteUrl, err := c.git.GetRemoteUrl(ctx, repoPath)
 	if err != nil {
 		return shared.Package{}, err
@@ -10,7 +10,6 @@ import (
 
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/lucas-ingemar/packtrak/internal/status"
-	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 	"github.com/spf13/viper"
 )
@@ -54,6 +53,10 @@ func (g *Git) NeedsSudo() []shared.CommandName {
 	return []shared.CommandName{}
 }
 
+func (g *Git) SupportsPins() bool {
+	return true
+}
+
 func (g *Git) InitCheckCmd() error {
 	_, err := exec.LookPath("git")
 	if err != nil {
@@ -90,7 +93,7 @@ func (g *Git) InitConfig() {
 func (g *Git) GetPackageNames(ctx context.Context, packages []string) []string {
 	var retpkgs []string
 	for _, p := range packages {
-		retpkgs = append(retpkgs, g.PkgNameFromUrl(p))
+		retpkgs = append(retpkgs, g.PkgNameFromUrl(shared.Unpin(p)))
 	}
 	return retpkgs
 }
@@ -123,14 +126,14 @@ func (g *Git) ListPackages(ctx context.Context, packages []string, statePkgs []s
 
 	pkgObjs := []shared.Package{}
 	for _, pkgNameWithTag := range packages {
-		pNT := strings.Split(pkgNameWithTag, ":")
-		pkgName := pkgNameWithTag
+		pkgName, pin := shared.SplitPin(pkgNameWithTag)
+		pNT := strings.Split(pkgName, ":")
 		useHeadRelease := false
 		if pNT[len(pNT)-1] == "latest" {
 			useHeadRelease = true
 			pkgName = strings.Join(pNT[:len(pNT)-1], ":")
 		}
-		pkg, err := g.GetRemotePkgMeta(ctx, pkgName, g.includeUnstableReleases, useHeadRelease)
+		pkg, err := g.GetRemotePkgMeta(ctx, pkgName, pin, g.includeUnstableReleases, useHeadRelease)
 		if err != nil {
 			return status.PackageStatus{}, err
 		}
@@ -143,6 +146,10 @@ func (g *Git) ListPackages(ctx context.Context, packages []string, statePkgs []s
 		})
 		if len(matchedPkgs) > 0 {
 			pkg.Version = matchedPkgs[0].Version
+			if pkg.Pin != "" && shared.MatchPin(pkg.Pin, pkg.Version) {
+				packageStatus.Synced = append(packageStatus.Synced, pkg)
+				continue
+			}
 			if pkg.Version != pkg.LatestVersion {
 				packageStatus.Updated = append(packageStatus.Updated, pkg)
 				continue
@@ -154,7 +161,7 @@ func (g *Git) ListPackages(ctx context.Context, packages []string, statePkgs []s
 	}
 
 	pkgsNoTags := lo.Map(packages, func(p string, _ int) string {
-		return strings.TrimSuffix(p, ":latest")
+		return strings.TrimSuffix(shared.Unpin(p), ":latest")
 	})
 
 	for _, pkg := range statePkgs {
@@ -169,6 +176,10 @@ func (g *Git) ListPackages(ctx context.Context, packages []string, statePkgs []s
 	return
 }
 
+func (g *Git) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	return g.ListInstalledPkgs(ctx, nil, g.pkgDirectory, g.includeUnstableReleases)
+}
+
 func (g *Git) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRemove []string) (packagesToRemove []string, userWarnings []string, err error) {
 	for _, p := range pkgsToRemove {
 		pkg, err := g.GetBasicPkgInfo(ctx, p, g.pkgDirectory)
@@ -184,40 +195,63 @@ func (g *Git) RemoveDependencies(ctx context.Context, allDeps []string, depsToRe
 	return
 }
 
-func (g *Git) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (userWarnings []string, err error) {
+func (g *Git) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
 	return
 }
 
-func (g *Git) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (userWarnings []string, err error) {
-	for _, pkg := range packageStatus.Missing {
-		err = shared.PtermSpinner(shared.PtermSpinnerInstall, pkg.Name, func() error {
+func (g *Git) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = shared.SyncEach(ctx, Name, packageStatus.Missing, packageStatus.Updated, packageStatus.Removed, g.SyncPackage)
+	return
+}
+
+func (g *Git) CanSyncConcurrently() bool {
+	return true
+}
+
+func (g *Git) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
+	return shared.RunWithHooks(ctx, Name, pkg, action, g.hookPath(pkg), func() error {
+		switch action {
+		case shared.PtermSpinnerInstall:
 			return g.InstallPkg(ctx, pkg, g.pkgDirectory)
-		})
-		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+		case shared.PtermSpinnerUpdate:
+			return g.UpdatePkg(ctx, pkg, g.pkgDirectory)
+		default:
+			return g.RemovePkg(ctx, pkg, g.pkgDirectory)
 		}
+	})
+}
+
+// hookPath returns the repository of the package, where its hooks run
+func (g *Git) hookPath(pkg shared.Package) func() string {
+	return func() string {
+		return repoPath(g.pkgDirectory, pkg.Name)
+	}
+}
+
+func (g *Git) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	return
+}
+
+func (g *Git) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	for _, pkg := range packageStatus.Missing {
+		rp := repoPath(g.pkgDirectory, pkg.Name)
+		actions = append(actions,
+			shared.CommandString("git", []string{"clone", pkg.FullName, rp}),
+			shared.CommandString("git", []string{"-C", rp, "checkout", pkg.LatestVersion}),
+		)
 	}
 
 	for _, pkg := range packageStatus.Updated {
-		err = shared.PtermSpinner(shared.PtermSpinnerUpdate, pkg.Name, func() error {
-			return g.UpdatePkg(ctx, pkg, g.pkgDirectory)
-		})
-		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
-		}
+		rp := repoPath(g.pkgDirectory, pkg.Name)
+		actions = append(actions,
+			shared.CommandString("git", []string{"-C", rp, "pull", "origin", "HEAD"}),
+			shared.CommandString("git", []string{"-C", rp, "fetch"}),
+			shared.CommandString("git", []string{"-C", rp, "checkout", pkg.LatestVersion}),
+		)
 	}
 
 	for _, pkg := range packageStatus.Removed {
-		err = shared.PtermSpinner(shared.PtermSpinnerRemove, pkg.Name, func() error {
-			return g.RemovePkg(ctx, pkg, g.pkgDirectory)
-		})
-		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
-		}
+		actions = append(actions, shared.CommandString("rm", []string{"-rf", repoPath(g.pkgDirectory, pkg.Name)}))
 	}
-
 	return
 }
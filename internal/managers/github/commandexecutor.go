@@ -39,6 +39,10 @@ func (ce commandExecutor) ListInstalledPkgs(ctx context.Context, folderPath stri
 		}
 		pkg := file2Package(e.Name())
 		if pkg != nil {
+			pkg.Checksum, err = fileChecksum(filepath.Join(folderPath, e.Name()))
+			if err != nil {
+				return nil, err
+			}
 			packages = append(packages, *pkg)
 		}
 	}
@@ -48,7 +52,8 @@ func (ce commandExecutor) ListInstalledPkgs(ctx context.Context, folderPath stri
 func (ce commandExecutor) GetManifestPackages(ctx context.Context, packages []string) (pkgObjs []shared.Package, err error) {
 	// FIXME: Add Github Access Token ENV
 	errs := []error{}
-	lo.ForEach(packages, func(pkgFullName string, _ int) {
+	lo.ForEach(packages, func(pkg string, _ int) {
+		pkgFullName, pin := shared.SplitPin(pkg)
 		pkgFullName, err = sanitizeGithubUrl(pkgFullName)
 		if err != nil {
 			errs = append(errs, err)
@@ -60,7 +65,12 @@ func (ce commandExecutor) GetManifestPackages(ctx context.Context, packages []st
 			return
 		}
 
-		latestVersion, err := ce.GetLatestRelease(ctx, user, repo, filePattern)
+		var latestVersion string
+		if pin != "" {
+			latestVersion, err = ce.GetPinnedRelease(ctx, user, repo, pin)
+		} else {
+			latestVersion, err = ce.GetLatestRelease(ctx, user, repo, filePattern)
+		}
 		if err != nil {
 			errs = append(errs, err)
 			return
@@ -70,17 +80,31 @@ func (ce commandExecutor) GetManifestPackages(ctx context.Context, packages []st
 			Name:          fmt.Sprintf("%s/%s", user, repo),
 			FullName:      pkgFullName,
 			LatestVersion: latestVersion,
+			Pin:           pin,
 		})
 	})
 	return pkgObjs, errors.Join(errs...)
 }
 
+// InstallPkg downloads the release pkg.LatestVersion. If pkg.Checksum is set the
+// download must match it.
 func (ce commandExecutor) InstallPkg(ctx context.Context, pkg shared.Package, folderPath, binPath string) error {
-	newFilename, err := ce.DownloadLatestRelease(ctx, pkg, folderPath)
+	newFilename, err := ce.DownloadRelease(ctx, pkg, folderPath)
 	if err != nil {
 		return err
 	}
 
+	if pkg.Checksum != "" {
+		checksum, err := fileChecksum(newFilename)
+		if err != nil {
+			return err
+		}
+		if checksum != pkg.Checksum {
+			os.Remove(newFilename)
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", pkg.Checksum, checksum)
+		}
+	}
+
 	err = os.Chmod(newFilename, 0755)
 	if err != nil {
 		return err
@@ -1,9 +1,13 @@
 package github
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/lucas-ingemar/packtrak/internal/shared"
@@ -69,3 +73,27 @@ func url2pkgComponents(ghUrl string) (user, repo, filePattern string, err error)
 	}
 	return urlCmps[1], urlCmps[2], cmps[1], nil
 }
+
+// assetUrl returns the download url of the release asset
+func assetUrl(ghUrl string, version string) (string, error) {
+	user, repo, filePattern, err := url2pkgComponents(ghUrl)
+	if err != nil {
+		return "", err
+	}
+	filename := strings.ReplaceAll(filePattern, "#version#", version)
+	return fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", user, repo, version, filename), nil
+}
+
+func fileChecksum(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
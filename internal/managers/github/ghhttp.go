@@ -2,7 +2,6 @@ package github
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,7 +15,8 @@ import (
 
 type GithubHttpFace interface {
 	GetLatestRelease(ctx context.Context, user, repo, filePattern string) (version string, err error)
-	DownloadLatestRelease(ctx context.Context, pkg shared.Package, targetFolder string) (newFilename string, err error)
+	GetPinnedRelease(ctx context.Context, user, repo, pin string) (version string, err error)
+	DownloadRelease(ctx context.Context, pkg shared.Package, targetFolder string) (newFilename string, err error)
 }
 
 type GithubHttp struct {
@@ -41,13 +41,41 @@ func (g GithubHttp) GetLatestRelease(ctx context.Context, user, repo, filePatter
 	return
 }
 
-func (g GithubHttp) DownloadLatestRelease(ctx context.Context, pkg shared.Package, targetFolder string) (newFilename string, err error) {
+// GetPinnedRelease returns the newest release tag matching the pin. Pins
+// without wildcards are returned as they are.
+func (g GithubHttp) GetPinnedRelease(ctx context.Context, user, repo, pin string) (version string, err error) {
+	if !shared.IsPinPattern(pin) {
+		return pin, nil
+	}
+
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases?per_page=100", user, repo)
+	resp, err := http.Get(url)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	// Releases are listed newest first
+	for _, tag := range gjson.GetBytes(body, "#.tag_name").Array() {
+		if shared.MatchPin(pin, tag.Str) {
+			return tag.Str, nil
+		}
+	}
+	return "", fmt.Errorf("could not find release matching %s for %s/%s", pin, user, repo)
+}
+
+// DownloadRelease downloads the asset of the release tagged pkg.LatestVersion
+func (g GithubHttp) DownloadRelease(ctx context.Context, pkg shared.Package, targetFolder string) (newFilename string, err error) {
 	user, repo, filePattern, err := url2pkgComponents(pkg.FullName)
 	if err != nil {
 		return
 	}
 
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/latest", user, repo)
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases/tags/%s", user, repo, pkg.LatestVersion)
 	resp, err := http.Get(url)
 	if err != nil {
 		return
@@ -62,7 +90,7 @@ func (g GithubHttp) DownloadLatestRelease(ctx context.Context, pkg shared.Packag
 
 	binaryUrl := gjson.GetBytes(body, fmt.Sprintf(`assets.#(name="%s").browser_download_url`, filename)).Str
 	if binaryUrl == "" {
-		return "", errors.New("could not find latest release url")
+		return "", fmt.Errorf("could not find %s in release %s", filename, pkg.LatestVersion)
 	}
 
 	newFilename = filepath.Join(targetFolder, package2Filename(pkg, filepath.Ext(binaryUrl)))
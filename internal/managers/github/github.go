@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/lucas-ingemar/packtrak/internal/status"
-	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 	"github.com/spf13/viper"
 )
@@ -57,6 +57,10 @@ func (gh *Github) NeedsSudo() []shared.CommandName {
 	return []shared.CommandName{}
 }
 
+func (gh *Github) SupportsPins() bool {
+	return true
+}
+
 func (gh *Github) InitConfig() {
 	viper.SetDefault(shared.ConfigKeyName(Name, packageDirectoryKey), "")
 	viper.SetDefault(shared.ConfigKeyName(Name, binDirectoryKey), "")
@@ -94,7 +98,7 @@ func (gh *Github) InitCheckConfig() error {
 func (gh *Github) GetPackageNames(ctx context.Context, packages []string) []string {
 	var retpkgs []string
 	for _, p := range packages {
-		user, repo, _, err := url2pkgComponents(p)
+		user, repo, _, err := url2pkgComponents(shared.Unpin(p))
 		if err != nil {
 			retpkgs = append(retpkgs, p)
 		} else {
@@ -137,6 +141,14 @@ func (gh *Github) ListPackages(ctx context.Context, packages []string, statePkgs
 		})
 		if len(matchedPkgs) > 0 {
 			pkg.Version = matchedPkgs[0].Version
+			pkg.Checksum = matchedPkgs[0].Checksum
+			if pkg.AssetUrl, err = assetUrl(pkg.FullName, pkg.Version); err != nil {
+				return status.PackageStatus{}, err
+			}
+			if pkg.Pin != "" && shared.MatchPin(pkg.Pin, pkg.Version) {
+				packageStatus.Synced = append(packageStatus.Synced, pkg)
+				continue
+			}
 			if pkg.Version != pkg.LatestVersion {
 				packageStatus.Updated = append(packageStatus.Updated, pkg)
 				continue
@@ -147,8 +159,9 @@ func (gh *Github) ListPackages(ctx context.Context, packages []string, statePkgs
 		}
 	}
 
+	unpinned := shared.UnpinAll(packages)
 	for _, pkg := range statePkgs {
-		if !lo.Contains(packages, pkg) {
+		if !lo.Contains(unpinned, pkg) {
 			user, repo, _, err := url2pkgComponents(pkg)
 			if err != nil {
 				return status.PackageStatus{}, err
@@ -163,6 +176,70 @@ func (gh *Github) ListPackages(ctx context.Context, packages []string, statePkgs
 	return
 }
 
+// ListInstalledPackages returns nothing, since the file pattern of a release
+// can not be derived from the downloaded file
+func (gh *Github) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	return
+}
+
+// ListStrayFiles returns the files in package_directory that don't belong to
+// any of knownPkgs, and the files in bin_directory that aren't linking to a
+// known package
+func (gh *Github) ListStrayFiles(ctx context.Context, knownPkgs []string) (files []string, err error) {
+	knownNames := gh.GetPackageNames(ctx, knownPkgs)
+	isKnown := func(filename string) bool {
+		pkg := file2Package(filepath.Base(filename))
+		return pkg != nil && lo.Contains(knownNames, pkg.Name)
+	}
+
+	pkgFiles, err := os.ReadDir(gh.pkgDirectory)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range pkgFiles {
+		if !e.IsDir() && !isKnown(e.Name()) {
+			files = append(files, filepath.Join(gh.pkgDirectory, e.Name()))
+		}
+	}
+
+	if !gh.symlinkToBin {
+		return
+	}
+
+	binFiles, err := os.ReadDir(gh.binDirectory)
+	if os.IsNotExist(err) {
+		return files, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, e := range binFiles {
+		if e.IsDir() {
+			continue
+		}
+		binFile := filepath.Join(gh.binDirectory, e.Name())
+		if target, err := os.Readlink(binFile); err == nil && filepath.Dir(target) == filepath.Clean(gh.pkgDirectory) && isKnown(target) {
+			continue
+		}
+		files = append(files, binFile)
+	}
+	return
+}
+
+// RemoveStrayFiles removes files returned by ListStrayFiles. Files outside of
+// package_directory and bin_directory are refused.
+func (gh *Github) RemoveStrayFiles(ctx context.Context, files []string) error {
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if dir != filepath.Clean(gh.pkgDirectory) && (gh.binDirectory == "" || dir != filepath.Clean(gh.binDirectory)) {
+			return fmt.Errorf("'%s' is not managed by %s", file, Name)
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (gh *Github) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRemove []string) (packagesToRemove []string, userWarnings []string, err error) {
 	for _, pR := range pkgsToRemove {
 		for _, pA := range allPkgs {
@@ -174,44 +251,109 @@ func (gh *Github) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRe
 	return
 }
 
-func (gh *Github) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (userWarnings []string, err error) {
+func (gh *Github) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = shared.SyncEach(ctx, Name, packageStatus.Missing, packageStatus.Updated, packageStatus.Removed, gh.SyncPackage)
+	return
+}
+
+func (gh *Github) CanSyncConcurrently() bool {
+	return true
+}
+
+func (gh *Github) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
 	binPath := ""
 	if gh.symlinkToBin {
 		binPath = gh.binDirectory
 	}
+	return shared.RunWithHooks(ctx, Name, pkg, action, gh.hookPath(pkg), func() error {
+		switch action {
+		case shared.PtermSpinnerInstall:
+			return gh.InstallPkg(ctx, pkg, gh.pkgDirectory, binPath)
+		case shared.PtermSpinnerUpdate:
+			if err := gh.RemovePkg(ctx, pkg, gh.pkgDirectory, binPath); err != nil {
+				return err
+			}
+			return gh.InstallPkg(ctx, pkg, gh.pkgDirectory, binPath)
+		default:
+			return gh.RemovePkg(ctx, pkg, gh.pkgDirectory, binPath)
+		}
+	})
+}
+
+// hookPath returns the downloaded release file of the package, or "" if it
+// isn't downloaded
+func (gh *Github) hookPath(pkg shared.Package) func() string {
+	return func() string {
+		user, repo, _, err := url2pkgComponents(pkg.FullName)
+		if err != nil {
+			return ""
+		}
+		files, err := filepath.Glob(filepath.Join(gh.pkgDirectory, fmt.Sprintf("%s.%s.*", user, repo)))
+		if err != nil || len(files) == 0 {
+			return ""
+		}
+		return files[0]
+	}
+}
+
+func (gh *Github) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	binPath := ""
+	if gh.symlinkToBin {
+		binPath = gh.binDirectory
+	}
+
+	installActions := func(pkg shared.Package) ([]string, error) {
+		user, repo, filePattern, err := url2pkgComponents(pkg.FullName)
+		if err != nil {
+			return nil, err
+		}
+		filename := strings.ReplaceAll(filePattern, "#version#", pkg.LatestVersion)
+		acts := []string{fmt.Sprintf("download %s from github.com/%s/%s release %s to %s", filename, user, repo, pkg.LatestVersion, gh.pkgDirectory)}
+		if binPath != "" {
+			acts = append(acts, fmt.Sprintf("symlink %s to %s", pkg.Name, filepath.Join(binPath, strings.ToLower(repo))))
+		}
+		return acts, nil
+	}
+
+	removeActions := func(pkg shared.Package) ([]string, error) {
+		user, repo, _, err := url2pkgComponents(pkg.FullName)
+		if err != nil {
+			return nil, err
+		}
+		acts := []string{shared.CommandString("rm", []string{filepath.Join(gh.pkgDirectory, fmt.Sprintf("%s.%s*", user, repo))})}
+		if binPath != "" {
+			acts = append(acts, shared.CommandString("rm", []string{filepath.Join(binPath, strings.ToLower(repo))}))
+		}
+		return acts, nil
+	}
 
 	for _, pkg := range packageStatus.Missing {
-		err = shared.PtermSpinner(shared.PtermSpinnerInstall, pkg.Name, func() error {
-			return gh.InstallPkg(ctx, pkg, gh.pkgDirectory, binPath)
-		})
+		acts, err := installActions(pkg)
 		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+			return nil, err
 		}
+		actions = append(actions, acts...)
 	}
 
 	for _, pkg := range packageStatus.Updated {
-		err = shared.PtermSpinner(shared.PtermSpinnerUpdate, pkg.Name, func() error {
-			err := gh.RemovePkg(ctx, pkg, gh.pkgDirectory, binPath)
-			if err != nil {
-				return err
-			}
-			return gh.InstallPkg(ctx, pkg, gh.pkgDirectory, binPath)
-		})
+		rActs, err := removeActions(pkg)
 		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+			return nil, err
 		}
+		iActs, err := installActions(pkg)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, rActs...)
+		actions = append(actions, iActs...)
 	}
 
 	for _, pkg := range packageStatus.Removed {
-		err = shared.PtermSpinner(shared.PtermSpinnerRemove, pkg.Name, func() error {
-			return gh.RemovePkg(ctx, pkg, gh.pkgDirectory, binPath)
-		})
+		acts, err := removeActions(pkg)
 		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+			return nil, err
 		}
+		actions = append(actions, acts...)
 	}
 	return
 }
@@ -232,6 +374,10 @@ func (gh *Github) RemoveDependencies(ctx context.Context, allDeps []string, deps
 	return
 }
 
-func (gh *Github) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (userWarnings []string, err error) {
+func (gh *Github) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	return
+}
+
+func (gh *Github) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
 	return
 }
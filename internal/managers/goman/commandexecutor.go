@@ -22,8 +22,26 @@ type CommandExecutorFace interface {
 type commandExecutor struct {
 }
 
+func installArgs(pkg shared.Package) []string {
+	return []string{"install", pkg.FullName + "@" + moduleQuery(pkg.Pin)}
+}
+
+// moduleQuery returns the pin as a version query of go install, which resolves
+// a version prefix like v0.15 to the latest v0.15.x. Versions need the leading
+// 'v', so 1.x becomes v1.
+func moduleQuery(pin string) string {
+	query := shared.PinPrefix(pin)
+	if query == "" {
+		return "latest"
+	}
+	if query[0] >= '0' && query[0] <= '9' {
+		query = "v" + query
+	}
+	return query
+}
+
 func (c *commandExecutor) Install(ctx context.Context, pkg shared.Package) error {
-	_, err := shared.Command(ctx, "go", []string{"install", pkg.FullName + "@latest"}, false, nil)
+	_, err := shared.Command(ctx, "go", installArgs(pkg), false, nil)
 	if err != nil {
 		return err
 	}
@@ -7,11 +7,11 @@ import (
 	"os/exec"
 	"path"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/lucas-ingemar/packtrak/internal/status"
-	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 )
 
@@ -47,6 +47,10 @@ func (g *Go) NeedsSudo() []shared.CommandName {
 	return []shared.CommandName{}
 }
 
+func (g *Go) SupportsPins() bool {
+	return true
+}
+
 func (g *Go) InitCheckCmd() error {
 	_, err := exec.LookPath("go")
 	if err != nil {
@@ -78,7 +82,7 @@ func (g *Go) InitConfig() {
 func (g *Go) GetPackageNames(ctx context.Context, packages []string) []string {
 	pkgNames := []string{}
 	for _, pkg := range packages {
-		pkgNames = append(pkgNames, g.nameFromFullName(pkg))
+		pkgNames = append(pkgNames, g.nameFromFullName(shared.Unpin(pkg)))
 	}
 	return pkgNames
 }
@@ -111,16 +115,30 @@ func (g *Go) ListPackages(ctx context.Context, packages []string, statePkgs []st
 		return
 	}
 
-	for _, pkgFullName := range packages {
+	for _, pkg := range packages {
+		pkgFullName, pin := shared.SplitPin(pkg)
 		pkgName := g.nameFromFullName(pkgFullName)
 		iPkg, err := shared.GetPackage(pkgName, installed)
 		if err != nil {
 			packageStatus.Missing = append(packageStatus.Missing, shared.Package{
 				Name:     pkgName,
 				FullName: pkgFullName,
+				Pin:      pin,
 			})
 			continue
 		}
+
+		if pin != "" {
+			iPkg.Pin = pin
+			if shared.MatchPin(pin, iPkg.Version) {
+				packageStatus.Synced = append(packageStatus.Synced, iPkg)
+			} else {
+				iPkg.LatestVersion = pin
+				packageStatus.Updated = append(packageStatus.Updated, iPkg)
+			}
+			continue
+		}
+
 		dPkg, err := shared.GetDepsDevDefaultPackage(string(g.Name()), iPkg)
 		if err != nil {
 			return packageStatus, err
@@ -139,8 +157,9 @@ func (g *Go) ListPackages(ctx context.Context, packages []string, statePkgs []st
 		}
 	}
 
+	unpinned := shared.UnpinAll(packages)
 	for _, pkg := range statePkgs {
-		if !lo.Contains(packages, pkg) {
+		if !lo.Contains(unpinned, pkg) {
 			packageStatus.Removed = append(packageStatus.Removed, shared.Package{
 				Name:     g.nameFromFullName(pkg),
 				FullName: pkg,
@@ -151,6 +170,10 @@ func (g *Go) ListPackages(ctx context.Context, packages []string, statePkgs []st
 	return
 }
 
+func (g *Go) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	return g.ListInstalled(ctx)
+}
+
 func (g *Go) RemovePackages(ctx context.Context, allPkgs []string, pkgs []string) (packagesUpdated []string, userWarnings []string, err error) {
 	binPath, err := g.BinPath()
 	if err != nil {
@@ -170,39 +193,62 @@ func (g *Go) RemoveDependencies(ctx context.Context, allDeps []string, depsToRem
 	return
 }
 
-func (g *Go) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (userWarnings []string, err error) {
+func (g *Go) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
 	return
 }
 
-func (g *Go) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (userWarnings []string, err error) {
-	for _, pkg := range packageStatus.Missing {
-		err = shared.PtermSpinner(shared.PtermSpinnerInstall, pkg.Name, func() error {
+func (g *Go) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = shared.SyncEach(ctx, Name, packageStatus.Missing, packageStatus.Updated, packageStatus.Removed, g.SyncPackage)
+	return
+}
+
+func (g *Go) CanSyncConcurrently() bool {
+	return true
+}
+
+func (g *Go) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
+	return shared.RunWithHooks(ctx, Name, pkg, action, g.hookPath(pkg), func() error {
+		switch action {
+		case shared.PtermSpinnerInstall:
 			return g.Install(ctx, pkg)
-		})
-		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+		case shared.PtermSpinnerUpdate:
+			return g.Install(ctx, pkg)
+		default:
+			return g.Remove(pkg)
 		}
-	}
+	})
+}
 
-	for _, pkg := range packageStatus.Updated {
-		err = shared.PtermSpinner(shared.PtermSpinnerUpdate, pkg.Name, func() error {
-			return g.Install(ctx, pkg)
-		})
+// hookPath returns the binary of the package
+func (g *Go) hookPath(pkg shared.Package) func() string {
+	return func() string {
+		binPath, err := g.BinPath()
 		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
+			return ""
 		}
+		return path.Join(binPath, pkg.Name)
 	}
+}
 
+func (g *Go) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	return
+}
+
+func (g *Go) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	for _, pkg := range slices.Concat(packageStatus.Missing, packageStatus.Updated) {
+		actions = append(actions, shared.CommandString("go", installArgs(pkg)))
+	}
+
+	if len(packageStatus.Removed) == 0 {
+		return
+	}
+
+	binPath, err := g.BinPath()
+	if err != nil {
+		return nil, err
+	}
 	for _, pkg := range packageStatus.Removed {
-		err = shared.PtermSpinner(shared.PtermSpinnerRemove, pkg.Name, func() error {
-			return g.Remove(pkg)
-		})
-		if err != nil {
-			log.Err(err).Str("manager", string(Name)).Str("package", pkg.Name)
-			err = nil
-		}
+		actions = append(actions, shared.CommandString("rm", []string{path.Join(binPath, pkg.Name)}))
 	}
 	return
 }
@@ -0,0 +1,20 @@
+package goman
+
+import (
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallArgs(t *testing.T) {
+	for pin, query := range map[string]string{
+		"":        "latest",
+		"v0.15.x": "v0.15",
+		"1.x":     "v1",
+		"1.2.3":   "v1.2.3",
+		"x":       "latest",
+	} {
+		assert.Equal(t, []string{"install", "golang.org/x/tools/gopls@" + query}, installArgs(shared.Package{FullName: "golang.org/x/tools/gopls", Pin: pin}), pin)
+	}
+}
@@ -4,18 +4,29 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/lucas-ingemar/packtrak/internal/managers/apt"
+	"github.com/lucas-ingemar/packtrak/internal/managers/cargo"
 	"github.com/lucas-ingemar/packtrak/internal/managers/dnf"
+	"github.com/lucas-ingemar/packtrak/internal/managers/external"
 	"github.com/lucas-ingemar/packtrak/internal/managers/flatpak"
 	"github.com/lucas-ingemar/packtrak/internal/managers/git"
 	"github.com/lucas-ingemar/packtrak/internal/managers/github"
 	"github.com/lucas-ingemar/packtrak/internal/managers/goman"
+	"github.com/lucas-ingemar/packtrak/internal/managers/npm"
+	"github.com/lucas-ingemar/packtrak/internal/managers/pacman"
+	"github.com/lucas-ingemar/packtrak/internal/managers/pipx"
 	"github.com/lucas-ingemar/packtrak/internal/shared"
 	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/samber/lo"
 	"github.com/spf13/viper"
 )
 
 var (
-	ManagersRegistered = []Manager{dnf.New(), flatpak.New(), git.New(), github.New(), goman.New()}
+	ManagersRegistered = []Manager{apt.New(), cargo.New(), dnf.New(), flatpak.New(), git.New(), github.New(), goman.New(), npm.New(), pacman.New(), pipx.New()}
+
+	// ManagersOptIn are disabled until they are enabled in the config, so hosts
+	// without them are not warned about them on every command
+	ManagersOptIn = []shared.ManagerName{apt.Name, cargo.Name, npm.Name, pacman.Name, pipx.Name}
 )
 
 type ManagerFactoryFace interface {
@@ -80,16 +91,71 @@ type Manager interface {
 	ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error)
 	ListPackages(ctx context.Context, packages []string, statePkgs []string) (packageStatus status.PackageStatus, err error)
 
+	// ListInstalledPackages returns the packages installed on the system that
+	// the manager can track, with FullName as it would be written in the manifest.
+	ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error)
+
 	RemovePackages(ctx context.Context, allPkgs []string, pkgsToRemove []string) (packagesToRemove []string, userWarnings []string, err error)
 	RemoveDependencies(ctx context.Context, allDeps []string, depsToRemove []string) (depsUpdated []string, userWarnings []string, err error)
 
-	SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (userWarnings []string, err error)
-	SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (userWarnings []string, err error)
+	// SyncDependencies and SyncPackages return a failure for every object that
+	// could not be synced. A non-nil err aborts the whole sync.
+	SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error)
+	SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error)
+
+	// PlanDependencies and PlanPackages describe the commands that the
+	// corresponding Sync function would run, without touching the system.
+	PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error)
+	PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error)
+}
+
+// StrayFileManager is implemented by managers that place files on the system
+// outside of any package manager, e.g. release binaries. Files in their
+// directories that don't belong to any of the known packages are reported as
+// drift.
+type StrayFileManager interface {
+	ListStrayFiles(ctx context.Context, knownPkgs []string) (files []string, err error)
+	RemoveStrayFiles(ctx context.Context, files []string) error
+}
+
+// ConcurrentManager is implemented by managers that can sync one package at a
+// time without sudo or an interactive terminal. Sync runs the packages of these
+// managers concurrently when more than one job is allowed. CanSyncConcurrently
+// tells if the current configuration allows it, and SyncPackage installs,
+// updates or removes a single package with its hooks.
+type ConcurrentManager interface {
+	CanSyncConcurrently() bool
+	SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error
+}
+
+// PinManager is implemented by managers that install the version pinned with
+// '@' in a manifest entry. With 'sync --locked' only their entries are pinned
+// to the locked versions, the entries of other managers are left as they are.
+type PinManager interface {
+	SupportsPins() bool
+}
+
+// RegisterExternalManagers appends all external managers found on the system
+// to ManagersRegistered. Names clashing with a built-in manager or a top level
+// command are ignored.
+func RegisterExternalManagers() {
+	reserved := append([]shared.ManagerName{"adopt", "completion", "drift", "help", "history", "list", "state", "sync", "version"}, RegisteredNames()...)
+
+	for _, e := range external.Discover(external.SearchDirs(), reserved) {
+		ManagersRegistered = append(ManagersRegistered, e)
+	}
+}
+
+// RegisteredNames returns the names of all registered managers, enabled or not
+func RegisteredNames() []shared.ManagerName {
+	return lo.Map(ManagersRegistered, func(m Manager, _ int) shared.ManagerName {
+		return m.Name()
+	})
 }
 
 func InitManagerConfig() {
 	for _, pm := range ManagersRegistered {
-		viper.SetDefault(keyName(pm, "enabled"), true)
+		viper.SetDefault(keyName(pm, "enabled"), !lo.Contains(ManagersOptIn, pm.Name()))
 		pm.InitConfig()
 	}
 }
@@ -114,6 +180,13 @@ func InitManagerFactory(managers []Manager, viperEnabled bool) (factory ManagerF
 	return
 }
 
+// ConfigHooks returns the hooks configured for every package of the manager,
+// under 'managers.<name>.hooks' in the config
+func ConfigHooks(name shared.ManagerName) (hooks shared.Hooks, err error) {
+	err = viper.UnmarshalKey(shared.ConfigKeyName(name, "hooks"), &hooks)
+	return
+}
+
 func keyName(m Manager, key string) string {
 	return fmt.Sprintf("managers.%s.%s", m.Name(), key)
 }
@@ -0,0 +1,22 @@
+package managers
+
+import (
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/managers/pacman"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitManagerConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	InitManagerConfig()
+	assert.True(t, viper.GetBool("managers.dnf.enabled"))
+	assert.False(t, viper.GetBool("managers.pacman.enabled"), "pacman is opt-in")
+
+	factory := InitManagerFactory([]Manager{pacman.New()}, true)
+	_, err := factory.GetManager(pacman.Name)
+	assert.NotNil(t, err, "pacman should not be checked or enabled")
+}
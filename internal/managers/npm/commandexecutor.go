@@ -0,0 +1,152 @@
+package npm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/alexellis/go-execute/v2"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+)
+
+type CommandExecutorFace interface {
+	ListInstalled(ctx context.Context, c cli) (packages []shared.Package, err error)
+	LatestVersion(ctx context.Context, c cli, name string) (version string, err error)
+	Root(ctx context.Context, c cli) (root string, err error)
+	Install(ctx context.Context, c cli, pkg shared.Package) error
+	Remove(ctx context.Context, c cli, pkg shared.Package) error
+}
+
+type commandExecutor struct {
+}
+
+// cli is the binary installing the global packages, npm or pnpm, and the
+// prefix it installs them in. An empty prefix is the default of the binary.
+type cli struct {
+	binary string
+	prefix string
+}
+
+// args returns the arguments with the prefix, which is --prefix for npm and
+// --global-dir for pnpm
+func (c cli) args(args ...string) []string {
+	if c.prefix == "" {
+		return args
+	}
+	if c.binary == binaryPnpm {
+		return append(args, "--global-dir", c.prefix)
+	}
+	return append(args, "--prefix", c.prefix)
+}
+
+func (c cli) installArgs(pkg shared.Package) []string {
+	version := "latest"
+	if pkg.Pin != "" {
+		// Both take a version range like 5.3.x as the newest matching version
+		version = pkg.Pin
+	}
+	cmd := "install"
+	if c.binary == binaryPnpm {
+		cmd = "add"
+	}
+	return c.args(cmd, "--global", pkg.FullName+"@"+version)
+}
+
+func (c cli) removeArgs(pkg shared.Package) []string {
+	cmd := "uninstall"
+	if c.binary == binaryPnpm {
+		cmd = "remove"
+	}
+	return c.args(cmd, "--global", pkg.FullName)
+}
+
+func (ce *commandExecutor) ListInstalled(ctx context.Context, c cli) (packages []shared.Package, err error) {
+	cmd := execute.ExecTask{
+		Command:     c.binary,
+		Args:        c.args("ls", "--global", "--json", "--depth=0"),
+		StreamStdio: false,
+	}
+
+	res, err := cmd.Execute(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// npm exits non-zero on problems like missing peer dependencies, but still
+	// lists the packages
+	if res.ExitCode != 0 && strings.TrimSpace(res.Stdout) == "" {
+		return nil, errors.New("Non-zero exit code: " + res.Stderr)
+	}
+	return parseList([]byte(res.Stdout))
+}
+
+// parseList parses the installed packages listed by 'npm ls --json', which is
+// an object, or by 'pnpm ls --json', which is a list of them
+func parseList(data []byte) (packages []shared.Package, err error) {
+	type listing struct {
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+
+	listings := []listing{}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err = json.Unmarshal(data, &listings)
+	} else {
+		l := listing{}
+		err = json.Unmarshal(data, &l)
+		listings = append(listings, l)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range listings {
+		for name, dep := range l.Dependencies {
+			packages = append(packages, shared.Package{
+				Name:     name,
+				FullName: name,
+				Version:  dep.Version,
+			})
+		}
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].FullName < packages[j].FullName
+	})
+	return
+}
+
+// LatestVersion returns the version of the latest tag in the registry
+func (ce *commandExecutor) LatestVersion(ctx context.Context, c cli, name string) (version string, err error) {
+	out, err := shared.Command(ctx, c.binary, []string{"view", name, "version"}, false, nil)
+	if err != nil {
+		return "", err
+	}
+	version = strings.TrimSpace(out)
+	if version == "" {
+		return "", errors.New("could not find version for " + name)
+	}
+	return
+}
+
+// Root returns the directory the global packages are installed in
+func (ce *commandExecutor) Root(ctx context.Context, c cli) (root string, err error) {
+	out, err := shared.Command(ctx, c.binary, c.args("root", "--global"), false, nil)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (ce *commandExecutor) Install(ctx context.Context, c cli, pkg shared.Package) error {
+	_, err := shared.Command(ctx, c.binary, c.installArgs(pkg), false, nil)
+	return err
+}
+
+func (ce *commandExecutor) Remove(ctx context.Context, c cli, pkg shared.Package) error {
+	_, err := shared.Command(ctx, c.binary, c.removeArgs(pkg), false, nil)
+	return err
+}
@@ -0,0 +1,235 @@
+package npm
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+func New() *Npm {
+	return &Npm{
+		CommandExecutorFace: &commandExecutor{},
+		cli:                 cli{binary: binaryNpm},
+	}
+}
+
+const Name shared.ManagerName = "npm"
+
+const (
+	binaryKey = "binary"
+	prefixKey = "prefix"
+)
+
+const (
+	binaryNpm  = "npm"
+	binaryPnpm = "pnpm"
+)
+
+type Npm struct {
+	cli cli
+	CommandExecutorFace
+}
+
+func (n *Npm) Name() shared.ManagerName {
+	return Name
+}
+
+func (n *Npm) Icon() string {
+	return ""
+}
+
+func (n *Npm) ShortDesc() string {
+	return "Install global npm packages"
+}
+
+func (n *Npm) LongDesc() string {
+	return "Install global packages from the npm registry with npm or pnpm, e.g. language servers and CLIs"
+}
+
+func (n *Npm) NeedsSudo() []shared.CommandName {
+	return []shared.CommandName{}
+}
+
+func (n *Npm) SupportsPins() bool {
+	return true
+}
+
+func (n *Npm) InitCheckCmd() error {
+	n.cli.binary = viper.GetString(shared.ConfigKeyName(Name, binaryKey))
+	if !lo.Contains([]string{binaryNpm, binaryPnpm}, n.cli.binary) {
+		return fmt.Errorf("config '%s' must be %s or %s", binaryKey, binaryNpm, binaryPnpm)
+	}
+	_, err := exec.LookPath(n.cli.binary)
+	if err != nil {
+		return fmt.Errorf("'%s' command not found on the computer", n.cli.binary)
+	}
+	return nil
+}
+
+func (n *Npm) InitCheckConfig() error {
+	n.cli.prefix = viper.GetString(shared.ConfigKeyName(Name, prefixKey))
+	return nil
+}
+
+func (n *Npm) InitConfig() {
+	viper.SetDefault(shared.ConfigKeyName(Name, binaryKey), binaryNpm)
+	viper.SetDefault(shared.ConfigKeyName(Name, prefixKey), "")
+}
+
+func (n *Npm) GetPackageNames(ctx context.Context, packages []string) []string {
+	return shared.UnpinAll(packages)
+}
+
+func (n *Npm) GetDependencyNames(ctx context.Context, deps []string) []string {
+	return []string{}
+}
+
+func (n *Npm) AddPackages(ctx context.Context, pkgsToAdd []string) (packagesUpdated []string, userWarnings []string, err error) {
+	return pkgsToAdd, nil, nil
+}
+
+func (n *Npm) AddDependencies(ctx context.Context, depsToAdd []string) (depsUpdated []string, userWarnings []string, err error) {
+	return
+}
+
+func (n *Npm) InstallValidArgs(ctx context.Context, toComplete string, dependencies bool) ([]string, error) {
+	return []string{}, nil
+}
+
+func (n *Npm) ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error) {
+	return
+}
+
+func (n *Npm) ListPackages(ctx context.Context, packages []string, statePkgs []string) (packageStatus status.PackageStatus, err error) {
+	installed, err := n.ListInstalled(ctx, n.cli)
+	if err != nil {
+		return
+	}
+
+	for _, pkg := range packages {
+		pkgName, pin := shared.SplitPin(pkg)
+		iPkg, err := shared.GetPackage(pkgName, installed)
+		if err != nil {
+			packageStatus.Missing = append(packageStatus.Missing, shared.Package{
+				Name:     pkgName,
+				FullName: pkgName,
+				Pin:      pin,
+			})
+			continue
+		}
+
+		if pin != "" {
+			iPkg.Pin = pin
+			if shared.MatchPin(pin, iPkg.Version) {
+				packageStatus.Synced = append(packageStatus.Synced, iPkg)
+			} else {
+				iPkg.LatestVersion = pin
+				packageStatus.Updated = append(packageStatus.Updated, iPkg)
+			}
+			continue
+		}
+
+		latest, err := n.LatestVersion(ctx, n.cli, pkgName)
+		if err != nil {
+			return packageStatus, err
+		}
+
+		if iPkg.Version == latest {
+			packageStatus.Synced = append(packageStatus.Synced, iPkg)
+		} else {
+			iPkg.LatestVersion = latest
+			packageStatus.Updated = append(packageStatus.Updated, iPkg)
+		}
+	}
+
+	unpinned := shared.UnpinAll(packages)
+	for _, pkg := range statePkgs {
+		if !lo.Contains(unpinned, pkg) {
+			packageStatus.Removed = append(packageStatus.Removed, shared.Package{
+				Name:     pkg,
+				FullName: pkg,
+			})
+		}
+	}
+
+	return
+}
+
+func (n *Npm) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	installed, err := n.ListInstalled(ctx, n.cli)
+	if err != nil {
+		return nil, err
+	}
+	// npm itself and corepack come with node
+	return lo.Filter(installed, func(pkg shared.Package, _ int) bool {
+		return !lo.Contains([]string{"npm", "corepack"}, pkg.Name)
+	}), nil
+}
+
+func (n *Npm) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRemove []string) (packagesToRemove []string, userWarnings []string, err error) {
+	return pkgsToRemove, nil, nil
+}
+
+func (n *Npm) RemoveDependencies(ctx context.Context, allDeps []string, depsToRemove []string) (depsUpdated []string, userWarnings []string, err error) {
+	return
+}
+
+func (n *Npm) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	return
+}
+
+func (n *Npm) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = shared.SyncEach(ctx, Name, packageStatus.Missing, packageStatus.Updated, packageStatus.Removed, n.SyncPackage)
+	return
+}
+
+// CanSyncConcurrently is false since all global packages share one
+// node_modules, which npm and pnpm don't lock
+func (n *Npm) CanSyncConcurrently() bool {
+	return false
+}
+
+func (n *Npm) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
+	return shared.RunWithHooks(ctx, Name, pkg, action, n.hookPath(ctx, pkg), func() error {
+		switch action {
+		case shared.PtermSpinnerInstall:
+			return n.Install(ctx, n.cli, pkg)
+		case shared.PtermSpinnerUpdate:
+			return n.Install(ctx, n.cli, pkg)
+		default:
+			return n.Remove(ctx, n.cli, pkg)
+		}
+	})
+}
+
+// hookPath returns the directory of the package in the global node_modules
+func (n *Npm) hookPath(ctx context.Context, pkg shared.Package) func() string {
+	return func() string {
+		root, err := n.Root(ctx, n.cli)
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(root, filepath.FromSlash(pkg.FullName))
+	}
+}
+
+func (n *Npm) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	return
+}
+
+func (n *Npm) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	for _, pkg := range slices.Concat(packageStatus.Missing, packageStatus.Updated) {
+		actions = append(actions, shared.CommandString(n.cli.binary, n.cli.installArgs(pkg)))
+	}
+	for _, pkg := range packageStatus.Removed {
+		actions = append(actions, shared.CommandString(n.cli.binary, n.cli.removeArgs(pkg)))
+	}
+	return
+}
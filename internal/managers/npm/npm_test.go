@@ -0,0 +1,118 @@
+package npm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testNpmList = `{
+  "name": "lib",
+  "dependencies": {
+    "typescript": {"version": "5.3.3", "overridden": false},
+    "@angular/cli": {"version": "17.0.0", "overridden": false},
+    "npm": {"version": "10.2.4", "overridden": false}
+  }
+}`
+
+const testPnpmList = `[
+  {
+    "path": "/home/user/.local/share/pnpm/global/5",
+    "private": false,
+    "dependencies": {
+      "typescript": {"from": "typescript", "version": "5.3.3", "resolved": "https://registry.npmjs.org/typescript/-/typescript-5.3.3.tgz"}
+    }
+  }
+]`
+
+// fakeExecutor has the npm packages above installed in root, and records the
+// commands it is asked to run
+type fakeExecutor struct {
+	commandExecutor
+	root     string
+	commands []string
+}
+
+func (f *fakeExecutor) Root(ctx context.Context, c cli) (string, error) {
+	return f.root, nil
+}
+
+func (f *fakeExecutor) Install(ctx context.Context, c cli, pkg shared.Package) error {
+	f.commands = append(f.commands, shared.CommandString(c.binary, c.installArgs(pkg)))
+	return nil
+}
+
+func (f *fakeExecutor) Remove(ctx context.Context, c cli, pkg shared.Package) error {
+	f.commands = append(f.commands, shared.CommandString(c.binary, c.removeArgs(pkg)))
+	return nil
+}
+
+func (f *fakeExecutor) ListInstalled(ctx context.Context, c cli) ([]shared.Package, error) {
+	return parseList([]byte(testNpmList))
+}
+
+func (f *fakeExecutor) LatestVersion(ctx context.Context, c cli, name string) (string, error) {
+	return map[string]string{"typescript": "5.4.2", "@angular/cli": "17.0.0"}[name], nil
+}
+
+func TestParseList(t *testing.T) {
+	pkgs, err := parseList([]byte(testNpmList))
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"@angular/cli", "npm", "typescript"}, shared.FullNames(pkgs))
+
+	pkgs, err = parseList([]byte(testPnpmList))
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []shared.Package{{Name: "typescript", FullName: "typescript", Version: "5.3.3"}}, pkgs)
+}
+
+func TestSyncPackages(t *testing.T) {
+	pterm.DisableOutput()
+	t.Cleanup(pterm.EnableOutput)
+
+	executor := &fakeExecutor{root: t.TempDir()}
+	n := New()
+	n.CommandExecutorFace = executor
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "out")
+
+	pkgStatus, err := n.ListPackages(ctx, []string{"typescript", "@angular/cli@16.x", "pyright"}, []string{"typescript", "eslint"})
+	assert.Nil(t, err, "should be no error")
+	assert.Empty(t, pkgStatus.Synced)
+	assert.Equal(t, []string{"typescript", "@angular/cli"}, shared.FullNames(pkgStatus.Updated))
+	assert.Equal(t, []string{"pyright"}, shared.FullNames(pkgStatus.Missing))
+	assert.Equal(t, []string{"eslint"}, shared.FullNames(pkgStatus.Removed))
+
+	// The hook runs in the directory of the package, scoped packages included
+	assert.Nil(t, os.MkdirAll(filepath.Join(executor.root, "@angular", "cli"), 0755))
+	pkgStatus.Updated[1].Hooks = shared.Hooks{PostUpdate: "pwd > " + out}
+	failures, _, err := n.SyncPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Empty(t, failures)
+	assert.Equal(t, []string{
+		"npm install --global pyright@latest",
+		"npm install --global typescript@latest",
+		"npm install --global @angular/cli@16.x",
+		"npm uninstall --global eslint",
+	}, executor.commands)
+	b, _ := os.ReadFile(out)
+	assert.Equal(t, filepath.Join(executor.root, "@angular", "cli")+"\n", string(b))
+
+	executor.commands = nil
+	n.cli = cli{binary: binaryPnpm, prefix: "/opt/pnpm"}
+	pkgStatus.Updated = nil
+	_, _, err = n.SyncPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{
+		"pnpm add --global pyright@latest --global-dir /opt/pnpm",
+		"pnpm remove --global eslint --global-dir /opt/pnpm",
+	}, executor.commands, "the prefix is passed as --global-dir to pnpm")
+
+	installed, err := n.ListInstalledPackages(ctx)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"@angular/cli", "typescript"}, shared.FullNames(installed), "npm should not be adopted")
+}
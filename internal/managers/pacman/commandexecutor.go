@@ -0,0 +1,216 @@
+package pacman
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/alexellis/go-execute/v2"
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+)
+
+type CommandExecutorFace interface {
+	InstallPkg(ctx context.Context, pkgs []shared.Package) error
+	InstallAurPkg(ctx context.Context, helper string, pkgs []shared.Package) error
+	RemovePkg(ctx context.Context, pkgs []shared.Package) error
+	ListInstalledPkgs(ctx context.Context) ([]string, []string, error)
+	ListUserInstalledPkgs(ctx context.Context) ([]string, error)
+	ListForeignPkgs(ctx context.Context) ([]string, error)
+	ListUpgradablePkgs(ctx context.Context, helper string) (map[string]string, error)
+}
+
+type commandExecutor struct {
+	cacheAllInstalled         []string
+	cacheAllInstalledVersions []string
+	cacheUserInstalled        []string
+	cacheForeign              []string
+	cacheUpgradable           map[string]string
+}
+
+// pkgArgs returns the arguments to pacman, or to the AUR helper which takes
+// the same ones. Packages are given without their pins, since only the version
+// in the sync database can be installed.
+func pkgArgs(action string, pkgs []shared.Package) []string {
+	cmds := []string{}
+	switch action {
+	case "install":
+		cmds = append(cmds, "-S", "--needed")
+	case "remove":
+		cmds = append(cmds, "-Rs")
+	}
+	if *config.AssumeYes {
+		cmds = append(cmds, "--noconfirm")
+	}
+
+	for _, pkg := range pkgs {
+		cmds = append(cmds, pkg.Name)
+	}
+	return cmds
+}
+
+func (a *commandExecutor) InstallPkg(ctx context.Context, pkgs []shared.Package) error {
+	if len(pkgs) == 0 {
+		return errors.New("no packages provided")
+	}
+	defer a.clearCache()
+	return runInteractive(ctx, "sudo", append([]string{"pacman"}, pkgArgs("install", pkgs)...))
+}
+
+// InstallAurPkg builds and installs the packages with the AUR helper, which
+// runs as the user and asks for sudo by itself
+func (a *commandExecutor) InstallAurPkg(ctx context.Context, helper string, pkgs []shared.Package) error {
+	if len(pkgs) == 0 {
+		return errors.New("no packages provided")
+	}
+	defer a.clearCache()
+	return runInteractive(ctx, helper, pkgArgs("install", pkgs))
+}
+
+func (a *commandExecutor) RemovePkg(ctx context.Context, pkgs []shared.Package) error {
+	if len(pkgs) == 0 {
+		return errors.New("no packages provided")
+	}
+	defer a.clearCache()
+	return runInteractive(ctx, "sudo", append([]string{"pacman"}, pkgArgs("remove", pkgs)...))
+}
+
+// clearCache forgets the listed packages, it is called when the system is
+// changed so that they are listed again
+func (a *commandExecutor) clearCache() {
+	a.cacheAllInstalled = nil
+	a.cacheAllInstalledVersions = nil
+	a.cacheUserInstalled = nil
+	a.cacheForeign = nil
+	a.cacheUpgradable = nil
+}
+
+func runInteractive(ctx context.Context, command string, args []string) error {
+	cmd := execute.ExecTask{
+		Command:     command,
+		Args:        args,
+		StreamStdio: true,
+		Stdin:       os.Stdin,
+	}
+
+	res, err := cmd.Execute(ctx)
+	if err != nil {
+		return err
+	}
+
+	if res.ExitCode != 0 {
+		return errors.New("Non-zero exit code: " + res.Stderr)
+	}
+
+	return nil
+}
+
+// query runs 'pacman -Q' with the flags. pacman exits with 1 when no package
+// matches, which is not an error here.
+func query(ctx context.Context, command string, flags string) (string, error) {
+	cmd := execute.ExecTask{
+		Command:     command,
+		Args:        []string{flags},
+		StreamStdio: false,
+	}
+
+	res, err := cmd.Execute(ctx)
+	if err != nil {
+		return "", err
+	}
+	if res.ExitCode != 0 && !(res.ExitCode == 1 && strings.TrimSpace(res.Stdout) == "") {
+		return "", errors.New("Non-zero exit code: " + res.Stderr)
+	}
+	return res.Stdout, nil
+}
+
+func (a *commandExecutor) ListInstalledPkgs(ctx context.Context) ([]string, []string, error) {
+	if len(a.cacheAllInstalled) > 0 && len(a.cacheAllInstalledVersions) > 0 {
+		return a.cacheAllInstalled, a.cacheAllInstalledVersions, nil
+	}
+
+	out, err := query(ctx, "pacman", "-Q")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 2 {
+			continue
+		}
+		a.cacheAllInstalled = append(a.cacheAllInstalled, parts[0])
+		a.cacheAllInstalledVersions = append(a.cacheAllInstalledVersions, parts[1])
+	}
+	return a.cacheAllInstalled, a.cacheAllInstalledVersions, nil
+}
+
+func (a *commandExecutor) ListUserInstalledPkgs(ctx context.Context) ([]string, error) {
+	if len(a.cacheUserInstalled) > 0 {
+		return a.cacheUserInstalled, nil
+	}
+
+	out, err := query(ctx, "pacman", "-Qqe")
+	if err != nil {
+		return nil, err
+	}
+	a.cacheUserInstalled = strings.Fields(out)
+	return a.cacheUserInstalled, nil
+}
+
+// ListForeignPkgs returns the installed packages that are in none of the sync
+// databases, i.e. the ones from the AUR or built by hand
+func (a *commandExecutor) ListForeignPkgs(ctx context.Context) ([]string, error) {
+	if len(a.cacheForeign) > 0 {
+		return a.cacheForeign, nil
+	}
+
+	out, err := query(ctx, "pacman", "-Qqm")
+	if err != nil {
+		return nil, err
+	}
+	a.cacheForeign = strings.Fields(out)
+	return a.cacheForeign, nil
+}
+
+// ListUpgradablePkgs returns the packages with a newer version in the sync
+// databases, and in the AUR if a helper is given, mapped to that version. The
+// sync databases are not refreshed.
+func (a *commandExecutor) ListUpgradablePkgs(ctx context.Context, helper string) (map[string]string, error) {
+	if a.cacheUpgradable != nil {
+		return a.cacheUpgradable, nil
+	}
+
+	out, err := query(ctx, "pacman", "-Qu")
+	if err != nil {
+		return nil, err
+	}
+	upgradable := parseUpgradable(out)
+
+	if helper != "" {
+		out, err := query(ctx, helper, "-Qua")
+		if err != nil {
+			return nil, err
+		}
+		upgradable = lo.Assign(upgradable, parseUpgradable(out))
+	}
+
+	a.cacheUpgradable = upgradable
+	return a.cacheUpgradable, nil
+}
+
+// parseUpgradable parses lines like 'ripgrep 14.0.3-1 -> 14.1.0-1', which
+// pacman ends with '[ignored]' for packages in IgnorePkg
+func parseUpgradable(out string) map[string]string {
+	upgradable := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 4 || parts[2] != "->" || lo.Contains(parts, "[ignored]") {
+			continue
+		}
+		upgradable[parts[0]] = parts[3]
+	}
+	return upgradable
+}
@@ -0,0 +1,365 @@
+package pacman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/samber/lo"
+	"github.com/spf13/viper"
+)
+
+func New() *Pacman {
+	return &Pacman{
+		CommandExecutorFace: &commandExecutor{},
+	}
+}
+
+const Name shared.ManagerName = "pacman"
+
+const aurHelperKey = "aur_helper"
+
+// aurPrefix marks the packages in the manifest that are built from the AUR
+const aurPrefix = "aur:"
+
+var aurHelpers = []string{"paru", "yay"}
+
+var errNoAurHelper = fmt.Errorf("AUR package, but no '%s' in the config", aurHelperKey)
+
+type Pacman struct {
+	aurHelper string
+	CommandExecutorFace
+}
+
+func (p *Pacman) Name() shared.ManagerName {
+	return Name
+}
+
+func (p *Pacman) Icon() string {
+	return ""
+}
+
+func (p *Pacman) ShortDesc() string {
+	return "A package manager for Arch-based Linux distributions"
+}
+
+func (p *Pacman) LongDesc() string {
+	return "Pacman is the package manager of Arch Linux and its derivatives. Packages from the AUR, written as 'aur:package', are installed with an AUR helper like paru or yay."
+}
+
+func (p *Pacman) NeedsSudo() []shared.CommandName {
+	return []shared.CommandName{shared.CommandInstall, shared.CommandRemove, shared.CommandSync}
+}
+
+func (p *Pacman) InitCheckCmd() error {
+	_, err := exec.LookPath("pacman")
+	if err != nil {
+		return errors.New("'pacman' command not found on the computer")
+	}
+
+	p.aurHelper = viper.GetString(shared.ConfigKeyName(Name, aurHelperKey))
+	if p.aurHelper == "" {
+		return nil
+	}
+	if !lo.Contains(aurHelpers, p.aurHelper) {
+		return fmt.Errorf("config '%s' must be empty or one of %s", aurHelperKey, strings.Join(aurHelpers, ", "))
+	}
+	_, err = exec.LookPath(p.aurHelper)
+	if err != nil {
+		return fmt.Errorf("'%s' command not found on the computer", p.aurHelper)
+	}
+	return nil
+}
+
+func (p *Pacman) InitConfig() {
+	viper.SetDefault(shared.ConfigKeyName(Name, aurHelperKey), "")
+}
+
+func (p *Pacman) InitCheckConfig() error {
+	return nil
+}
+
+func (p *Pacman) GetPackageNames(ctx context.Context, packages []string) []string {
+	return shared.UnpinAll(packages)
+}
+
+func (p *Pacman) GetDependencyNames(ctx context.Context, deps []string) []string {
+	return []string{}
+}
+
+func (p *Pacman) AddPackages(ctx context.Context, pkgsToAdd []string) (packagesUpdated []string, userWarnings []string, err error) {
+	for _, entry := range pkgsToAdd {
+		pkg := parsePackage(entry)
+		isSysPkg, err := p.isSystemPackage(ctx, pkg.Name)
+		if err != nil {
+			return packagesUpdated, []string{}, err
+		}
+
+		if isSysPkg {
+			userWarnings = append(userWarnings, fmt.Sprintf("'%s' is a system package and cannot be managed", entry))
+			continue
+		}
+		if pkg.Pin != "" {
+			userWarnings = append(userWarnings, fmt.Sprintf("'%s' is pinned, pacman can only install the version in the sync databases", entry))
+			continue
+		}
+		if isAur(pkg) && p.aurHelper == "" {
+			userWarnings = append(userWarnings, fmt.Sprintf("'%s' is an AUR package, set '%s' in the config to install it", entry, aurHelperKey))
+		}
+		packagesUpdated = append(packagesUpdated, entry)
+	}
+	return packagesUpdated, userWarnings, nil
+}
+
+func (p *Pacman) AddDependencies(ctx context.Context, depsToAdd []string) (depsUpdated []string, userWarnings []string, err error) {
+	return
+}
+
+func (p *Pacman) InstallValidArgs(ctx context.Context, toComplete string, dependencies bool) ([]string, error) {
+	if dependencies {
+		return []string{}, nil
+	}
+
+	res, err := shared.Command(ctx, "pacman", []string{"-Slq"}, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Filter(strings.Fields(res), func(pkg string, _ int) bool {
+		return strings.HasPrefix(pkg, toComplete)
+	}), nil
+}
+
+func (p *Pacman) ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error) {
+	return
+}
+
+func (p *Pacman) ListPackages(ctx context.Context, packages []string, statePkgs []string) (packageStatus status.PackageStatus, err error) {
+	installed, versions, err := p.ListInstalledPkgs(ctx)
+	if err != nil {
+		return
+	}
+
+	upgradable, err := p.ListUpgradablePkgs(ctx, p.aurHelper)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range packages {
+		pkg := parsePackage(entry)
+		idx := lo.IndexOf(installed, pkg.Name)
+		if idx == -1 {
+			packageStatus.Missing = append(packageStatus.Missing, pkg)
+			continue
+		}
+
+		pkg.Version = versions[idx]
+		if pkg.Pin != "" {
+			if matchPacmanPin(pkg.Pin, pkg.Version) {
+				packageStatus.Synced = append(packageStatus.Synced, pkg)
+			} else {
+				pkg.LatestVersion = pkg.Pin
+				packageStatus.Updated = append(packageStatus.Updated, pkg)
+			}
+			continue
+		}
+
+		if latest, found := upgradable[pkg.Name]; found {
+			pkg.LatestVersion = latest
+			packageStatus.Updated = append(packageStatus.Updated, pkg)
+		} else {
+			packageStatus.Synced = append(packageStatus.Synced, pkg)
+		}
+	}
+
+	unpinned := shared.UnpinAll(packages)
+	for _, entry := range statePkgs {
+		pkg := parsePackage(entry)
+		if lo.Contains(installed, pkg.Name) && !lo.Contains(unpinned, pkg.FullName) {
+			packageStatus.Removed = append(packageStatus.Removed, pkg)
+		}
+	}
+
+	return
+}
+
+// ListInstalledPackages returns the explicitly installed packages, with the
+// ones from the AUR prefixed as in the manifest
+func (p *Pacman) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	userPkgs, err := p.ListUserInstalledPkgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	foreignPkgs, err := p.ListForeignPkgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pkg := range userPkgs {
+		fullName := pkg
+		if lo.Contains(foreignPkgs, pkg) {
+			fullName = aurPrefix + pkg
+		}
+		packages = append(packages, shared.Package{Name: pkg, FullName: fullName})
+	}
+	return
+}
+
+func (p *Pacman) RemovePackages(ctx context.Context, allPkgs []string, pkgs []string) (packagesToRemove []string, userWarnings []string, err error) {
+	for _, entry := range pkgs {
+		var isSysPkg bool
+		isSysPkg, err = p.isSystemPackage(ctx, parsePackage(entry).Name)
+		if err != nil {
+			return
+		}
+
+		if isSysPkg {
+			userWarnings = append(userWarnings, fmt.Sprintf("'%s' is a system package and cannot be managed", entry))
+			continue
+		}
+		packagesToRemove = append(packagesToRemove, entry)
+	}
+
+	return
+}
+
+func (p *Pacman) RemoveDependencies(ctx context.Context, allDeps []string, depsToRemove []string) (depsUpdated []string, userWarnings []string, err error) {
+	return
+}
+
+func (p *Pacman) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	return
+}
+
+// SyncPackages installs the packages from the sync databases in one pacman
+// run, and the ones from the AUR in one run of the AUR helper. Packages are
+// removed with pacman wherever they came from. Updated packages are never
+// installed, since upgrading single packages is a partial upgrade: the ones
+// with a newer version are pending until the user runs 'pacman -Syu', and the
+// ones not matching their pin fail.
+func (p *Pacman) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	repoPkgs, aurPkgs := splitAur(p.filterSystemPackages(ctx, packageStatus.Missing))
+	failures = append(failures, shared.SyncTransaction(ctx, Name, repoPkgs, shared.PtermSpinnerInstall, p.InstallPkg)...)
+	if p.aurHelper == "" {
+		for _, pkg := range aurPkgs {
+			failures = append(failures, shared.SyncFailure{Manager: Name, Name: pkg.Name, Action: shared.PtermSpinnerInstall, Err: errNoAurHelper})
+		}
+	} else {
+		failures = append(failures, shared.SyncTransaction(ctx, Name, aurPkgs, shared.PtermSpinnerInstall, func(ctx context.Context, pkgs []shared.Package) error {
+			return p.InstallAurPkg(ctx, p.aurHelper, pkgs)
+		})...)
+	}
+
+	for _, pkg := range packageStatus.Updated {
+		failures = append(failures, shared.SyncFailure{Manager: Name, Name: pkg.Name, Action: shared.PtermSpinnerUpdate, Err: updateErr(pkg)})
+	}
+
+	failures = append(failures, shared.SyncTransaction(ctx, Name, p.filterSystemPackages(ctx, packageStatus.Removed), shared.PtermSpinnerRemove, p.RemovePkg)...)
+	return
+}
+
+// updateErr returns why the package is not updated
+func updateErr(pkg shared.Package) error {
+	if pkg.Pin != "" {
+		return fmt.Errorf("'%s' is pinned to %s, pacman can only install the version in the sync databases", pkg.FullName, pkg.Pin)
+	}
+	return fmt.Errorf("%w: %s is available, run 'pacman -Syu' to upgrade", shared.ErrSyncPending, pkg.LatestVersion)
+}
+
+func (p *Pacman) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	return
+}
+
+func (p *Pacman) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	repoPkgs, aurPkgs := splitAur(p.filterSystemPackages(ctx, packageStatus.Missing))
+	if len(repoPkgs) > 0 {
+		actions = append(actions, shared.CommandString("sudo", append([]string{"pacman"}, pkgArgs("install", repoPkgs)...)))
+	}
+	if len(aurPkgs) > 0 {
+		if p.aurHelper == "" {
+			return nil, fmt.Errorf("%s: %w", strings.Join(shared.FullNames(aurPkgs), ", "), errNoAurHelper)
+		}
+		actions = append(actions, shared.CommandString(p.aurHelper, pkgArgs("install", aurPkgs)))
+	}
+
+	if pkgs := p.filterSystemPackages(ctx, packageStatus.Removed); len(pkgs) > 0 {
+		actions = append(actions, shared.CommandString("sudo", append([]string{"pacman"}, pkgArgs("remove", pkgs)...)))
+	}
+	return
+}
+
+// parsePackage parses a manifest entry like 'ripgrep', 'aur:paru-bin' or
+// 'ripgrep@14.1.x'
+func parsePackage(entry string) shared.Package {
+	fullName, pin := shared.SplitPin(entry)
+	return shared.Package{
+		Name:     strings.TrimPrefix(fullName, aurPrefix),
+		FullName: fullName,
+		Pin:      pin,
+	}
+}
+
+func isAur(pkg shared.Package) bool {
+	return strings.HasPrefix(pkg.FullName, aurPrefix)
+}
+
+// splitAur splits the packages into the ones from the sync databases and the
+// ones from the AUR
+func splitAur(pkgs []shared.Package) (repoPkgs []shared.Package, aurPkgs []shared.Package) {
+	for _, pkg := range pkgs {
+		if isAur(pkg) {
+			aurPkgs = append(aurPkgs, pkg)
+		} else {
+			repoPkgs = append(repoPkgs, pkg)
+		}
+	}
+	return
+}
+
+// matchPacmanPin matches the pin against the installed version, with or
+// without the epoch and pkgrel, e.g. '1:14.1.0-1'
+func matchPacmanPin(pin string, version string) bool {
+	if _, v, found := strings.Cut(version, ":"); found {
+		version = v
+	}
+	v := version
+	if idx := strings.LastIndex(version, "-"); idx > 0 {
+		v = version[:idx]
+	}
+	return shared.MatchPin(pin, version) || shared.MatchPin(pin, v)
+}
+
+func (p *Pacman) filterSystemPackages(ctx context.Context, pkgs []shared.Package) []shared.Package {
+	return lo.Filter(pkgs, func(item shared.Package, _ int) bool {
+		isSysPkg, err := p.isSystemPackage(ctx, item.Name)
+		if err != nil || isSysPkg {
+			return false
+		}
+		return true
+	})
+}
+
+// isSystemPackage reports if the package is installed, but not explicitly,
+// i.e. it was pulled in as a dependency of another package
+func (p *Pacman) isSystemPackage(ctx context.Context, pkg string) (bool, error) {
+	allPkgs, _, err := p.ListInstalledPkgs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	userPkgs, err := p.ListUserInstalledPkgs(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if lo.Contains(allPkgs, pkg) && !lo.Contains(userPkgs, pkg) {
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,142 @@
+package pacman
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExecutor has ripgrep and paru-bin explicitly installed, and pcre2 as a
+// dependency of ripgrep. It records the packages it is asked to install and
+// remove.
+type fakeExecutor struct {
+	commandExecutor
+	installed [][]string
+	removed   [][]string
+}
+
+func (f *fakeExecutor) InstallPkg(ctx context.Context, pkgs []shared.Package) error {
+	f.installed = append(f.installed, shared.FullNames(pkgs))
+	return nil
+}
+
+func (f *fakeExecutor) InstallAurPkg(ctx context.Context, helper string, pkgs []shared.Package) error {
+	f.installed = append(f.installed, append([]string{helper}, shared.FullNames(pkgs)...))
+	return nil
+}
+
+func (f *fakeExecutor) RemovePkg(ctx context.Context, pkgs []shared.Package) error {
+	f.removed = append(f.removed, shared.FullNames(pkgs))
+	return nil
+}
+
+func (f *fakeExecutor) ListInstalledPkgs(ctx context.Context) ([]string, []string, error) {
+	return []string{"fd", "paru-bin", "pcre2", "ripgrep", "zsh"}, []string{"9.0.0-1", "2.0.3-1", "10.42-2", "1:14.0.3-1", "5.9-5"}, nil
+}
+
+func (f *fakeExecutor) ListUserInstalledPkgs(ctx context.Context) ([]string, error) {
+	return []string{"fd", "paru-bin", "ripgrep", "zsh"}, nil
+}
+
+func (f *fakeExecutor) ListForeignPkgs(ctx context.Context) ([]string, error) {
+	return []string{"paru-bin"}, nil
+}
+
+func (f *fakeExecutor) ListUpgradablePkgs(ctx context.Context, helper string) (map[string]string, error) {
+	return parseUpgradable("ripgrep 1:14.0.3-1 -> 1:14.1.0-1\nzsh 5.9-5 -> 5.9-6 [ignored]\n"), nil
+}
+
+func TestListPackages(t *testing.T) {
+	p := New()
+	p.CommandExecutorFace = &fakeExecutor{}
+	ctx := context.Background()
+
+	pkgStatus, err := p.ListPackages(ctx, []string{
+		"ripgrep",
+		"zsh",
+		"aur:paru-bin",
+		"neovim",
+	}, []string{"aur:paru-bin", "pcre2", "htop"})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"zsh", "aur:paru-bin"}, shared.FullNames(pkgStatus.Synced), "ignored upgrades should be left out")
+	assert.Equal(t, []string{"ripgrep"}, shared.FullNames(pkgStatus.Updated))
+	assert.Equal(t, "1:14.1.0-1", pkgStatus.Updated[0].LatestVersion)
+	assert.Equal(t, []string{"pcre2"}, shared.FullNames(pkgStatus.Removed), "htop is not installed")
+
+	installed, err := p.ListInstalledPackages(ctx)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"fd", "aur:paru-bin", "ripgrep", "zsh"}, shared.FullNames(installed), "foreign packages should be adopted as AUR packages")
+
+	added, warnings, err := p.AddPackages(ctx, []string{"pcre2", "aur:yay-bin", "neovim@0.9.x"})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"aur:yay-bin"}, added)
+	assert.Len(t, warnings, 3, "system packages and pins should be rejected, and AUR packages need a helper")
+}
+
+func TestSyncAurPackages(t *testing.T) {
+	pterm.DisableOutput()
+	t.Cleanup(pterm.EnableOutput)
+	assumeYes := true
+	config.AssumeYes = &assumeYes
+
+	executor := &fakeExecutor{}
+	p := New()
+	p.CommandExecutorFace = executor
+	ctx := context.Background()
+
+	pkgStatus, err := p.ListPackages(ctx, []string{"neovim", "aur:yay-bin"}, []string{"pcre2", "aur:paru-bin"})
+	assert.Nil(t, err, "should be no error")
+
+	failures, _, err := p.SyncPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Len(t, failures, 1)
+	assert.ErrorIs(t, failures[0].Err, errNoAurHelper)
+	assert.Equal(t, [][]string{{"neovim"}}, executor.installed)
+	assert.Equal(t, [][]string{{"aur:paru-bin"}}, executor.removed, "AUR packages are removed with pacman, and pcre2 is a dependency")
+
+	_, err = p.PlanPackages(ctx, pkgStatus)
+	assert.ErrorIs(t, err, errNoAurHelper)
+
+	executor.installed = nil
+	p.aurHelper = "paru"
+	failures, _, err = p.SyncPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Empty(t, failures)
+	assert.Equal(t, [][]string{{"neovim"}, {"paru", "aur:yay-bin"}}, executor.installed)
+
+	actions, err := p.PlanPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{
+		"sudo pacman -S --needed --noconfirm neovim",
+		"paru -S --needed --noconfirm yay-bin",
+		"sudo pacman -Rs --noconfirm paru-bin",
+	}, actions)
+}
+
+func TestSyncUpdatedPackages(t *testing.T) {
+	executor := &fakeExecutor{}
+	p := New()
+	p.CommandExecutorFace = executor
+	ctx := context.Background()
+
+	pkgStatus, err := p.ListPackages(ctx, []string{"ripgrep", "fd@8.x", "neovim"}, []string{})
+	assert.Nil(t, err, "should be no error")
+
+	failures, _, err := p.SyncPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, [][]string{{"neovim"}}, executor.installed, "updates should not be installed one by one")
+	assert.Len(t, failures, 2)
+	assert.ErrorIs(t, failures[0].Err, shared.ErrSyncPending, "ripgrep waits for a system upgrade")
+	assert.Equal(t, "fd", failures[1].Name)
+	assert.NotErrorIs(t, failures[1].Err, shared.ErrSyncPending, "fd can't be installed at its pin")
+}
+
+func TestPacmanPins(t *testing.T) {
+	assert.True(t, matchPacmanPin("14.1.x", "1:14.1.0-1"))
+	assert.True(t, matchPacmanPin("14.1.0-1", "14.1.0-1"))
+	assert.False(t, matchPacmanPin("14.0.x", "14.1.0-1"))
+}
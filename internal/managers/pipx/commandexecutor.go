@@ -0,0 +1,204 @@
+package pipx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/tidwall/gjson"
+)
+
+type CommandExecutorFace interface {
+	ListInstalled(ctx context.Context) (venvs []venv, err error)
+	LatestVersion(ctx context.Context, name string) (version string, err error)
+	Install(ctx context.Context, pkg shared.Package) error
+	Upgrade(ctx context.Context, pkg shared.Package) error
+	Reinstall(ctx context.Context, pkg shared.Package) error
+	Uninstall(ctx context.Context, venvName string) error
+	Inject(ctx context.Context, venvName string, pkgs []string) error
+	Uninject(ctx context.Context, venvName string, pkg string) error
+}
+
+type commandExecutor struct {
+}
+
+// venv is an app installed by pipx, with the packages injected into it
+type venv struct {
+	Name         string
+	Package      string
+	PackageOrUrl string
+	Version      string
+	AppPaths     []string
+	Injected     map[string]string
+}
+
+// pipxList is the output of 'pipx list --json'
+type pipxList struct {
+	Venvs map[string]struct {
+		Metadata struct {
+			MainPackage struct {
+				Package        string `json:"package"`
+				PackageOrUrl   string `json:"package_or_url"`
+				PackageVersion string `json:"package_version"`
+				AppPaths       []struct {
+					Path string `json:"__Path__"`
+				} `json:"app_paths"`
+			} `json:"main_package"`
+			InjectedPackages map[string]struct {
+				PackageVersion string `json:"package_version"`
+			} `json:"injected_packages"`
+		} `json:"metadata"`
+	} `json:"venvs"`
+}
+
+func parsePipxList(data []byte) (venvs []venv, err error) {
+	list := pipxList{}
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	for name, v := range list.Venvs {
+		main := v.Metadata.MainPackage
+		iVenv := venv{
+			Name:         name,
+			Package:      main.Package,
+			PackageOrUrl: main.PackageOrUrl,
+			Version:      main.PackageVersion,
+			Injected:     map[string]string{},
+		}
+		for _, appPath := range main.AppPaths {
+			iVenv.AppPaths = append(iVenv.AppPaths, appPath.Path)
+		}
+		for pkg, injected := range v.Metadata.InjectedPackages {
+			iVenv.Injected[normalize(pkg)] = injected.PackageVersion
+		}
+		venvs = append(venvs, iVenv)
+	}
+
+	sort.Slice(venvs, func(i, j int) bool {
+		return venvs[i].Name < venvs[j].Name
+	})
+	return
+}
+
+var rSeparators = regexp.MustCompile(`[-_.]+`)
+
+// normalize returns the normalized name of a Python package, so that e.g.
+// 'Poetry_Plugin.Export' and 'poetry-plugin-export' are the same package
+func normalize(name string) string {
+	return rSeparators.ReplaceAllString(strings.ToLower(name), "-")
+}
+
+// isUrl reports if the manifest entry is installed from an url, e.g.
+// 'git+https://github.com/user/tool', instead of from PyPI
+func isUrl(entry string) bool {
+	return strings.Contains(entry, "://")
+}
+
+// installSpec returns the package as pip takes it. A pin is a version on PyPI,
+// and a tag or commit of an url.
+func installSpec(pkg shared.Package) string {
+	if pkg.Pin == "" {
+		return pkg.FullName
+	}
+	if isUrl(pkg.FullName) {
+		return pkg.FullName + "@" + pkg.Pin
+	}
+	return pkg.FullName + "==" + shared.PinGlob(pkg.Pin)
+}
+
+func installArgs(pkg shared.Package) []string {
+	return []string{"install", installSpec(pkg)}
+}
+
+// upgradeArgs upgrades unpinned packages to the latest version, and reinstalls
+// pinned packages with the version of the pin
+func upgradeArgs(pkg shared.Package) []string {
+	if pkg.Pin == "" {
+		return []string{"upgrade", pkg.Name}
+	}
+	return reinstallArgs(pkg)
+}
+
+// reinstallArgs installs the package over the installed one, e.g. with other
+// extras, which 'pipx upgrade' keeps as they were installed
+func reinstallArgs(pkg shared.Package) []string {
+	return []string{"install", "--force", installSpec(pkg)}
+}
+
+func (c *commandExecutor) ListInstalled(ctx context.Context) (venvs []venv, err error) {
+	out, err := shared.Command(ctx, "pipx", []string{"list", "--json"}, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	return parsePipxList([]byte(out))
+}
+
+// LatestVersion returns the latest version of the package on PyPI
+func (c *commandExecutor) LatestVersion(ctx context.Context, name string) (version string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("https://pypi.org/pypi/%s/json", name), nil)
+	if err != nil {
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not find %s on PyPI: %s", name, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	version = gjson.GetBytes(body, "info.version").Str
+	if version == "" {
+		return "", fmt.Errorf("could not find version for %s", name)
+	}
+	return
+}
+
+func (c *commandExecutor) Install(ctx context.Context, pkg shared.Package) error {
+	_, err := shared.Command(ctx, "pipx", installArgs(pkg), false, nil)
+	return err
+}
+
+func (c *commandExecutor) Upgrade(ctx context.Context, pkg shared.Package) error {
+	_, err := shared.Command(ctx, "pipx", upgradeArgs(pkg), false, nil)
+	return err
+}
+
+func (c *commandExecutor) Reinstall(ctx context.Context, pkg shared.Package) error {
+	_, err := shared.Command(ctx, "pipx", reinstallArgs(pkg), false, nil)
+	return err
+}
+
+func (c *commandExecutor) Uninstall(ctx context.Context, venvName string) error {
+	_, err := shared.Command(ctx, "pipx", []string{"uninstall", venvName}, false, nil)
+	return err
+}
+
+func (c *commandExecutor) Inject(ctx context.Context, venvName string, pkgs []string) error {
+	_, err := shared.Command(ctx, "pipx", append([]string{"inject", venvName}, pkgs...), false, nil)
+	return err
+}
+
+func (c *commandExecutor) Uninject(ctx context.Context, venvName string, pkg string) error {
+	_, err := shared.Command(ctx, "pipx", []string{"uninject", venvName, pkg}, false, nil)
+	return err
+}
+
+// nameFromUrl returns the name of a package installed from an url before it is
+// known from pipx, e.g. 'tool' for 'git+https://github.com/user/tool.git'
+func nameFromUrl(u string) string {
+	return strings.TrimSuffix(path.Base(strings.TrimSuffix(u, "/")), ".git")
+}
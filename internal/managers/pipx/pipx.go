@@ -0,0 +1,464 @@
+package pipx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/lucas-ingemar/packtrak/internal/status"
+	"github.com/samber/lo"
+)
+
+func New() *Pipx {
+	return &Pipx{
+		CommandExecutorFace: &commandExecutor{},
+		injectLater:         map[string][]string{},
+	}
+}
+
+const Name shared.ManagerName = "pipx"
+
+// Pipx installs Python apps in their own virtual environments. The
+// dependencies of the manager are packages injected into an app, written as
+// 'app:package'.
+type Pipx struct {
+	CommandExecutorFace
+
+	// injectLater holds the packages to inject into apps that are not installed
+	// yet, which are injected right after the app is installed. They are
+	// reported as pending until then.
+	injectLater map[string][]string
+}
+
+func (p *Pipx) Name() shared.ManagerName {
+	return Name
+}
+
+func (p *Pipx) Icon() string {
+	return ""
+}
+
+func (p *Pipx) ShortDesc() string {
+	return "Install Python apps in isolated environments"
+}
+
+func (p *Pipx) LongDesc() string {
+	return "Install Python apps in isolated environments with pipx. Packages injected into an app are its dependencies, written as app:package"
+}
+
+func (p *Pipx) NeedsSudo() []shared.CommandName {
+	return []shared.CommandName{}
+}
+
+func (p *Pipx) SupportsPins() bool {
+	return true
+}
+
+func (p *Pipx) InitCheckCmd() error {
+	_, err := exec.LookPath("pipx")
+	if err != nil {
+		return errors.New("'pipx' command not found on the computer")
+	}
+	return nil
+}
+
+func (p *Pipx) InitCheckConfig() error {
+	return nil
+}
+
+func (p *Pipx) InitConfig() {
+}
+
+func (p *Pipx) GetPackageNames(ctx context.Context, packages []string) []string {
+	pkgNames := []string{}
+	for _, pkg := range packages {
+		pkgNames = append(pkgNames, nameFromEntry(shared.Unpin(pkg)))
+	}
+	return pkgNames
+}
+
+func (p *Pipx) GetDependencyNames(ctx context.Context, deps []string) []string {
+	return deps
+}
+
+func (p *Pipx) AddPackages(ctx context.Context, pkgsToAdd []string) (packagesUpdated []string, userWarnings []string, err error) {
+	return pkgsToAdd, nil, nil
+}
+
+func (p *Pipx) AddDependencies(ctx context.Context, depsToAdd []string) (depsUpdated []string, userWarnings []string, err error) {
+	for _, dep := range depsToAdd {
+		if _, _, ok := splitDependency(dep); !ok {
+			return nil, nil, fmt.Errorf("'%s' is not an injected package, write it as app:package", dep)
+		}
+	}
+	return depsToAdd, nil, nil
+}
+
+func (p *Pipx) InstallValidArgs(ctx context.Context, toComplete string, dependencies bool) ([]string, error) {
+	return []string{}, nil
+}
+
+func (p *Pipx) ListDependencies(ctx context.Context, deps []string, stateDeps []string) (depStatus status.DependenciesStatus, err error) {
+	installed, err := p.ListInstalled(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, dep := range deps {
+		app, pkg, ok := splitDependency(dep)
+		if !ok {
+			return depStatus, fmt.Errorf("'%s' is not an injected package, write it as app:package", dep)
+		}
+		iVenv, found := findVenv(installed, app)
+		if _, injected := iVenv.Injected[normalize(pkg)]; found && injected {
+			depStatus.Synced = append(depStatus.Synced, shared.Dependency{Name: pkg, FullName: dep})
+		} else {
+			depStatus.Missing = append(depStatus.Missing, shared.Dependency{Name: pkg, FullName: dep})
+		}
+	}
+
+	for _, dep := range stateDeps {
+		if _, pkg, ok := splitDependency(dep); ok && !lo.Contains(deps, dep) {
+			depStatus.Removed = append(depStatus.Removed, shared.Dependency{Name: pkg, FullName: dep})
+		}
+	}
+	return
+}
+
+func (p *Pipx) ListPackages(ctx context.Context, packages []string, statePkgs []string) (packageStatus status.PackageStatus, err error) {
+	installed, err := p.ListInstalled(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, pkg := range packages {
+		pkgFullName, pin := shared.SplitPin(pkg)
+		iVenv, found := lo.Find(installed, func(v venv) bool { return v.matches(pkgFullName) })
+		if !found {
+			packageStatus.Missing = append(packageStatus.Missing, shared.Package{
+				Name:     nameFromEntry(pkgFullName),
+				FullName: pkgFullName,
+				Pin:      pin,
+			})
+			continue
+		}
+
+		iPkg := shared.Package{
+			Name:     iVenv.Name,
+			FullName: pkgFullName,
+			Version:  iVenv.Version,
+			Pin:      pin,
+		}
+
+		if isUrl(pkgFullName) {
+			// Packages from an url can't be compared to a newer version, only to
+			// the ref they are pinned to
+			if pin == "" || strings.HasSuffix(iVenv.PackageOrUrl, "@"+pin) {
+				packageStatus.Synced = append(packageStatus.Synced, iPkg)
+			} else {
+				iPkg.LatestVersion = pin
+				packageStatus.Updated = append(packageStatus.Updated, iPkg)
+			}
+			continue
+		}
+
+		if pin != "" {
+			if shared.MatchPin(pin, iPkg.Version) && iVenv.sameExtras(pkgFullName) {
+				packageStatus.Synced = append(packageStatus.Synced, iPkg)
+			} else {
+				iPkg.LatestVersion = pin
+				packageStatus.Updated = append(packageStatus.Updated, iPkg)
+			}
+			continue
+		}
+
+		latest, err := p.LatestVersion(ctx, iVenv.Package)
+		if err != nil {
+			return packageStatus, err
+		}
+		if iPkg.Version == latest && iVenv.sameExtras(pkgFullName) {
+			packageStatus.Synced = append(packageStatus.Synced, iPkg)
+		} else {
+			iPkg.LatestVersion = latest
+			packageStatus.Updated = append(packageStatus.Updated, iPkg)
+		}
+	}
+
+	unpinned := shared.UnpinAll(packages)
+	for _, pkg := range statePkgs {
+		if lo.ContainsBy(unpinned, func(entry string) bool { return sameEntry(entry, pkg) }) {
+			continue
+		}
+		name := nameFromEntry(pkg)
+		if iVenv, found := lo.Find(installed, func(v venv) bool { return v.matches(pkg) }); found {
+			name = iVenv.Name
+		}
+		packageStatus.Removed = append(packageStatus.Removed, shared.Package{
+			Name:     name,
+			FullName: pkg,
+		})
+	}
+
+	return
+}
+
+func (p *Pipx) ListInstalledPackages(ctx context.Context) (packages []shared.Package, err error) {
+	installed, err := p.ListInstalled(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(installed, func(v venv, _ int) shared.Package {
+		fullName := v.Package
+		if isUrl(v.PackageOrUrl) {
+			fullName = shared.Unpin(v.PackageOrUrl)
+		}
+		return shared.Package{
+			Name:     v.Name,
+			FullName: fullName,
+			Version:  v.Version,
+		}
+	}), nil
+}
+
+func (p *Pipx) RemovePackages(ctx context.Context, allPkgs []string, pkgsToRemove []string) (packagesToRemove []string, userWarnings []string, err error) {
+	for _, pR := range pkgsToRemove {
+		for _, pA := range shared.UnpinAll(allPkgs) {
+			if nameFromEntry(pA) == pR {
+				packagesToRemove = append(packagesToRemove, pA)
+			}
+		}
+	}
+	return
+}
+
+func (p *Pipx) RemoveDependencies(ctx context.Context, allDeps []string, depsToRemove []string) (depsUpdated []string, userWarnings []string, err error) {
+	return lo.Intersect(allDeps, depsToRemove), nil, nil
+}
+
+func (p *Pipx) SyncDependencies(ctx context.Context, depStatus status.DependenciesStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	if len(depStatus.Missing) == 0 && len(depStatus.Removed) == 0 {
+		return
+	}
+
+	installed, err := p.ListInstalled(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, dep := range depStatus.Missing {
+		app, pkg, _ := splitDependency(dep.FullName)
+		iVenv, found := findVenv(installed, app)
+		if !found {
+			p.injectLater[normalize(app)] = append(p.injectLater[normalize(app)], pkg)
+			failures = append(failures, shared.SyncFailure{
+				Manager: Name,
+				Name:    dep.Name,
+				Action:  shared.PtermSpinnerInstall,
+				Err:     fmt.Errorf("%w: injected once '%s' is installed", shared.ErrSyncPending, app),
+			})
+			continue
+		}
+		err = shared.PtermSpinner(shared.PtermSpinnerInstall, dep.FullName, func() error {
+			return p.Inject(ctx, iVenv.Name, []string{pkg})
+		})
+		if err != nil {
+			failures = append(failures, shared.SyncFailure{Manager: Name, Name: dep.Name, Action: shared.PtermSpinnerInstall, Err: err})
+			err = nil
+		}
+	}
+
+	for _, dep := range depStatus.Removed {
+		app, pkg, _ := splitDependency(dep.FullName)
+		iVenv, found := findVenv(installed, app)
+		if _, injected := iVenv.Injected[normalize(pkg)]; !found || !injected {
+			continue
+		}
+		err = shared.PtermSpinner(shared.PtermSpinnerRemove, dep.FullName, func() error {
+			return p.Uninject(ctx, iVenv.Name, pkg)
+		})
+		if err != nil {
+			failures = append(failures, shared.SyncFailure{Manager: Name, Name: dep.Name, Action: shared.PtermSpinnerRemove, Err: err})
+			err = nil
+		}
+	}
+	return
+}
+
+func (p *Pipx) SyncPackages(ctx context.Context, packageStatus status.PackageStatus) (failures []shared.SyncFailure, userWarnings []string, err error) {
+	failures = shared.SyncEach(ctx, Name, packageStatus.Missing, packageStatus.Updated, packageStatus.Removed, p.SyncPackage)
+	return
+}
+
+// CanSyncConcurrently is false since every install may upgrade the shared
+// libraries of pipx, which is not safe to do concurrently
+func (p *Pipx) CanSyncConcurrently() bool {
+	return false
+}
+
+// SyncPackage injects the packages that could not be injected before the app
+// was installed right after installing it
+func (p *Pipx) SyncPackage(ctx context.Context, pkg shared.Package, action shared.PtermSpinnerStatus) error {
+	return shared.RunWithHooks(ctx, Name, pkg, action, p.hookPath(ctx, pkg), func() error {
+		switch action {
+		case shared.PtermSpinnerInstall:
+			if err := p.Install(ctx, pkg); err != nil {
+				return err
+			}
+			if pkgs := p.injectLater[normalize(pkg.Name)]; len(pkgs) > 0 {
+				return p.Inject(ctx, pkg.Name, pkgs)
+			}
+			return nil
+		case shared.PtermSpinnerUpdate:
+			return p.update(ctx, pkg)
+		default:
+			return p.Uninstall(ctx, pkg.Name)
+		}
+	})
+}
+
+// update upgrades the package. An unpinned package whose extras changed is
+// reinstalled first, and only upgraded if there is also a newer version.
+func (p *Pipx) update(ctx context.Context, pkg shared.Package) error {
+	changed, err := p.extrasChanged(ctx, pkg)
+	if err != nil {
+		return err
+	}
+	if changed && pkg.Pin == "" {
+		if err := p.Reinstall(ctx, pkg); err != nil {
+			return err
+		}
+		if pkg.Version == pkg.LatestVersion {
+			return nil
+		}
+	}
+	return p.Upgrade(ctx, pkg)
+}
+
+// extrasChanged reports if the package is installed with other extras than
+// the ones in its manifest entry
+func (p *Pipx) extrasChanged(ctx context.Context, pkg shared.Package) (bool, error) {
+	installed, err := p.ListInstalled(ctx)
+	if err != nil {
+		return false, err
+	}
+	iVenv, found := lo.Find(installed, func(v venv) bool { return v.matches(pkg.FullName) })
+	return found && !iVenv.sameExtras(pkg.FullName), nil
+}
+
+// hookPath returns the first app of the package
+func (p *Pipx) hookPath(ctx context.Context, pkg shared.Package) func() string {
+	return func() string {
+		installed, err := p.ListInstalled(ctx)
+		if err != nil {
+			return ""
+		}
+		iVenv, found := findVenv(installed, pkg.Name)
+		if !found || len(iVenv.AppPaths) == 0 {
+			return ""
+		}
+		return iVenv.AppPaths[0]
+	}
+}
+
+func (p *Pipx) PlanDependencies(ctx context.Context, depStatus status.DependenciesStatus) (actions []string, err error) {
+	for _, dep := range depStatus.Missing {
+		app, pkg, _ := splitDependency(dep.FullName)
+		actions = append(actions, shared.CommandString("pipx", []string{"inject", app, pkg}))
+	}
+	for _, dep := range depStatus.Removed {
+		app, pkg, _ := splitDependency(dep.FullName)
+		actions = append(actions, shared.CommandString("pipx", []string{"uninject", app, pkg}))
+	}
+	return
+}
+
+func (p *Pipx) PlanPackages(ctx context.Context, packageStatus status.PackageStatus) (actions []string, err error) {
+	for _, pkg := range packageStatus.Missing {
+		actions = append(actions, shared.CommandString("pipx", installArgs(pkg)))
+	}
+	for _, pkg := range packageStatus.Updated {
+		changed, err := p.extrasChanged(ctx, pkg)
+		if err != nil {
+			return nil, err
+		}
+		if changed && pkg.Pin == "" {
+			actions = append(actions, shared.CommandString("pipx", reinstallArgs(pkg)))
+			if pkg.Version == pkg.LatestVersion {
+				continue
+			}
+		}
+		actions = append(actions, shared.CommandString("pipx", upgradeArgs(pkg)))
+	}
+	for _, pkg := range packageStatus.Removed {
+		actions = append(actions, shared.CommandString("pipx", []string{"uninstall", pkg.Name}))
+	}
+	return
+}
+
+// splitDependency splits an injected package into the app and the package
+func splitDependency(dep string) (app string, pkg string, ok bool) {
+	app, pkg, ok = strings.Cut(dep, ":")
+	return app, pkg, ok && app != "" && pkg != ""
+}
+
+// nameFromEntry returns the name of the package in a manifest entry, without
+// extras like 'black[d]'
+func nameFromEntry(entry string) string {
+	if isUrl(entry) {
+		return nameFromUrl(entry)
+	}
+	name, _, _ := strings.Cut(entry, "[")
+	return name
+}
+
+// sameEntry reports if both manifest entries are the same package, regardless
+// of extras
+func sameEntry(a string, b string) bool {
+	if isUrl(a) || isUrl(b) {
+		return a == b
+	}
+	return normalize(nameFromEntry(a)) == normalize(nameFromEntry(b))
+}
+
+// extrasFromEntry returns the sorted extras of a manifest entry, or of the
+// package_or_url of pipx, e.g. [d jupyter] for 'black[jupyter,d]==24.1.0'
+func extrasFromEntry(entry string) []string {
+	if isUrl(entry) {
+		return nil
+	}
+	_, extras, found := strings.Cut(entry, "[")
+	if !found {
+		return nil
+	}
+	extras, _, _ = strings.Cut(extras, "]")
+	names := lo.Compact(lo.Map(strings.Split(extras, ","), func(extra string, _ int) string {
+		return normalize(strings.TrimSpace(extra))
+	}))
+	slices.Sort(names)
+	return names
+}
+
+// sameExtras reports if the venv is installed with the extras of the manifest
+// entry
+func (v venv) sameExtras(entry string) bool {
+	return slices.Equal(extrasFromEntry(v.PackageOrUrl), extrasFromEntry(entry))
+}
+
+// matches reports if the venv is the package in the manifest entry
+func (v venv) matches(entry string) bool {
+	if isUrl(entry) {
+		return v.PackageOrUrl == entry || strings.HasPrefix(v.PackageOrUrl, entry+"@")
+	}
+	name := normalize(nameFromEntry(entry))
+	return normalize(v.Package) == name || normalize(v.Name) == name
+}
+
+func findVenv(venvs []venv, name string) (venv, bool) {
+	return lo.Find(venvs, func(v venv) bool {
+		return normalize(v.Name) == normalize(name)
+	})
+}
@@ -0,0 +1,191 @@
+package pipx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/pterm/pterm"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPipxList = `{
+  "pipx_spec_version": "0.1",
+  "venvs": {
+    "black": {"metadata": {
+      "main_package": {"package": "black", "package_or_url": "black", "package_version": "24.1.0",
+        "app_paths": [{"__type__": "Path", "__Path__": "/home/user/.local/bin/black"}]},
+      "injected_packages": {}
+    }},
+    "poetry": {"metadata": {
+      "main_package": {"package": "poetry", "package_or_url": "poetry==1.7.0", "package_version": "1.7.0", "app_paths": []},
+      "injected_packages": {"poetry-plugin-export": {"package_version": "1.6.0"}}
+    }},
+    "tool": {"metadata": {
+      "main_package": {"package": "tool", "package_or_url": "git+https://github.com/user/tool@v1.0", "package_version": "1.0", "app_paths": []},
+      "injected_packages": {}
+    }}
+  }
+}`
+
+// fakeExecutor lists the apps above and records the pipx commands it is asked
+// to run
+type fakeExecutor struct {
+	commandExecutor
+	commands []string
+}
+
+func (f *fakeExecutor) ListInstalled(ctx context.Context) ([]venv, error) {
+	return parsePipxList([]byte(testPipxList))
+}
+
+func (f *fakeExecutor) LatestVersion(ctx context.Context, name string) (string, error) {
+	return map[string]string{"black": "24.2.0", "poetry": "1.7.0"}[name], nil
+}
+
+func (f *fakeExecutor) Install(ctx context.Context, pkg shared.Package) error {
+	f.commands = append(f.commands, strings.Join(installArgs(pkg), " "))
+	return nil
+}
+
+func (f *fakeExecutor) Upgrade(ctx context.Context, pkg shared.Package) error {
+	f.commands = append(f.commands, strings.Join(upgradeArgs(pkg), " "))
+	return nil
+}
+
+func (f *fakeExecutor) Reinstall(ctx context.Context, pkg shared.Package) error {
+	f.commands = append(f.commands, strings.Join(reinstallArgs(pkg), " "))
+	return nil
+}
+
+func (f *fakeExecutor) Uninstall(ctx context.Context, venvName string) error {
+	f.commands = append(f.commands, "uninstall "+venvName)
+	return nil
+}
+
+func (f *fakeExecutor) Inject(ctx context.Context, venvName string, pkgs []string) error {
+	f.commands = append(f.commands, "inject "+venvName+" "+strings.Join(pkgs, " "))
+	return nil
+}
+
+func (f *fakeExecutor) Uninject(ctx context.Context, venvName string, pkg string) error {
+	f.commands = append(f.commands, "uninject "+venvName+" "+pkg)
+	return nil
+}
+
+func TestSyncPackages(t *testing.T) {
+	pterm.DisableOutput()
+	t.Cleanup(pterm.EnableOutput)
+
+	executor := &fakeExecutor{}
+	p := New()
+	p.CommandExecutorFace = executor
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "out")
+
+	pkgStatus, err := p.ListPackages(ctx, []string{
+		"black",
+		"Poetry@1.7.x",
+		"git+https://github.com/user/tool@v1.1",
+		"ansible",
+	}, []string{"black[d]", "poetry", "git+https://github.com/user/old-tool", "httpie"})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"Poetry"}, shared.FullNames(pkgStatus.Synced))
+	assert.Equal(t, []string{"black", "git+https://github.com/user/tool"}, shared.FullNames(pkgStatus.Updated), "an url should only be reinstalled when its pin changes")
+	assert.Equal(t, "24.2.0", pkgStatus.Updated[0].LatestVersion)
+	assert.Equal(t, []string{"ansible"}, shared.FullNames(pkgStatus.Missing))
+	assert.Equal(t, []string{"git+https://github.com/user/old-tool", "httpie"}, shared.FullNames(pkgStatus.Removed), "black only lost its extras")
+	assert.Equal(t, "old-tool", pkgStatus.Removed[0].Name, "an url that is not installed is removed by its repository name")
+
+	pkgStatus.Updated[0].Hooks = shared.Hooks{PostUpdate: "echo $PACKTRAK_PACKAGE $PACKTRAK_VERSION $PACKTRAK_PATH > " + out}
+	failures, _, err := p.SyncPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Empty(t, failures)
+	assert.Equal(t, []string{
+		"install ansible",
+		"upgrade black",
+		"install --force git+https://github.com/user/tool@v1.1",
+		"uninstall old-tool",
+		"uninstall httpie",
+	}, executor.commands)
+
+	b, _ := os.ReadFile(out)
+	assert.Equal(t, "black 24.2.0 /home/user/.local/bin/black\n", string(b), "hooks should get the first app of the package")
+}
+
+func TestChangedExtras(t *testing.T) {
+	pterm.DisableOutput()
+	t.Cleanup(pterm.EnableOutput)
+
+	executor := &fakeExecutor{}
+	p := New()
+	p.CommandExecutorFace = executor
+	ctx := context.Background()
+
+	assert.Equal(t, []string{"d", "jupyter"}, extrasFromEntry("black[jupyter, d]==24.1.0"))
+	assert.Nil(t, extrasFromEntry("black==24.1.0"))
+
+	pkgStatus, err := p.ListPackages(ctx, []string{"black[d]", "poetry[plugin]"}, []string{"black", "poetry"})
+	assert.Nil(t, err, "should be no error")
+	assert.Empty(t, pkgStatus.Synced)
+	assert.Equal(t, []string{"black[d]", "poetry[plugin]"}, shared.FullNames(pkgStatus.Updated), "the extras of both changed")
+	assert.Equal(t, "1.7.0", pkgStatus.Updated[1].LatestVersion, "poetry is already the latest version")
+	assert.Empty(t, pkgStatus.Removed)
+
+	actions, err := p.PlanPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Len(t, actions, 3)
+
+	failures, _, err := p.SyncPackages(ctx, pkgStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Empty(t, failures)
+	assert.Equal(t, []string{
+		"install --force black[d]",
+		"upgrade black",
+		"install --force poetry[plugin]",
+	}, executor.commands, "only black has a newer version to upgrade to")
+}
+
+func TestInjectedPackages(t *testing.T) {
+	pterm.DisableOutput()
+	t.Cleanup(pterm.EnableOutput)
+
+	executor := &fakeExecutor{}
+	p := New()
+	p.CommandExecutorFace = executor
+	ctx := context.Background()
+
+	depStatus, err := p.ListDependencies(ctx, []string{
+		"poetry:Poetry_Plugin_Export",
+		"black:tokenize-rt",
+		"ansible:ansible-lint",
+	}, []string{"poetry:poetry-plugin-up", "poetry:Poetry_Plugin_Export"})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"poetry:Poetry_Plugin_Export"}, fullNames(depStatus.Synced))
+	assert.Equal(t, []string{"black:tokenize-rt", "ansible:ansible-lint"}, fullNames(depStatus.Missing))
+	assert.Equal(t, []string{"poetry:poetry-plugin-up"}, fullNames(depStatus.Removed))
+
+	failures, _, err := p.SyncDependencies(ctx, depStatus)
+	assert.Nil(t, err, "should be no error")
+	assert.Len(t, failures, 1)
+	assert.Equal(t, "ansible-lint", failures[0].Name)
+	assert.ErrorIs(t, failures[0].Err, shared.ErrSyncPending, "ansible-lint is injected once ansible is installed")
+	assert.Equal(t, []string{"inject black tokenize-rt"}, executor.commands, "ansible is not installed yet, and poetry-plugin-up is not injected")
+
+	err = p.SyncPackage(ctx, shared.Package{Name: "ansible", FullName: "ansible"}, shared.PtermSpinnerInstall)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"inject black tokenize-rt", "install ansible", "inject ansible ansible-lint"}, executor.commands)
+
+	_, _, err = p.AddDependencies(ctx, []string{"ansible-lint"})
+	assert.NotNil(t, err, "a dependency without an app should fail")
+}
+
+func fullNames(deps []shared.Dependency) (names []string) {
+	for _, dep := range deps {
+		names = append(names, dep.FullName)
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Environment is the part of the system the conditionals are matched against
+type Environment interface {
+	Hostname() (string, error)
+	OSRelease() (map[string]string, error)
+	Arch() string
+	LookupEnv(key string) (string, bool)
+	LookPath(file string) (string, error)
+	Stat(name string) (os.FileInfo, error)
+}
+
+const osReleaseFile = "/etc/os-release"
+
+// env is replaced in tests to match against a fake system
+var env Environment = systemEnvironment{}
+
+type systemEnvironment struct{}
+
+func (systemEnvironment) Hostname() (string, error) {
+	return os.Hostname()
+}
+
+// OSRelease returns the fields of /etc/os-release, or nothing if the file does
+// not exist
+func (systemEnvironment) OSRelease() (map[string]string, error) {
+	b, err := os.ReadFile(osReleaseFile)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseOSRelease(b), nil
+}
+
+func (systemEnvironment) Arch() string {
+	return runtime.GOARCH
+}
+
+func (systemEnvironment) LookupEnv(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+func (systemEnvironment) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+func (systemEnvironment) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func parseOSRelease(b []byte) map[string]string {
+	release := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		release[key] = strings.Trim(value, `"'`)
+	}
+	return release
+}
@@ -0,0 +1,318 @@
+package manifest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+)
+
+// The 'when' field of a conditional holds a boolean expression over the
+// condition types, e.g:
+//
+//	group == "dev" && !host("laptop01")
+//	os in ["fedora", "rhel"] || env("CI")
+//
+// Every comparison is matched like a conditional of that type and value.
+// Supported are ==, !=, in [...], type("value"), !, &&, || and parentheses.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "!", "(", ")", "[", "]", ","}
+
+func tokenize(expr string) ([]token, error) {
+	tokens := []token{}
+	for i := 0; i < len(expr); {
+		r := rune(expr[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			value, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, pos: i})
+			i = end + 1
+		case r == '_' || unicode.IsLetter(r):
+			end := i
+			for end < len(expr) && (expr[end] == '_' || unicode.IsLetter(rune(expr[end])) || unicode.IsDigit(rune(expr[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, value: expr[i:end], pos: i})
+			i = end
+		default:
+			op, found := lo.Find(operators, func(op string) bool {
+				return strings.HasPrefix(expr[i:], op)
+			})
+			if !found {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", r, i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, value: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+// exprNode is a parsed expression that can be matched against the system
+type exprNode interface {
+	eval() (bool, error)
+}
+
+type (
+	notNode   struct{ x exprNode }
+	andNode   struct{ x, y exprNode }
+	orNode    struct{ x, y exprNode }
+	constNode bool
+	// matchNode matches if any of the values match the condition type
+	matchNode struct {
+		cType  ManifestConditionalType
+		values []string
+	}
+)
+
+func (n notNode) eval() (bool, error) {
+	match, err := n.x.eval()
+	return !match, err
+}
+
+func (n andNode) eval() (bool, error) {
+	match, err := n.x.eval()
+	if err != nil || !match {
+		return false, err
+	}
+	return n.y.eval()
+}
+
+func (n orNode) eval() (bool, error) {
+	match, err := n.x.eval()
+	if err != nil || match {
+		return match, err
+	}
+	return n.y.eval()
+}
+
+func (n constNode) eval() (bool, error) {
+	return bool(n), nil
+}
+
+func (n matchNode) eval() (bool, error) {
+	for _, value := range n.values {
+		match, err := MatchConditional(Conditional{Type: n.cType, Value: value})
+		if err != nil || match {
+			return match, err
+		}
+	}
+	return false, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpression parses a 'when' expression
+func parseExpression(expr string) (exprNode, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.unexpected(t)
+	}
+	return node, nil
+}
+
+// EvalExpression parses and evaluates a 'when' expression
+func EvalExpression(expr string) (match bool, err error) {
+	node, err := parseExpression(expr)
+	if err != nil {
+		return false, fmt.Errorf("invalid expression '%s': %s", expr, err)
+	}
+	return node.eval()
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOp && t.value == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		return p.unexpected(p.peek())
+	}
+	return nil
+}
+
+func (p *parser) expectString() (string, error) {
+	t := p.next()
+	if t.kind != tokenString {
+		return "", p.unexpected(t)
+	}
+	return t.value, nil
+}
+
+func (p *parser) unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected '%s' at position %d", t.value, t.pos)
+}
+
+func (p *parser) parseOr() (exprNode, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = orNode{x, y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (exprNode, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = andNode{x, y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (exprNode, error) {
+	if p.accept("!") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (exprNode, error) {
+	if p.accept("(") {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	}
+
+	t := p.next()
+	if t.kind != tokenIdent {
+		return nil, p.unexpected(t)
+	}
+	switch t.value {
+	case "true":
+		return constNode(true), nil
+	case "false":
+		return constNode(false), nil
+	}
+
+	cType := ManifestConditionalType(t.value)
+	if !lo.Contains(conditionalTypes, cType) {
+		return nil, fmt.Errorf("unknown condition type '%s' at position %d", t.value, t.pos)
+	}
+
+	switch {
+	case p.accept("("):
+		value, err := p.expectString()
+		if err != nil {
+			return nil, err
+		}
+		return matchNode{cType: cType, values: []string{value}}, p.expect(")")
+	case p.accept("=="):
+		value, err := p.expectString()
+		return matchNode{cType: cType, values: []string{value}}, err
+	case p.accept("!="):
+		value, err := p.expectString()
+		return notNode{matchNode{cType: cType, values: []string{value}}}, err
+	}
+
+	if in := p.peek(); in.kind == tokenIdent && in.value == "in" {
+		p.next()
+		values, err := p.parseList()
+		return matchNode{cType: cType, values: values}, err
+	}
+	return nil, p.unexpected(p.peek())
+}
+
+func (p *parser) parseList() ([]string, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	values := []string{}
+	if p.accept("]") {
+		return values, nil
+	}
+	for {
+		value, err := p.expectString()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.accept("]") {
+			return values, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
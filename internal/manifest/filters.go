@@ -3,17 +3,67 @@ package manifest
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/lucas-ingemar/packtrak/internal/config"
 	"github.com/samber/lo"
 )
 
+var (
+	MConditionOS         ManifestConditionalType = "os"
+	MConditionOSVersion  ManifestConditionalType = "os_version"
+	MConditionArch       ManifestConditionalType = "arch"
+	MConditionEnv        ManifestConditionalType = "env"
+	MConditionCommand    ManifestConditionalType = "command"
+	MConditionFileExists ManifestConditionalType = "file_exists"
+)
+
+var conditionalTypes = []ManifestConditionalType{
+	MConditionHost,
+	MConditionGroup,
+	MConditionOS,
+	MConditionOSVersion,
+	MConditionArch,
+	MConditionEnv,
+	MConditionCommand,
+	MConditionFileExists,
+}
+
+// archAliases maps the names used by uname to the names used by Go
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"i386":    "386",
+	"i686":    "386",
+}
+
+// MatchConditional matches the 'when' expression of the conditional, if any,
+// and its type and value. A conditional with only an expression has no type.
 func MatchConditional(c Conditional) (match bool, err error) {
+	if c.When != "" {
+		match, err = EvalExpression(c.When)
+		if err != nil || !match || c.Type == "" {
+			return
+		}
+	}
+
 	switch c.Type {
 	case MConditionHost:
 		return filterHost(c)
 	case MConditionGroup:
 		return filterGroup(c)
+	case MConditionOS:
+		return filterOS(c)
+	case MConditionOSVersion:
+		return filterOSVersion(c)
+	case MConditionArch:
+		return filterArch(c)
+	case MConditionEnv:
+		return filterEnv(c)
+	case MConditionCommand:
+		return filterCommand(c)
+	case MConditionFileExists:
+		return filterFileExists(c)
 	default:
 		return false, fmt.Errorf("unknown condition type '%s'", c.Type)
 	}
@@ -24,35 +74,33 @@ func Filter(pmManifest PmManifest) (packages []string, dependencies []string, er
 	dependencies = append(dependencies, pmManifest.Global.Dependencies...)
 
 	for _, c := range pmManifest.Conditional {
-		switch c.Type {
-		case MConditionHost:
-			match, err := filterHost(c)
-			if err != nil {
-				return nil, nil, err
-			}
-			if match {
-				packages = append(packages, c.Packages...)
-				dependencies = append(dependencies, c.Dependencies...)
-			}
-		case MConditionGroup:
-			match, err := filterGroup(c)
-			if err != nil {
-				return nil, nil, err
-			}
-			if match {
-				packages = append(packages, c.Packages...)
-				dependencies = append(dependencies, c.Dependencies...)
-			}
-		default:
-			return nil, nil, fmt.Errorf("unknown condition type '%s'", c.Type)
+		match, err := MatchConditional(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		if match {
+			packages = append(packages, c.Packages...)
+			dependencies = append(dependencies, c.Dependencies...)
 		}
 	}
 
 	return
 }
 
+// All returns the packages and dependencies of every section, whether the
+// conditionals match or not
+func All(pmManifest PmManifest) (packages []string, dependencies []string) {
+	packages = append(packages, pmManifest.Global.Packages...)
+	dependencies = append(dependencies, pmManifest.Global.Dependencies...)
+	for _, c := range pmManifest.Conditional {
+		packages = append(packages, c.Packages...)
+		dependencies = append(dependencies, c.Dependencies...)
+	}
+	return
+}
+
 func filterHost(c Conditional) (match bool, err error) {
-	hostname, err := os.Hostname()
+	hostname, err := env.Hostname()
 	if err != nil {
 		return
 	}
@@ -68,3 +116,62 @@ func filterGroup(c Conditional) (match bool, err error) {
 	}
 	return
 }
+
+// filterOS matches the ID of the OS, or any of the distributions it is like
+func filterOS(c Conditional) (match bool, err error) {
+	release, err := env.OSRelease()
+	if err != nil {
+		return
+	}
+	if release["ID"] == c.Value {
+		return true, nil
+	}
+	return lo.Contains(strings.Fields(release["ID_LIKE"]), c.Value), nil
+}
+
+func filterOSVersion(c Conditional) (match bool, err error) {
+	release, err := env.OSRelease()
+	if err != nil {
+		return
+	}
+	return release["VERSION_ID"] != "" && release["VERSION_ID"] == c.Value, nil
+}
+
+func filterArch(c Conditional) (match bool, err error) {
+	arch := c.Value
+	if alias, ok := archAliases[arch]; ok {
+		arch = alias
+	}
+	return arch == env.Arch(), nil
+}
+
+// filterEnv matches 'NAME' if the variable is set, and 'NAME=value' if it is
+// set to the value
+func filterEnv(c Conditional) (match bool, err error) {
+	key, value, withValue := strings.Cut(c.Value, "=")
+	if key == "" {
+		return false, fmt.Errorf("invalid env condition '%s'", c.Value)
+	}
+	actual, ok := env.LookupEnv(key)
+	if !ok {
+		return
+	}
+	return !withValue || actual == value, nil
+}
+
+func filterCommand(c Conditional) (match bool, err error) {
+	_, err = env.LookPath(c.Value)
+	return err == nil, nil
+}
+
+func filterFileExists(c Conditional) (match bool, err error) {
+	path, err := expandHome(c.Value)
+	if err != nil {
+		return
+	}
+	_, err = env.Stat(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,190 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeEnvironment struct {
+	hostname  string
+	osRelease map[string]string
+	arch      string
+	env       map[string]string
+	commands  []string
+	files     []string
+}
+
+func (f fakeEnvironment) Hostname() (string, error) {
+	return f.hostname, nil
+}
+
+func (f fakeEnvironment) OSRelease() (map[string]string, error) {
+	return f.osRelease, nil
+}
+
+func (f fakeEnvironment) Arch() string {
+	return f.arch
+}
+
+func (f fakeEnvironment) LookupEnv(key string) (string, bool) {
+	value, ok := f.env[key]
+	return value, ok
+}
+
+func (f fakeEnvironment) LookPath(file string) (string, error) {
+	for _, c := range f.commands {
+		if c == file {
+			return "/usr/bin/" + file, nil
+		}
+	}
+	return "", errors.New("not found")
+}
+
+func (f fakeEnvironment) Stat(name string) (os.FileInfo, error) {
+	for _, file := range f.files {
+		if file == name {
+			return nil, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func withEnvironment(t *testing.T, e Environment) {
+	orig := env
+	env = e
+	t.Cleanup(func() { env = orig })
+}
+
+var silverblueAarch64 = fakeEnvironment{
+	hostname:  "laptop01",
+	osRelease: parseOSRelease([]byte("NAME=\"Fedora Linux\"\nID=fedora\nVERSION_ID=40\nVARIANT_ID=silverblue\n")),
+	arch:      "arm64",
+	env:       map[string]string{"XDG_CURRENT_DESKTOP": "GNOME", "EMPTY": ""},
+	commands:  []string{"rpm-ostree"},
+	files:     []string{"/run/ostree-booted"},
+}
+
+func TestMatchConditional(t *testing.T) {
+	withEnvironment(t, silverblueAarch64)
+	config.Groups = []string{"dev"}
+	t.Cleanup(func() { config.Groups = nil })
+
+	tests := []struct {
+		cType ManifestConditionalType
+		value string
+		match bool
+	}{
+		{MConditionHost, "laptop01", true},
+		{MConditionHost, "build01", false},
+		{MConditionGroup, "dev", true},
+		{MConditionGroup, "ops", false},
+		{MConditionOS, "fedora", true},
+		{MConditionOS, "ubuntu", false},
+		{MConditionOSVersion, "40", true},
+		{MConditionOSVersion, "39", false},
+		{MConditionArch, "arm64", true},
+		{MConditionArch, "aarch64", true},
+		{MConditionArch, "amd64", false},
+		{MConditionEnv, "XDG_CURRENT_DESKTOP", true},
+		{MConditionEnv, "XDG_CURRENT_DESKTOP=GNOME", true},
+		{MConditionEnv, "XDG_CURRENT_DESKTOP=KDE", false},
+		{MConditionEnv, "EMPTY", true},
+		{MConditionEnv, "EMPTY=", true},
+		{MConditionEnv, "MISSING", false},
+		{MConditionCommand, "rpm-ostree", true},
+		{MConditionCommand, "dnf", false},
+		{MConditionFileExists, "/run/ostree-booted", true},
+		{MConditionFileExists, "/etc/missing", false},
+	}
+	for _, tt := range tests {
+		match, err := MatchConditional(Conditional{Type: tt.cType, Value: tt.value})
+		assert.Nil(t, err, "should be no error")
+		assert.Equal(t, tt.match, match, "%s: %s", tt.cType, tt.value)
+	}
+
+	_, err := MatchConditional(Conditional{Type: MConditionEnv, Value: "=x"})
+	assert.NotNil(t, err, "env without a name should be an error")
+	_, err = MatchConditional(Conditional{Type: "kernel", Value: "6"})
+	assert.EqualError(t, err, "unknown condition type 'kernel'")
+}
+
+func TestMatchOSLike(t *testing.T) {
+	withEnvironment(t, fakeEnvironment{osRelease: parseOSRelease([]byte("# Rocky\nID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n"))})
+
+	for value, expected := range map[string]bool{"rocky": true, "rhel": true, "fedora": true, "debian": false} {
+		match, err := MatchConditional(Conditional{Type: MConditionOS, Value: value})
+		assert.Nil(t, err, "should be no error")
+		assert.Equal(t, expected, match, value)
+	}
+	match, err := MatchConditional(Conditional{Type: MConditionOSVersion, Value: "9.3"})
+	assert.Nil(t, err, "should be no error")
+	assert.True(t, match, "quoted values should be unquoted")
+
+	withEnvironment(t, fakeEnvironment{osRelease: map[string]string{}})
+	match, err = MatchConditional(Conditional{Type: MConditionOSVersion, Value: ""})
+	assert.Nil(t, err, "should be no error")
+	assert.False(t, match, "missing os-release should not match")
+}
+
+func TestFilter(t *testing.T) {
+	withEnvironment(t, silverblueAarch64)
+
+	packages, dependencies, err := Filter(PmManifest{
+		Global: Global{Packages: []string{"git"}},
+		Conditional: []Conditional{
+			{Type: MConditionArch, Value: "aarch64", Packages: []string{"arm-tools"}},
+			{Type: MConditionArch, Value: "x86_64", Packages: []string{"steam"}},
+			{Type: MConditionFileExists, Value: "/run/ostree-booted", Dependencies: []string{"layered"}},
+		},
+	})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"git", "arm-tools"}, packages)
+	assert.Equal(t, []string{"layered"}, dependencies)
+}
+
+func TestEvalExpression(t *testing.T) {
+	withEnvironment(t, silverblueAarch64)
+	config.Groups = []string{"dev"}
+	t.Cleanup(func() { config.Groups = nil })
+
+	tests := map[string]bool{
+		`group == "dev" && arch == "arm64"`: true,
+		`group == "dev" && arch == "amd64"`: false,
+		`!host("build01")`:                  true,
+		`!host("laptop01")`:                 false,
+		`os in ["fedora", "rhel"]`:          true,
+		`os in []`:                          false,
+		`host != "laptop01" || env("XDG_CURRENT_DESKTOP=GNOME")`:     true,
+		`!(group == "dev" || group == "ops")`:                        false,
+		`group == "ops" || group == "dev" && false`:                  false,
+		`(group == "ops" || group == "dev") && true`:                 true,
+		`file_exists("/run/ostree-booted") && command("rpm-ostree")`: true,
+		`env == "A \"quoted\" value"`:                                false,
+	}
+	for expr, expected := range tests {
+		match, err := EvalExpression(expr)
+		assert.Nil(t, err, expr)
+		assert.Equal(t, expected, match, expr)
+	}
+
+	for expr, msg := range map[string]string{
+		`group == dev`:            "unexpected 'dev' at position 9",
+		`group == "dev" &&`:       "unexpected end of expression",
+		`kernel == "6"`:           "unknown condition type 'kernel' at position 0",
+		`(group == "dev"`:         "unexpected end of expression",
+		`group == "dev`:           "unterminated string at position 9",
+		`group = "dev"`:           "unexpected '=' at position 6",
+		`os in ["fedora" "rhel"]`: "unexpected 'rhel' at position 16",
+	} {
+		_, err := EvalExpression(expr)
+		assert.EqualError(t, err, "invalid expression '"+expr+"': "+msg)
+	}
+
+	match, err := MatchConditional(Conditional{Type: MConditionGroup, Value: "dev", When: `arch == "amd64"`})
+	assert.Nil(t, err, "should be no error")
+	assert.False(t, match, "both type and expression should match")
+}
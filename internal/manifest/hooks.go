@@ -0,0 +1,147 @@
+package manifest
+
+import (
+	"fmt"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"gopkg.in/yaml.v3"
+)
+
+// packageEntry is an item of a packages list. It is either the package, or a
+// mapping with the package, its hooks and the packages it requires:
+//
+//	packages:
+//	  - github.com/user/tool
+//	  - name: github.com/user/other
+//	    requires: dnf:golang
+//	    post_install: make install
+type packageEntry struct {
+	Name         string       `yaml:"name"`
+	Requires     requirements `yaml:"requires,omitempty"`
+	shared.Hooks `yaml:",inline"`
+}
+
+func (e *packageEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&e.Name)
+	}
+
+	type plain packageEntry
+	if err := value.Decode((*plain)(e)); err != nil {
+		return err
+	}
+	if e.Name == "" {
+		return fmt.Errorf("line %d: package entry must have a name", value.Line)
+	}
+	return nil
+}
+
+func (e packageEntry) MarshalYAML() (interface{}, error) {
+	if e.Hooks.IsZero() && len(e.Requires) == 0 {
+		return e.Name, nil
+	}
+	type plain packageEntry
+	return plain(e), nil
+}
+
+// splitEntries returns the packages of the entries, and the hooks and
+// requirements keyed by the package without pin
+func splitEntries(entries []packageEntry) (packages []string, hooks map[string]shared.Hooks, requires map[string][]string) {
+	for _, e := range entries {
+		packages = append(packages, e.Name)
+		if !e.Hooks.IsZero() {
+			if hooks == nil {
+				hooks = map[string]shared.Hooks{}
+			}
+			hooks[shared.Unpin(e.Name)] = e.Hooks
+		}
+		if len(e.Requires) > 0 {
+			if requires == nil {
+				requires = map[string][]string{}
+			}
+			requires[shared.Unpin(e.Name)] = e.Requires
+		}
+	}
+	return
+}
+
+func joinEntries(packages []string, hooks map[string]shared.Hooks, requires map[string][]string) []packageEntry {
+	entries := []packageEntry{}
+	for _, pkg := range packages {
+		entries = append(entries, packageEntry{
+			Name:     pkg,
+			Requires: requires[shared.Unpin(pkg)],
+			Hooks:    hooks[shared.Unpin(pkg)],
+		})
+	}
+	return entries
+}
+
+type rawGlobal struct {
+	Dependencies []string       `yaml:"dependencies"`
+	Packages     []packageEntry `yaml:"packages"`
+}
+
+func (g *Global) UnmarshalYAML(value *yaml.Node) error {
+	raw := rawGlobal{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	g.Dependencies = raw.Dependencies
+	g.Packages, g.Hooks, g.Requires = splitEntries(raw.Packages)
+	return nil
+}
+
+func (g Global) MarshalYAML() (interface{}, error) {
+	return rawGlobal{Dependencies: g.Dependencies, Packages: joinEntries(g.Packages, g.Hooks, g.Requires)}, nil
+}
+
+type rawConditional struct {
+	Type         ManifestConditionalType `yaml:"type"`
+	Value        string                  `yaml:"value"`
+	When         string                  `yaml:"when,omitempty"`
+	Dependencies []string                `yaml:"dependencies"`
+	Packages     []packageEntry          `yaml:"packages"`
+}
+
+func (c *Conditional) UnmarshalYAML(value *yaml.Node) error {
+	raw := rawConditional{}
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+	c.Type, c.Value, c.When, c.Dependencies = raw.Type, raw.Value, raw.When, raw.Dependencies
+	c.Packages, c.Hooks, c.Requires = splitEntries(raw.Packages)
+	return nil
+}
+
+func (c Conditional) MarshalYAML() (interface{}, error) {
+	return rawConditional{
+		Type:         c.Type,
+		Value:        c.Value,
+		When:         c.When,
+		Dependencies: c.Dependencies,
+		Packages:     joinEntries(c.Packages, c.Hooks, c.Requires),
+	}, nil
+}
+
+// FilterHooks returns the hooks of the packages in the global section and
+// the matching conditionals, keyed by the package without pin
+func FilterHooks(pmManifest PmManifest) (map[string]shared.Hooks, error) {
+	hooks := map[string]shared.Hooks{}
+	for pkg, h := range pmManifest.Global.Hooks {
+		hooks[pkg] = h
+	}
+	for _, c := range pmManifest.Conditional {
+		match, err := MatchConditional(c)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+		for pkg, h := range c.Hooks {
+			hooks[pkg] = hooks[pkg].Merge(h)
+		}
+	}
+	return hooks, nil
+}
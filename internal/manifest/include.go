@@ -0,0 +1,248 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+)
+
+// ManifestFile is a manifest together with the file it was read from
+type ManifestFile struct {
+	Manifest
+	Filename string
+}
+
+// ManifestSet is the main manifest together with all manifests it includes,
+// directly or through other included files. Reads are merged over all files,
+// while writes go to the file owning the entry.
+type ManifestSet struct {
+	Files []*ManifestFile
+
+	groupFiles map[string]string
+}
+
+func readManifestSet(filename string, groupFiles map[string]string) (*ManifestSet, error) {
+	main, err := readManifest(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	mainFile, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := &ManifestSet{
+		Files:      []*ManifestFile{{Manifest: main, Filename: mainFile}},
+		groupFiles: map[string]string{},
+	}
+	if err = ms.resolveIncludes(ms.Files[0], map[string]bool{mainFile: true}); err != nil {
+		return nil, err
+	}
+
+	for group, groupFile := range groupFiles {
+		path, err := resolvePath(filepath.Dir(mainFile), groupFile)
+		if err != nil {
+			return nil, err
+		}
+		ms.groupFiles[group] = path
+	}
+	return ms, nil
+}
+
+// resolveIncludes loads the files included by mf, depth first, in the order
+// they are listed. Files already visited are skipped to avoid include cycles.
+func (ms *ManifestSet) resolveIncludes(mf *ManifestFile, visited map[string]bool) error {
+	for _, pattern := range mf.Include {
+		path, err := resolvePath(filepath.Dir(mf.Filename), pattern)
+		if err != nil {
+			return err
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return fmt.Errorf("%s: invalid include '%s': %s", mf.Filename, pattern, err)
+		}
+		if len(matches) == 0 && !isGlob(path) {
+			return fmt.Errorf("%s: included file '%s' does not exist", mf.Filename, pattern)
+		}
+		slices.Sort(matches)
+
+		for _, match := range matches {
+			if visited[match] {
+				continue
+			}
+			visited[match] = true
+
+			included, err := loadManifest(match)
+			if err != nil {
+				return fmt.Errorf("%s: %s", match, err)
+			}
+			incFile := &ManifestFile{Manifest: included, Filename: match}
+			ms.Files = append(ms.Files, incFile)
+			if err = ms.resolveIncludes(incFile, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func resolvePath(dir string, path string) (string, error) {
+	path, err := expandHome(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path), nil
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+func (ms *ManifestSet) main() *ManifestFile {
+	return ms.Files[0]
+}
+
+// Sections returns the names of the manager sections in all files, sorted
+func (ms *ManifestSet) Sections() []shared.ManagerName {
+	names := []shared.ManagerName{}
+	for _, mf := range ms.Files {
+		names = append(names, mf.Sections()...)
+	}
+	names = lo.Uniq(names)
+	slices.Sort(names)
+	return names
+}
+
+// UnknownSections returns the sections not belonging to any of the given managers
+func (ms *ManifestSet) UnknownSections(managers []shared.ManagerName) []shared.ManagerName {
+	return lo.Filter(ms.Sections(), func(name shared.ManagerName, _ int) bool {
+		return !lo.Contains(managers, name)
+	})
+}
+
+// Pm merges the section of the manager from all files
+func (ms *ManifestSet) Pm(name shared.ManagerName) PmManifest {
+	merged := PmManifest{}
+	for _, mf := range ms.Files {
+		pm := mf.Pm(name)
+		merged.Global.Dependencies = append(merged.Global.Dependencies, pm.Global.Dependencies...)
+		merged.Global.Packages = append(merged.Global.Packages, pm.Global.Packages...)
+		merged.Conditional = append(merged.Conditional, pm.Conditional...)
+		for pkg, hooks := range pm.Global.Hooks {
+			if merged.Global.Hooks == nil {
+				merged.Global.Hooks = map[string]shared.Hooks{}
+			}
+			merged.Global.Hooks[pkg] = merged.Global.Hooks[pkg].Merge(hooks)
+		}
+		for pkg, requires := range pm.Global.Requires {
+			if merged.Global.Requires == nil {
+				merged.Global.Requires = map[string][]string{}
+			}
+			merged.Global.Requires[pkg] = lo.Uniq(append(merged.Global.Requires[pkg], requires...))
+		}
+	}
+	merged.Global.Dependencies = lo.Uniq(merged.Global.Dependencies)
+	merged.Global.Packages = lo.Uniq(merged.Global.Packages)
+	return merged
+}
+
+func (ms *ManifestSet) AddConditional(oType ManifestObjectType, pmName shared.ManagerName, cType ManifestConditionalType, cValue string, objects []string) error {
+	mf, err := ms.conditionalTarget(cType, cValue)
+	if err != nil {
+		return err
+	}
+	return mf.AddConditional(oType, pmName, cType, cValue, objects)
+}
+
+func (ms *ManifestSet) RemoveConditional(oType ManifestObjectType, pmName shared.ManagerName, c Conditional, objects []string) error {
+	for _, mf := range ms.Files {
+		if err := mf.RemoveConditional(oType, pmName, c, objects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ms *ManifestSet) AddGlobal(oType ManifestObjectType, pmName shared.ManagerName, objects []string) error {
+	return ms.main().AddGlobal(oType, pmName, objects)
+}
+
+func (ms *ManifestSet) RemoveGlobal(oType ManifestObjectType, pmName shared.ManagerName, objects []string) error {
+	for _, mf := range ms.Files {
+		if err := mf.RemoveGlobal(oType, pmName, objects); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ms *ManifestSet) AddToHost(toAdd []string, pmName shared.ManagerName, oType ManifestObjectType) error {
+	hostname, err := env.Hostname()
+	if err != nil {
+		return err
+	}
+	return ms.AddConditional(oType, pmName, MConditionHost, hostname, toAdd)
+}
+
+func (ms *ManifestSet) AddToGroup(toAdd []string, group string, pmName shared.ManagerName, oType ManifestObjectType) error {
+	return ms.AddConditional(oType, pmName, MConditionGroup, group, toAdd)
+}
+
+// conditionalTarget returns the file new conditional entries are written to.
+// Groups can be mapped to a file in the config, everything else goes to the
+// main manifest.
+func (ms *ManifestSet) conditionalTarget(cType ManifestConditionalType, cValue string) (*ManifestFile, error) {
+	if cType != MConditionGroup {
+		return ms.main(), nil
+	}
+	path, ok := ms.groupFiles[cValue]
+	if !ok {
+		return ms.main(), nil
+	}
+	mf, found := lo.Find(ms.Files, func(mf *ManifestFile) bool {
+		return mf.Filename == path
+	})
+	if !found {
+		return nil, fmt.Errorf("manifest file '%s' for group '%s' is not included in the manifest", path, cValue)
+	}
+	return mf, nil
+}
+
+// Save writes every file that has been changed since it was read. The version
+// is only written to the main manifest.
+func (ms *ManifestSet) Save() error {
+	for i, mf := range ms.Files {
+		if !mf.changed {
+			continue
+		}
+		save := mf.write
+		if i == 0 {
+			save = mf.Save
+		}
+		if err := save(mf.Filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
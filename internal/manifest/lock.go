@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+type LockFace interface {
+	Pin(pmName shared.ManagerName, entries []string) ([]string, error)
+	Get(pmName shared.ManagerName, fullName string) (LockedPackage, bool)
+	Update(pmName shared.ManagerName, packages []shared.Package)
+	Save() error
+}
+
+// Lock records the versions installed by the last successful sync, so that
+// 'sync --locked' can install exactly the same versions on another machine.
+type Lock struct {
+	Managers map[shared.ManagerName][]LockedPackage `yaml:"managers"`
+	Version  string                                 `yaml:"_version"`
+
+	filename string
+}
+
+type LockedPackage struct {
+	Name     string `yaml:"name"`
+	Version  string `yaml:"version"`
+	Url      string `yaml:"url,omitempty"`
+	Checksum string `yaml:"checksum,omitempty"`
+}
+
+const lockHeader = "# Generated by packtrak sync. Do not edit.\n"
+
+func InitLock() (*Lock, error) {
+	return ReadLock(config.LockFile)
+}
+
+// ReadLock reads the lock file. A missing file is an empty lock.
+func ReadLock(filename string) (*Lock, error) {
+	lock := &Lock{Managers: map[shared.ManagerName][]LockedPackage{}, filename: filename}
+
+	b, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = yaml.Unmarshal(b, lock); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if lock.Managers == nil {
+		lock.Managers = map[shared.ManagerName][]LockedPackage{}
+	}
+	return lock, nil
+}
+
+// Get returns the locked package. Git entries tracking the latest commit are
+// written as 'url:latest' in the manifest but locked as 'url'.
+func (l *Lock) Get(pmName shared.ManagerName, fullName string) (LockedPackage, bool) {
+	return lo.Find(l.Managers[pmName], func(p LockedPackage) bool {
+		return p.Name == fullName || p.Name == strings.TrimSuffix(fullName, ":latest")
+	})
+}
+
+// Pin pins the manifest entries to their locked versions. An existing pin in
+// the manifest is replaced by the locked version.
+func (l *Lock) Pin(pmName shared.ManagerName, entries []string) ([]string, error) {
+	pinned := []string{}
+	errs := []error{}
+	for _, entry := range entries {
+		name := shared.Unpin(entry)
+		locked, found := l.Get(pmName, name)
+		if !found || locked.Version == "" {
+			errs = append(errs, fmt.Errorf("%s: '%s' is not locked, run sync without --locked to update the lock file", pmName, name))
+			continue
+		}
+
+		// Pins can not contain ':', the epoch of e.g. rpm versions is dropped
+		version := locked.Version
+		if idx := strings.LastIndex(version, ":"); idx != -1 {
+			version = version[idx+1:]
+		}
+		pinned = append(pinned, name+"@"+version)
+	}
+	return pinned, errors.Join(errs...)
+}
+
+// Update replaces the locked packages of the manager
+func (l *Lock) Update(pmName shared.ManagerName, packages []shared.Package) {
+	locked := lo.Map(packages, func(pkg shared.Package, _ int) LockedPackage {
+		return LockedPackage{
+			Name:     pkg.FullName,
+			Version:  pkg.Version,
+			Url:      pkg.AssetUrl,
+			Checksum: pkg.Checksum,
+		}
+	})
+	slices.SortFunc(locked, func(a, b LockedPackage) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	l.Managers[pmName] = locked
+}
+
+func (l *Lock) Save() error {
+	l.Version = config.Version
+
+	var b bytes.Buffer
+	b.WriteString(lockHeader)
+	yamlEncoder := yaml.NewEncoder(&b)
+	yamlEncoder.SetIndent(2)
+	if err := yamlEncoder.Encode(l); err != nil {
+		return err
+	}
+	return os.WriteFile(l.filename, b.Bytes(), 0644)
+}
@@ -4,10 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/lucas-ingemar/packtrak/internal/config"
 	"github.com/lucas-ingemar/packtrak/internal/shared"
-	"github.com/rs/zerolog/log"
 	"github.com/samber/lo"
 
 	"gopkg.in/yaml.v3"
@@ -25,11 +25,19 @@ var (
 	TypeDependency  ManifestObjectType      = "dependency"
 )
 
+// key returns the manifest key holding objects of the type
+func (t ManifestObjectType) key() string {
+	if t == TypeDependency {
+		return "dependencies"
+	}
+	return "packages"
+}
+
 type ManifestFace interface {
-	Save(filename string) error
+	Save() error
 	Pm(name shared.ManagerName) PmManifest
 	AddConditional(oType ManifestObjectType, pmName shared.ManagerName, cType ManifestConditionalType, cValue string, objects []string) error
-	RemoveConditional(oType ManifestObjectType, pmName shared.ManagerName, cType ManifestConditionalType, cValue string, objects []string) error
+	RemoveConditional(oType ManifestObjectType, pmName shared.ManagerName, c Conditional, objects []string) error
 	AddGlobal(oType ManifestObjectType, pmName shared.ManagerName, objects []string) error
 	RemoveGlobal(oType ManifestObjectType, pmName shared.ManagerName, objects []string) error
 
@@ -37,82 +45,152 @@ type ManifestFace interface {
 	AddToGroup(toAdd []string, group string, pmName shared.ManagerName, oType ManifestObjectType) error
 }
 
+// Manifest holds one section per manager, keyed by the manager name. Sections
+// of managers that are unknown or disabled on this host are kept as they are.
 type Manifest struct {
-	Dnf     PmManifest `yaml:"dnf"`
-	Flatpak PmManifest `yaml:"flatpak"`
-	Git     PmManifest `yaml:"git"`
-	Github  PmManifest `yaml:"github"`
-	Go      PmManifest `yaml:"go"`
-	Version string     `yaml:"_version"`
-}
+	Managers map[shared.ManagerName]*PmManifest
+	Include  []string
+	Version  string
 
-func (m *Manifest) Pm(name shared.ManagerName) PmManifest {
-	return *m.pmPnt(name)
+	doc     *yaml.Node
+	changed bool
 }
 
-func (m *Manifest) AddConditional(oType ManifestObjectType, pmName shared.ManagerName, cType ManifestConditionalType, cValue string, objects []string) error {
-	c, err := m.getOrAddConditional(pmName, cType, cValue)
-	if err != nil {
-		return err
+const (
+	versionKey = "_version"
+	includeKey = "include"
+)
+
+func (m *Manifest) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: manifest must be a mapping", value.Line)
 	}
-	switch oType {
-	case TypePackage:
-		c.Packages = append(c.Packages, objects...)
-	case TypeDependency:
-		c.Dependencies = append(c.Dependencies, objects...)
+
+	m.Managers = map[shared.ManagerName]*PmManifest{}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, val := value.Content[i], value.Content[i+1]
+		switch key.Value {
+		case versionKey:
+			if err := val.Decode(&m.Version); err != nil {
+				return err
+			}
+			continue
+		case includeKey:
+			if err := val.Decode(&m.Include); err != nil {
+				return err
+			}
+			continue
+		}
+
+		pm := PmManifest{}
+		if err := val.Decode(&pm); err != nil {
+			return err
+		}
+		m.Managers[shared.ManagerName(key.Value)] = &pm
 	}
 	return nil
 }
 
-func (m *Manifest) RemoveConditional(oType ManifestObjectType, pmName shared.ManagerName, cType ManifestConditionalType, cValue string, objects []string) error {
-	c, err := m.getOrAddConditional(pmName, cType, cValue)
-	if err != nil {
-		return err
+func (m Manifest) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	if len(m.Include) > 0 {
+		val := &yaml.Node{}
+		if err := val.Encode(m.Include); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: includeKey}, val)
+	}
+	for _, name := range m.Sections() {
+		val := &yaml.Node{}
+		if err := val.Encode(m.Managers[name]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: string(name)}, val)
 	}
+	if m.Version != "" {
+		node.Content = append(node.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: versionKey},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: m.Version},
+		)
+	}
+	return node, nil
+}
+
+// Sections returns the names of all manager sections, sorted
+func (m *Manifest) Sections() []shared.ManagerName {
+	names := lo.Keys(m.Managers)
+	slices.Sort(names)
+	return names
+}
 
-	switch oType {
-	case TypePackage:
-		c.Packages = lo.Filter(c.Packages, func(item string, index int) bool {
-			return !lo.Contains(objects, item)
-		})
-	case TypeDependency:
-		c.Dependencies = lo.Filter(c.Dependencies, func(item string, index int) bool {
-			return !lo.Contains(objects, item)
-		})
+// UnknownSections returns the sections not belonging to any of the given managers
+func (m *Manifest) UnknownSections(managers []shared.ManagerName) []shared.ManagerName {
+	return lo.Filter(m.Sections(), func(name shared.ManagerName, _ int) bool {
+		return !lo.Contains(managers, name)
+	})
+}
+
+func (m *Manifest) Pm(name shared.ManagerName) PmManifest {
+	if pm, ok := m.Managers[name]; ok {
+		return *pm
 	}
-	return nil
+	return PmManifest{}
 }
 
-func (m *Manifest) AddGlobal(oType ManifestObjectType, pmName shared.ManagerName, objects []string) error {
-	fmt.Println(oType, "manifest")
-	pm := m.pmPnt(pmName)
-	switch oType {
-	case TypePackage:
-		pm.Global.Packages = append(pm.Global.Packages, objects...)
-	case TypeDependency:
-		pm.Global.Dependencies = append(pm.Global.Dependencies, objects...)
+func (m *Manifest) AddConditional(oType ManifestObjectType, pmName shared.ManagerName, cType ManifestConditionalType, cValue string, objects []string) error {
+	section := ensureMappingValue(m.root(), string(pmName), yaml.MappingNode)
+	c := conditionalNode(section, Conditional{Type: cType, Value: cValue})
+	if c == nil {
+		c = newConditionalNode(cType, cValue)
+		conditionals := ensureMappingValue(section, "conditional", yaml.SequenceNode)
+		conditionals.Style &^= yaml.FlowStyle
+		conditionals.Content = append(conditionals.Content, c)
 	}
-	return nil
+	appendScalars(ensureMappingValue(c, oType.key(), yaml.SequenceNode), objects)
+	m.changed = true
+	return m.refresh()
+}
+
+func (m *Manifest) RemoveConditional(oType ManifestObjectType, pmName shared.ManagerName, conditional Conditional, objects []string) error {
+	section := mappingValue(m.root(), string(pmName))
+	if section == nil {
+		return nil
+	}
+	c := conditionalNode(section, conditional)
+	if c == nil {
+		return nil
+	}
+	if seq := mappingValue(c, oType.key()); seq != nil && removeScalars(seq, objects) > 0 {
+		m.changed = true
+	}
+	return m.refresh()
+}
+
+func (m *Manifest) AddGlobal(oType ManifestObjectType, pmName shared.ManagerName, objects []string) error {
+	section := ensureMappingValue(m.root(), string(pmName), yaml.MappingNode)
+	global := ensureMappingValue(section, "global", yaml.MappingNode)
+	appendScalars(ensureMappingValue(global, oType.key(), yaml.SequenceNode), objects)
+	m.changed = true
+	return m.refresh()
 }
 
 func (m *Manifest) RemoveGlobal(oType ManifestObjectType, pmName shared.ManagerName, objects []string) error {
-	pm := m.pmPnt(pmName)
-	switch oType {
-	case TypePackage:
-		pm.Global.Packages = lo.Filter(pm.Global.Packages, func(item string, index int) bool {
-			return !lo.Contains(objects, item)
-		})
-	case TypeDependency:
-		pm.Global.Dependencies = lo.Filter(pm.Global.Dependencies, func(item string, index int) bool {
-			return !lo.Contains(objects, item)
-		})
+	section := mappingValue(m.root(), string(pmName))
+	if section == nil {
+		return nil
 	}
-	return nil
+	global := mappingValue(section, "global")
+	if global == nil {
+		return nil
+	}
+	if seq := mappingValue(global, oType.key()); seq != nil && removeScalars(seq, objects) > 0 {
+		m.changed = true
+	}
+	return m.refresh()
 }
 
-// FIXME: Somhow os.Hostname needs to be moved to an interface
 func (m *Manifest) AddToHost(toAdd []string, pmName shared.ManagerName, oType ManifestObjectType) error {
-	hostname, err := os.Hostname()
+	hostname, err := env.Hostname()
 	if err != nil {
 		return err
 	}
@@ -123,50 +201,63 @@ func (m *Manifest) AddToGroup(toAdd []string, group string, pmName shared.Manage
 	return m.AddConditional(oType, pmName, MConditionGroup, group, toAdd)
 }
 
-func (m *Manifest) getOrAddConditional(pmName shared.ManagerName, cType ManifestConditionalType, cValue string) (*Conditional, error) {
-	pm := m.pmPnt(pmName)
-	for idx := range pm.Conditional {
-		if pm.Conditional[idx].Type == cType && pm.Conditional[idx].Value == cValue {
-			return &pm.Conditional[idx], nil
+// root returns the top level mapping of the manifest document. If the manifest
+// was not read from a file the document is created from the current content.
+func (m *Manifest) root() *yaml.Node {
+	if m.doc == nil || len(m.doc.Content) == 0 || m.doc.Content[0].Kind != yaml.MappingNode {
+		mapping := &yaml.Node{}
+		if err := mapping.Encode(m); err != nil {
+			mapping = &yaml.Node{Kind: yaml.MappingNode}
 		}
+		m.doc = &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{mapping}}
 	}
-	pm.Conditional = append(pm.Conditional, Conditional{
-		Type:         cType,
-		Value:        cValue,
-		Dependencies: []string{},
-		Packages:     []string{},
-	})
-	return &pm.Conditional[len(pm.Conditional)-1], nil
+	return m.doc.Content[0]
 }
 
-func (m *Manifest) pmPnt(name shared.ManagerName) *PmManifest {
-	switch name {
-	case "dnf":
-		return &m.Dnf
-	case "flatpak":
-		return &m.Flatpak
-	case "git":
-		return &m.Git
-	case "github":
-		return &m.Github
-	case "go":
-		return &m.Go
-	default:
-		log.Fatal().Msgf("%s is not a registered package manager", name)
-		panic("")
-	}
+// refresh decodes the document again after it has been edited
+func (m *Manifest) refresh() error {
+	return m.doc.Decode(m)
 }
 
+// Save writes the manifest with the version of packtrak
 func (m *Manifest) Save(filename string) error {
 	m.Version = config.Version
+	setScalar(m.root(), versionKey, m.Version)
+	return m.write(filename)
+}
+
+// write writes the manifest as it is, which is how included files are saved
+// since only the main manifest holds the version
+func (m *Manifest) write(filename string) error {
 	var b bytes.Buffer
 	yamlEncoder := yaml.NewEncoder(&b)
 	yamlEncoder.SetIndent(2)
-	err := yamlEncoder.Encode(&m)
+	err := yamlEncoder.Encode(m.doc)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filename, b.Bytes(), 0755)
+	if err = os.WriteFile(filename, restoreBlankLines(b.Bytes()), 0755); err != nil {
+		return err
+	}
+	m.changed = false
+	return nil
+}
+
+// conditionalNode returns the conditional with the same type, value and
+// 'when' expression
+func conditionalNode(section *yaml.Node, conditional Conditional) *yaml.Node {
+	conditionals := mappingValue(section, "conditional")
+	if conditionals == nil {
+		return nil
+	}
+	for _, c := range conditionals.Content {
+		if scalarValue(c, "type") == string(conditional.Type) &&
+			scalarValue(c, "value") == conditional.Value &&
+			scalarValue(c, "when") == conditional.When {
+			return c
+		}
+	}
+	return nil
 }
 
 type PmManifest struct {
@@ -177,17 +268,28 @@ type PmManifest struct {
 type Global struct {
 	Dependencies []string `yaml:"dependencies"`
 	Packages     []string `yaml:"packages"`
+	// Hooks of the packages, keyed by the package without pin
+	Hooks map[string]shared.Hooks `yaml:"-"`
+	// Requires holds the packages of other managers that the packages need,
+	// keyed by the package without pin
+	Requires map[string][]string `yaml:"-"`
 }
 
 type Conditional struct {
 	Type         ManifestConditionalType `yaml:"type"`
 	Value        string                  `yaml:"value"`
+	When         string                  `yaml:"when,omitempty"`
 	Dependencies []string                `yaml:"dependencies"`
 	Packages     []string                `yaml:"packages"`
+	// Hooks of the packages, keyed by the package without pin
+	Hooks map[string]shared.Hooks `yaml:"-"`
+	// Requires holds the packages of other managers that the packages need,
+	// keyed by the package without pin
+	Requires map[string][]string `yaml:"-"`
 }
 
-func InitManifest() (Manifest, error) {
-	return readManifest(config.ManifestFile)
+func InitManifest() (*ManifestSet, error) {
+	return readManifestSet(config.ManifestFile, config.ManifestGroupFiles)
 }
 
 func readManifest(filename string) (manifest Manifest, err error) {
@@ -195,16 +297,30 @@ func readManifest(filename string) (manifest Manifest, err error) {
 	if err != nil {
 		return
 	}
+	return loadManifest(filename)
+}
 
+// loadManifest reads a manifest without creating it if it is missing
+func loadManifest(filename string) (manifest Manifest, err error) {
 	yamlRaw, err := os.ReadFile(filename)
 	if err != nil {
 		return
 	}
 
-	err = yaml.Unmarshal(yamlRaw, &manifest)
+	doc := yaml.Node{}
+	err = yaml.Unmarshal(yamlRaw, &doc)
 	if err != nil {
 		return
 	}
+	markBlankLines(&doc, yamlRaw)
+
+	if len(doc.Content) > 0 {
+		err = doc.Decode(&manifest)
+		if err != nil {
+			return
+		}
+	}
+	manifest.doc = &doc
 	return
 }
 
@@ -0,0 +1,324 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const testManifest = `dnf:
+  global:
+    dependencies:
+      - copr:copr.fedorainfracloud.org/phracek/PyCharm
+    packages:
+      - sway
+  conditional:
+    - type: host
+      value: spock
+      dependencies: []
+      packages:
+        - krita
+cargo:
+  global:
+    dependencies: []
+    packages:
+      - ripgrep
+  conditional: []
+_version: v1.0.0
+`
+
+func TestManifestYaml(t *testing.T) {
+	m := Manifest{}
+	err := yaml.Unmarshal([]byte(testManifest), &m)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, "v1.0.0", m.Version)
+	assert.Equal(t, []string{"sway"}, m.Pm("dnf").Global.Packages)
+	assert.Equal(t, "krita", m.Pm("dnf").Conditional[0].Packages[0])
+	assert.Equal(t, PmManifest{}, m.Pm("go"), "missing sections should be empty")
+	assert.Equal(t, []shared.ManagerName{"cargo"}, m.UnknownSections([]shared.ManagerName{"dnf", "go"}))
+
+	b, err := yaml.Marshal(&m)
+	assert.Nil(t, err, "should be no error")
+
+	m2 := Manifest{}
+	err = yaml.Unmarshal(b, &m2)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, m, m2, "unknown sections should survive a round trip")
+}
+
+const testCommentedManifest = `# Packages for all machines
+go:
+  global:
+    dependencies: []
+    packages:
+      # Language server, asked for by the backend team
+      - golang.org/x/tools/gopls
+      - sigs.k8s.io/kind # remove after the migration
+
+  conditional: []
+dnf:
+  global:
+    packages:
+      - sway
+_version: v1.0.0
+`
+
+func TestManifestEditPreservesComments(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "manifest.yaml")
+	err := os.WriteFile(filename, []byte(testCommentedManifest), 0644)
+	assert.Nil(t, err, "should be no error")
+
+	m, err := readManifest(filename)
+	assert.Nil(t, err, "should be no error")
+
+	assert.Nil(t, m.AddGlobal(TypePackage, "go", []string{"github.com/mikefarah/yq/v4"}))
+	assert.Nil(t, m.RemoveGlobal(TypePackage, "go", []string{"sigs.k8s.io/kind"}))
+	assert.Nil(t, m.AddGlobal(TypeDependency, "dnf", []string{"copr:atim/lazygit"}))
+	assert.Nil(t, m.AddConditional(TypePackage, "dnf", MConditionGroup, "work", []string{"terraform"}))
+	assert.Nil(t, m.AddConditional(TypePackage, "dnf", MConditionGroup, "work", []string{"vault"}))
+	assert.Nil(t, m.RemoveConditional(TypePackage, "dnf", Conditional{Type: MConditionGroup, Value: "work"}, []string{"terraform"}))
+
+	assert.Equal(t, []string{"golang.org/x/tools/gopls", "github.com/mikefarah/yq/v4"}, m.Pm("go").Global.Packages, "struct should follow the edits")
+	assert.Equal(t, []string{"vault"}, m.Pm("dnf").Conditional[0].Packages)
+
+	assert.Nil(t, m.Save(filename))
+	b, err := os.ReadFile(filename)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, `# Packages for all machines
+go:
+  global:
+    dependencies: []
+    packages:
+      # Language server, asked for by the backend team
+      - golang.org/x/tools/gopls
+      - github.com/mikefarah/yq/v4
+
+  conditional: []
+dnf:
+  global:
+    packages:
+      - sway
+    dependencies:
+      - copr:atim/lazygit
+  conditional:
+    - type: group
+      value: work
+      dependencies: []
+      packages:
+        - vault
+_version: ""
+`, string(b))
+}
+
+func TestManifestSetIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"manifest.yaml":     "include:\n  - manifest.d/*.yaml\n  - team.yaml\ngo:\n  global:\n    packages: [personal]\n",
+		"team.yaml":         "include: [manifest.yaml]\ngo:\n  global:\n    packages: [shared, personal]\n",
+		"manifest.d/a.yaml": "go:\n  conditional:\n    - type: group\n      value: work\n      packages: [a]\n",
+		"manifest.d/b.yaml": "dnf:\n  global:\n    packages: [b]\n",
+	}
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "manifest.d"), 0755))
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	ms, err := readManifestSet(filepath.Join(dir, "manifest.yaml"), map[string]string{"work": "manifest.d/a.yaml", "home": "home.yaml"})
+	assert.Nil(t, err, "should be no error")
+	assert.Len(t, ms.Files, 4, "include cycles should be skipped")
+	assert.Equal(t, filepath.Join(dir, "manifest.d", "a.yaml"), ms.Files[1].Filename, "globs should be sorted")
+	assert.Equal(t, []shared.ManagerName{"dnf", "go"}, ms.Sections())
+	assert.Equal(t, []string{"personal", "shared"}, ms.Pm("go").Global.Packages, "globals should be merged")
+	assert.Len(t, ms.Pm("go").Conditional, 1)
+
+	version := config.Version
+	config.Version = "v1.2.0"
+	t.Cleanup(func() { config.Version = version })
+
+	assert.Nil(t, ms.AddToGroup([]string{"c"}, "work", "go", TypePackage))
+	assert.Nil(t, ms.RemoveGlobal(TypePackage, "dnf", []string{"b"}))
+	assert.EqualError(t, ms.AddToGroup([]string{"c"}, "home", "go", TypePackage),
+		"manifest file '"+filepath.Join(dir, "home.yaml")+"' for group 'home' is not included in the manifest")
+	assert.Nil(t, ms.Save())
+
+	a, err := loadManifest(filepath.Join(dir, "manifest.d", "a.yaml"))
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"a", "c"}, a.Pm("go").Conditional[0].Packages, "group should be written to its file")
+	b, err := loadManifest(filepath.Join(dir, "manifest.d", "b.yaml"))
+	assert.Nil(t, err, "should be no error")
+	assert.Empty(t, b.Pm("dnf").Global.Packages)
+	bRaw, err := os.ReadFile(filepath.Join(dir, "manifest.d", "b.yaml"))
+	assert.Nil(t, err, "should be no error")
+	assert.NotContains(t, string(bRaw), versionKey, "only the main manifest should get a version")
+	team, err := os.ReadFile(filepath.Join(dir, "team.yaml"))
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, files["team.yaml"], string(team), "unchanged files should not be written")
+
+	_, err = readManifestSet(filepath.Join(dir, "team.yaml"), nil)
+	assert.Nil(t, err, "should be no error")
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("include: [missing.yaml]\n"), 0644))
+	_, err = readManifestSet(filepath.Join(dir, "broken.yaml"), nil)
+	assert.ErrorContains(t, err, "included file 'missing.yaml' does not exist")
+}
+
+func TestLock(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "manifest.lock")
+	lock, err := ReadLock(filename)
+	assert.Nil(t, err, "missing lock file should be empty")
+
+	lock.Update("dnf", []shared.Package{
+		{Name: "vim", FullName: "vim", Version: "2:9.1.0-1.fc40"},
+		{Name: "ripgrep", FullName: "ripgrep", Version: "14.1.0-1.fc40"},
+	})
+	lock.Update("git", []shared.Package{{Name: "user/repo", FullName: "https://github.com/user/repo", Version: "abc123"}})
+	lock.Update("github", []shared.Package{{
+		Name:     "user/tool",
+		FullName: "github.com/user/tool:tool_#version#",
+		Version:  "v1.0.0",
+		AssetUrl: "https://github.com/user/tool/releases/download/v1.0.0/tool_v1.0.0",
+		Checksum: "sha256:00",
+	}})
+	assert.Nil(t, lock.Save())
+
+	lock, err = ReadLock(filename)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, "ripgrep", lock.Managers["dnf"][0].Name, "packages should be sorted")
+
+	pinned, err := lock.Pin("dnf", []string{"ripgrep@14.x", "vim"})
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []string{"ripgrep@14.1.0-1.fc40", "vim@9.1.0-1.fc40"}, pinned, "lock should replace pins and drop epochs")
+
+	pinned, err = lock.Pin("git", []string{"https://github.com/user/repo:latest", "https://github.com/user/other"})
+	assert.Equal(t, []string{"https://github.com/user/repo:latest@abc123"}, pinned)
+	assert.EqualError(t, err, "git: 'https://github.com/user/other' is not locked, run sync without --locked to update the lock file")
+
+	locked, found := lock.Get("github", "github.com/user/tool:tool_#version#")
+	assert.True(t, found)
+	assert.Equal(t, "sha256:00", locked.Checksum)
+}
+
+const testHooksManifest = `git:
+  global:
+    dependencies: []
+    packages:
+      - https://github.com/junegunn/fzf
+      - name: https://github.com/neovim/neovim@v0.9.5
+        post_install: make install
+        post_update: make install
+  conditional:
+    - type: host
+      value: spock
+      dependencies: []
+      packages:
+        - name: https://github.com/tmux/tmux
+          pre_remove: tmux kill-server
+_version: v1.0.0
+`
+
+func TestManifestHooks(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.Nil(t, os.WriteFile(filename, []byte(testHooksManifest), 0644))
+
+	m, err := readManifest(filename)
+	assert.Nil(t, err, "should be no error")
+	pm := m.Pm("git")
+	assert.Equal(t, []string{"https://github.com/junegunn/fzf", "https://github.com/neovim/neovim@v0.9.5"}, pm.Global.Packages)
+	assert.Equal(t, shared.Hooks{PostInstall: "make install", PostUpdate: "make install"}, pm.Global.Hooks["https://github.com/neovim/neovim"])
+	assert.Equal(t, shared.Hooks{PreRemove: "tmux kill-server"}, pm.Conditional[0].Hooks["https://github.com/tmux/tmux"])
+
+	withEnvironment(t, fakeEnvironment{hostname: "kirk"})
+	hooks, err := FilterHooks(pm)
+	assert.Nil(t, err, "should be no error")
+	assert.Len(t, hooks, 1, "hooks of conditionals not matching should be left out")
+
+	b, err := yaml.Marshal(&m)
+	assert.Nil(t, err, "should be no error")
+	m2 := Manifest{}
+	assert.Nil(t, yaml.Unmarshal(b, &m2))
+	assert.Equal(t, pm, m2.Pm("git"), "hooks should survive a round trip")
+
+	assert.Nil(t, m.RemoveGlobal(TypePackage, "git", []string{"https://github.com/neovim/neovim"}))
+	assert.Equal(t, []string{"https://github.com/junegunn/fzf"}, m.Pm("git").Global.Packages)
+	assert.Empty(t, m.Pm("git").Global.Hooks)
+
+	err = yaml.Unmarshal([]byte("packages:\n  - post_install: make\n"), &Global{})
+	assert.Error(t, err, "entries without a name should fail")
+}
+
+const testBlockHookManifest = `git:
+  global:
+    dependencies: []
+
+    # Built from source
+    packages:
+      - name: https://github.com/neovim/neovim
+        post_install: |
+          make
+
+          make install
+      - https://github.com/junegunn/fzf
+
+  conditional: []
+_version: v1.0.0
+`
+
+func TestManifestBlankLinesInBlockScalar(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.Nil(t, os.WriteFile(filename, []byte(testBlockHookManifest), 0644))
+
+	m, err := readManifest(filename)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, "make\n\nmake install\n", m.Pm("git").Global.Hooks["https://github.com/neovim/neovim"].PostInstall)
+
+	assert.Nil(t, m.Save(filename))
+	b, err := os.ReadFile(filename)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, strings.Replace(testBlockHookManifest, "v1.0.0", `""`, 1), string(b), "blank lines should be kept, also in the hook")
+}
+
+const testRequiresManifest = `go:
+  global:
+    dependencies: []
+    packages:
+      - name: golang.org/x/tools/gopls
+        requires: dnf:golang
+      - name: github.com/user/tool@v1.0.0
+        requires:
+          - dnf:golang
+          - git:https://github.com/user/config
+  conditional: []
+_version: v1.0.0
+`
+
+func TestManifestRequires(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.Nil(t, os.WriteFile(filename, []byte(testRequiresManifest), 0644))
+
+	m, err := readManifest(filename)
+	assert.Nil(t, err, "should be no error")
+	pm := m.Pm("go")
+	assert.Equal(t, []string{"golang.org/x/tools/gopls", "github.com/user/tool@v1.0.0"}, pm.Global.Packages)
+
+	requires, err := FilterRequires(pm)
+	assert.Nil(t, err, "should be no error")
+	assert.Equal(t, []Requirement{{Manager: "dnf", Package: "golang"}}, requires["golang.org/x/tools/gopls"])
+	assert.Equal(t, []Requirement{
+		{Manager: "dnf", Package: "golang"},
+		{Manager: "git", Package: "https://github.com/user/config"},
+	}, requires["github.com/user/tool"])
+
+	b, err := yaml.Marshal(&m)
+	assert.Nil(t, err, "should be no error")
+	m2 := Manifest{}
+	assert.Nil(t, yaml.Unmarshal(b, &m2))
+	assert.Equal(t, pm, m2.Pm("go"), "requirements should survive a round trip")
+
+	err = yaml.Unmarshal([]byte("packages:\n  - name: tool\n    requires: golang\n"), &Global{})
+	assert.Error(t, err, "requirements without a manager should fail")
+}
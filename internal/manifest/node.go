@@ -0,0 +1,191 @@
+package manifest
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// The functions in this file edit the yaml.Node tree of the manifest in place,
+// so that comments, blank lines and ordering written by the user survive a save.
+
+const blankLineMarker = "#__packtrak_blank__"
+
+var blankLineMarkerRegex = regexp.MustCompile(`(?m)^[ \t]*` + blankLineMarker + `$`)
+
+// markBlankLines adds a comment to the head of the node following each blank
+// line of the raw manifest, since yaml.v3 keeps comments but drops blank lines
+// other than the ones after a comment. Blank lines in or right after a
+// multi-line scalar, e.g. a hook written as a '|' block, are left alone since
+// they are part of the value. restoreBlankLines turns the comments back into
+// blank lines after encoding.
+func markBlankLines(doc *yaml.Node, raw []byte) {
+	// All nodes in the order they are written, and whether a comment above
+	// them belongs to them. Comments above a mapping value belong to its key.
+	nodes := []*yaml.Node{}
+	heads := map[*yaml.Node]bool{}
+	var walk func(n *yaml.Node, isValue bool)
+	walk = func(n *yaml.Node, isValue bool) {
+		nodes = append(nodes, n)
+		heads[n] = !isValue
+		for i, c := range n.Content {
+			walk(c, n.Kind == yaml.DocumentNode || (n.Kind == yaml.MappingNode && i%2 == 1))
+		}
+	}
+	walk(doc, true)
+
+	lines := strings.Split(string(raw), "\n")
+	blanks := map[*yaml.Node][]int{}
+	for idx, line := range lines {
+		lineNo := idx + 1
+		if strings.TrimSpace(line) != "" || afterComment(lines[:idx]) {
+			continue
+		}
+		prev, _, _ := lo.FindLastIndexOf(nodes, func(n *yaml.Node) bool { return n.Line < lineNo })
+		if prev != nil && isMultilineScalar(prev) {
+			continue
+		}
+		next, found := lo.Find(nodes, func(n *yaml.Node) bool { return n.Line > lineNo && heads[n] })
+		if !found {
+			continue
+		}
+		blanks[next] = append(blanks[next], lineNo)
+	}
+
+	for n, blankLines := range blanks {
+		comments := []string{}
+		if n.HeadComment != "" {
+			comments = strings.Split(n.HeadComment, "\n")
+		}
+		for i := len(blankLines) - 1; i >= 0; i-- {
+			// Comment lines between the blank line and the node stay below it
+			below := lo.CountBy(lines[blankLines[i]:n.Line-1], func(line string) bool {
+				return strings.HasPrefix(strings.TrimSpace(line), "#")
+			})
+			at := max(len(comments)-below, 0)
+			comments = append(comments[:at], append([]string{blankLineMarker}, comments[at:]...)...)
+		}
+		n.HeadComment = strings.Join(comments, "\n")
+	}
+}
+
+// afterComment reports if the last of the lines that is not blank is a
+// comment, yaml.v3 keeps a blank line after comments by itself
+func afterComment(lines []string) bool {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return strings.HasPrefix(line, "#")
+		}
+	}
+	return false
+}
+
+func isMultilineScalar(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode &&
+		(n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 || strings.Contains(strings.TrimSuffix(n.Value, "\n"), "\n"))
+}
+
+func restoreBlankLines(b []byte) []byte {
+	return blankLineMarkerRegex.ReplaceAll(b, nil)
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the value of a scalar in the mapping, or "" if it is missing
+func scalarValue(mapping *yaml.Node, key string) string {
+	if val := mappingValue(mapping, key); val != nil && val.Kind == yaml.ScalarNode {
+		return val.Value
+	}
+	return ""
+}
+
+func ensureMappingValue(mapping *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	if val := mappingValue(mapping, key); val != nil {
+		if val.Kind != kind {
+			// E.g. 'packages:' without a value is decoded as a null scalar
+			*val = yaml.Node{Kind: kind}
+		}
+		return val
+	}
+
+	val := &yaml.Node{Kind: kind}
+	if kind == yaml.ScalarNode {
+		val.Tag = "!!str"
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, val)
+	return val
+}
+
+func setScalar(mapping *yaml.Node, key string, value string) {
+	node := ensureMappingValue(mapping, key, yaml.ScalarNode)
+	node.Value = value
+}
+
+func appendScalars(seq *yaml.Node, values []string) {
+	if len(values) > 0 && len(seq.Content) == 0 {
+		// An empty list is usually written as '[]', keep the added items in block style
+		seq.Style &^= yaml.FlowStyle
+	}
+	for _, v := range values {
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: v}
+		if len(seq.Content) > 0 {
+			// Foot comments, e.g. a trailing blank line, belong to the end of
+			// the list rather than to the item that used to be last
+			last := seq.Content[len(seq.Content)-1]
+			node.FootComment, last.FootComment = last.FootComment, ""
+		}
+		seq.Content = append(seq.Content, node)
+	}
+}
+
+// removeScalars removes the values from the list, including pinned versions
+// of them, e.g. 'ripgrep@14.1.0' for 'ripgrep'. Entries with hooks are matched
+// on their name.
+func removeScalars(seq *yaml.Node, values []string) (removed int) {
+	content := []*yaml.Node{}
+	for _, n := range seq.Content {
+		name := entryName(n)
+		if name != "" && (lo.Contains(values, name) || lo.Contains(values, shared.Unpin(name))) {
+			if n.FootComment != "" && len(content) > 0 {
+				prev := content[len(content)-1]
+				prev.FootComment = strings.TrimPrefix(prev.FootComment+"\n"+n.FootComment, "\n")
+			}
+			removed++
+			continue
+		}
+		content = append(content, n)
+	}
+	seq.Content = content
+	return
+}
+
+// entryName returns the package of a packages list item, which is either a
+// scalar or a mapping with a name
+func entryName(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Value
+	case yaml.MappingNode:
+		return scalarValue(n, "name")
+	}
+	return ""
+}
+
+func newConditionalNode(cType ManifestConditionalType, cValue string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	setScalar(node, "type", string(cType))
+	setScalar(node, "value", cValue)
+	ensureMappingValue(node, TypeDependency.key(), yaml.SequenceNode).Style = yaml.FlowStyle
+	ensureMappingValue(node, TypePackage.key(), yaml.SequenceNode).Style = yaml.FlowStyle
+	return node
+}
@@ -0,0 +1,94 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// Requirement is a package of a manager that has to be installed before the
+// package requiring it, written as 'manager:package', e.g. 'dnf:golang'
+type Requirement struct {
+	Manager shared.ManagerName
+	Package string
+}
+
+func (r Requirement) String() string {
+	return fmt.Sprintf("%s:%s", r.Manager, r.Package)
+}
+
+// ParseRequirement splits the requirement on the first colon, since the
+// package itself might contain one, e.g. 'git:https://github.com/user/repo'
+func ParseRequirement(s string) (Requirement, error) {
+	manager, pkg, found := strings.Cut(s, ":")
+	if !found || manager == "" || pkg == "" {
+		return Requirement{}, fmt.Errorf("invalid requirement '%s', should be 'manager:package'", s)
+	}
+	return Requirement{Manager: shared.ManagerName(manager), Package: shared.Unpin(pkg)}, nil
+}
+
+// requirements is either a single requirement or a list of them
+type requirements []string
+
+func (r *requirements) UnmarshalYAML(value *yaml.Node) error {
+	list := []string{}
+	if value.Kind == yaml.ScalarNode {
+		list = append(list, value.Value)
+	} else if err := value.Decode(&list); err != nil {
+		return err
+	}
+	for _, s := range list {
+		if _, err := ParseRequirement(s); err != nil {
+			return fmt.Errorf("line %d: %w", value.Line, err)
+		}
+	}
+	*r = list
+	return nil
+}
+
+func (r requirements) MarshalYAML() (interface{}, error) {
+	if len(r) == 1 {
+		return r[0], nil
+	}
+	return []string(r), nil
+}
+
+// FilterRequires returns the requirements of the packages in the global
+// section and the matching conditionals, keyed by the package without pin
+func FilterRequires(pmManifest PmManifest) (map[string][]Requirement, error) {
+	requires := map[string][]Requirement{}
+	add := func(pkgRequires map[string][]string) error {
+		for pkg, reqs := range pkgRequires {
+			for _, s := range reqs {
+				req, err := ParseRequirement(s)
+				if err != nil {
+					return err
+				}
+				if !lo.Contains(requires[pkg], req) {
+					requires[pkg] = append(requires[pkg], req)
+				}
+			}
+		}
+		return nil
+	}
+
+	if err := add(pmManifest.Global.Requires); err != nil {
+		return nil, err
+	}
+	for _, c := range pmManifest.Conditional {
+		match, err := MatchConditional(c)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+		if err := add(c.Requires); err != nil {
+			return nil, err
+		}
+	}
+	return requires, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 
 	"github.com/alexellis/go-execute/v2"
 )
@@ -27,3 +28,7 @@ func Command(ctx context.Context, command string, args []string, streamStdio boo
 
 	return res.Stdout, nil
 }
+
+func CommandString(command string, args []string) string {
+	return strings.Join(append([]string{command}, args...), " ")
+}
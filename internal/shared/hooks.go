@@ -0,0 +1,172 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+type HookStage string
+
+const (
+	HookPre  HookStage = "pre"
+	HookPost HookStage = "post"
+)
+
+// Hooks are shell commands run before and after a package is installed,
+// updated or removed
+type Hooks struct {
+	PreInstall  string `json:"pre_install,omitempty" yaml:"pre_install,omitempty" mapstructure:"pre_install"`
+	PostInstall string `json:"post_install,omitempty" yaml:"post_install,omitempty" mapstructure:"post_install"`
+	PreUpdate   string `json:"pre_update,omitempty" yaml:"pre_update,omitempty" mapstructure:"pre_update"`
+	PostUpdate  string `json:"post_update,omitempty" yaml:"post_update,omitempty" mapstructure:"post_update"`
+	PreRemove   string `json:"pre_remove,omitempty" yaml:"pre_remove,omitempty" mapstructure:"pre_remove"`
+	PostRemove  string `json:"post_remove,omitempty" yaml:"post_remove,omitempty" mapstructure:"post_remove"`
+}
+
+func (h Hooks) IsZero() bool {
+	return h == Hooks{}
+}
+
+// Merge returns h with every hook set in override replacing the one in h
+func (h Hooks) Merge(override Hooks) Hooks {
+	pick := func(a, b string) string {
+		if b != "" {
+			return b
+		}
+		return a
+	}
+	return Hooks{
+		PreInstall:  pick(h.PreInstall, override.PreInstall),
+		PostInstall: pick(h.PostInstall, override.PostInstall),
+		PreUpdate:   pick(h.PreUpdate, override.PreUpdate),
+		PostUpdate:  pick(h.PostUpdate, override.PostUpdate),
+		PreRemove:   pick(h.PreRemove, override.PreRemove),
+		PostRemove:  pick(h.PostRemove, override.PostRemove),
+	}
+}
+
+// Get returns the hook of the stage and action, e.g. post_install
+func (h Hooks) Get(stage HookStage, action PtermSpinnerStatus) string {
+	switch {
+	case stage == HookPre && action == PtermSpinnerInstall:
+		return h.PreInstall
+	case stage == HookPost && action == PtermSpinnerInstall:
+		return h.PostInstall
+	case stage == HookPre && action == PtermSpinnerUpdate:
+		return h.PreUpdate
+	case stage == HookPost && action == PtermSpinnerUpdate:
+		return h.PostUpdate
+	case stage == HookPre && action == PtermSpinnerRemove:
+		return h.PreRemove
+	case stage == HookPost && action == PtermSpinnerRemove:
+		return h.PostRemove
+	}
+	return ""
+}
+
+// RunHook runs the hook of the package for the stage and action with sh. The
+// package is described in PACKTRAK_* environment variables, and the hook runs
+// in path if it is a directory, e.g. a cloned repository.
+func RunHook(ctx context.Context, manager ManagerName, pkg Package, stage HookStage, action PtermSpinnerStatus, path string) error {
+	script := pkg.Hooks.Get(stage, action)
+	if script == "" {
+		return nil
+	}
+
+	version := pkg.LatestVersion
+	if action == PtermSpinnerRemove || version == "" {
+		version = pkg.Version
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", script)
+	cmd.Env = append(os.Environ(),
+		"PACKTRAK_MANAGER="+string(manager),
+		"PACKTRAK_ACTION="+string(action),
+		"PACKTRAK_PACKAGE="+pkg.Name,
+		"PACKTRAK_FULL_NAME="+pkg.FullName,
+		"PACKTRAK_VERSION="+version,
+		"PACKTRAK_OLD_VERSION="+pkg.Version,
+		"PACKTRAK_PATH="+path,
+	)
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		cmd.Dir = path
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s_%s hook '%s' failed: %w: %s", stage, action, script, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RunWithHooks runs the pre hook, fn and the post hook of the action. A
+// failing pre hook skips fn. path is resolved after fn, since it might not
+// exist before the package is installed.
+func RunWithHooks(ctx context.Context, manager ManagerName, pkg Package, action PtermSpinnerStatus, path func() string, fn func() error) error {
+	if err := RunHook(ctx, manager, pkg, HookPre, action, path()); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	return RunHook(ctx, manager, pkg, HookPost, action, path())
+}
+
+// SyncEach syncs the missing, updated and removed packages one at a time with
+// sync, which runs their hooks, and returns a failure for every package that
+// failed
+func SyncEach(ctx context.Context, manager ManagerName, missing, updated, removed []Package, sync func(ctx context.Context, pkg Package, action PtermSpinnerStatus) error) (failures []SyncFailure) {
+	each := func(pkgs []Package, action PtermSpinnerStatus) {
+		for _, pkg := range pkgs {
+			err := PtermSpinner(action, pkg.Name, func() error {
+				return sync(ctx, pkg, action)
+			})
+			if err != nil {
+				failures = append(failures, SyncFailure{Manager: manager, Name: pkg.Name, Action: action, Err: err})
+			}
+		}
+	}
+	each(missing, PtermSpinnerInstall)
+	each(updated, PtermSpinnerUpdate)
+	each(removed, PtermSpinnerRemove)
+	return
+}
+
+// SyncTransaction runs the pre hooks of the packages, the transaction for the
+// ones whose hooks succeeded, and then their post hooks. A failing transaction
+// fails every package in it.
+func SyncTransaction(ctx context.Context, manager ManagerName, pkgs []Package, action PtermSpinnerStatus, transaction func(ctx context.Context, pkgs []Package) error) (failures []SyncFailure) {
+	if len(pkgs) == 0 {
+		return
+	}
+
+	ready := []Package{}
+	for _, pkg := range pkgs {
+		if err := RunHook(ctx, manager, pkg, HookPre, action, ""); err != nil {
+			failures = append(failures, SyncFailure{Manager: manager, Name: pkg.Name, Action: action, Err: err})
+			continue
+		}
+		ready = append(ready, pkg)
+	}
+	if len(ready) == 0 {
+		return
+	}
+
+	fmt.Println("")
+	if err := transaction(ctx, ready); err != nil {
+		for _, pkg := range ready {
+			failures = append(failures, SyncFailure{Manager: manager, Name: pkg.Name, Action: action, Err: err})
+		}
+		return
+	}
+
+	for _, pkg := range ready {
+		if err := RunHook(ctx, manager, pkg, HookPost, action, ""); err != nil {
+			failures = append(failures, SyncFailure{Manager: manager, Name: pkg.Name, Action: action, Err: err})
+		}
+	}
+	return
+}
@@ -0,0 +1,88 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunWithHooks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+
+	pkg := Package{
+		Name:          "neovim",
+		Version:       "v0.9.4",
+		LatestVersion: "v0.9.5",
+		Hooks: Hooks{
+			PreUpdate:  "echo pre $PACKTRAK_OLD_VERSION >> " + out,
+			PostUpdate: "echo post $PACKTRAK_PACKAGE $PACKTRAK_VERSION $(pwd) >> " + out,
+		},
+	}
+	ran := false
+	err := RunWithHooks(ctx, "git", pkg, PtermSpinnerUpdate, func() string { return dir }, func() error {
+		ran = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	b, _ := os.ReadFile(out)
+	assert.Equal(t, "pre v0.9.4\npost neovim v0.9.5 "+dir+"\n", string(b))
+
+	pkg.Hooks = Hooks{PreInstall: "echo nope >&2; exit 3"}
+	ran = false
+	err = RunWithHooks(ctx, "git", pkg, PtermSpinnerInstall, func() string { return "" }, func() error {
+		ran = true
+		return nil
+	})
+	assert.ErrorContains(t, err, "pre_install hook")
+	assert.ErrorContains(t, err, "nope")
+	assert.False(t, ran, "a failing pre hook should skip the action")
+
+	pkg.Hooks = Hooks{PostRemove: "touch " + out + ".removed"}
+	err = RunWithHooks(ctx, "git", pkg, PtermSpinnerRemove, func() string { return "" }, func() error {
+		return errors.New("remove failed")
+	})
+	assert.EqualError(t, err, "remove failed")
+	assert.NoFileExists(t, out+".removed", "post hooks should not run when the action fails")
+}
+
+func TestHooksMerge(t *testing.T) {
+	config := Hooks{PostInstall: "a", PostUpdate: "b"}
+	assert.Equal(t, Hooks{PostInstall: "c", PostUpdate: "b"}, config.Merge(Hooks{PostInstall: "c"}))
+	assert.Equal(t, "b", config.Get(HookPost, PtermSpinnerUpdate))
+	assert.Equal(t, "", config.Get(HookPre, PtermSpinnerUpdate))
+}
+
+func TestSyncTransaction(t *testing.T) {
+	ctx := context.Background()
+	out := filepath.Join(t.TempDir(), "out")
+
+	pkgs := []Package{
+		{Name: "ripgrep", Hooks: Hooks{PostInstall: "echo $PACKTRAK_PACKAGE >> " + out}},
+		{Name: "neovim", Hooks: Hooks{PreInstall: "exit 1"}},
+		{Name: "zsh"},
+	}
+	var installed []string
+	failures := SyncTransaction(ctx, "dnf", pkgs, PtermSpinnerInstall, func(ctx context.Context, pkgs []Package) error {
+		for _, pkg := range pkgs {
+			installed = append(installed, pkg.Name)
+		}
+		return nil
+	})
+	assert.Equal(t, []string{"ripgrep", "zsh"}, installed, "a failing pre hook should leave the package out")
+	assert.Len(t, failures, 1)
+	assert.Equal(t, "neovim", failures[0].Name)
+	b, _ := os.ReadFile(out)
+	assert.Equal(t, "ripgrep\n", string(b))
+
+	failures = SyncTransaction(ctx, "dnf", pkgs[2:], PtermSpinnerInstall, func(ctx context.Context, pkgs []Package) error {
+		return errors.New("transaction failed")
+	})
+	assert.Len(t, failures, 1, "a failing transaction should fail every package in it")
+}
@@ -0,0 +1,86 @@
+package shared
+
+import (
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// A manifest entry can pin the version of a package with '@', e.g.
+// 'ripgrep@14.1.0' or 'golang.org/x/tools/gopls@v0.15.x'. Segments of the pin
+// that are 'x' or '*' match any value.
+
+// SplitPin splits a manifest entry into the package and its pin. Anything after
+// the last '@' containing '/' or ':' is not a pin, so that e.g.
+// 'git@github.com:user/repo.git' is left as it is.
+func SplitPin(entry string) (name string, pin string) {
+	idx := strings.LastIndex(entry, "@")
+	if idx <= 0 {
+		return entry, ""
+	}
+	pin = entry[idx+1:]
+	if pin == "" || strings.ContainsAny(pin, "/:") {
+		return entry, ""
+	}
+	return entry[:idx], pin
+}
+
+// Unpin returns the manifest entry without its pin
+func Unpin(entry string) string {
+	name, _ := SplitPin(entry)
+	return name
+}
+
+func UnpinAll(entries []string) []string {
+	return lo.Map(entries, func(entry string, _ int) string {
+		return Unpin(entry)
+	})
+}
+
+func isWildcard(segment string) bool {
+	return segment == "x" || segment == "X" || segment == "*"
+}
+
+// IsPinPattern reports if the pin matches more than one version
+func IsPinPattern(pin string) bool {
+	return lo.ContainsBy(strings.Split(pin, "."), isWildcard)
+}
+
+// MatchPin reports if the version satisfies the pin. A leading 'v' is ignored
+// on both.
+func MatchPin(pin string, version string) bool {
+	pSegments := strings.Split(strings.TrimPrefix(pin, "v"), ".")
+	vSegments := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	for idx, segment := range pSegments {
+		if isWildcard(segment) {
+			if idx == len(pSegments)-1 {
+				return len(vSegments) > idx
+			}
+			continue
+		}
+		if idx >= len(vSegments) || vSegments[idx] != segment {
+			return false
+		}
+	}
+	return len(pSegments) == len(vSegments)
+}
+
+// PinPrefix returns the pin up to the first wildcard, e.g. 'v0.15' for 'v0.15.x'
+func PinPrefix(pin string) string {
+	segments := strings.Split(pin, ".")
+	idx := lo.IndexOf(lo.Map(segments, func(s string, _ int) bool { return isWildcard(s) }), true)
+	if idx == -1 {
+		return pin
+	}
+	return strings.Join(segments[:idx], ".")
+}
+
+// PinGlob returns the pin with the wildcards replaced by '*'
+func PinGlob(pin string) string {
+	return strings.Join(lo.Map(strings.Split(pin, "."), func(s string, _ int) string {
+		if isWildcard(s) {
+			return "*"
+		}
+		return s
+	}), ".")
+}
@@ -0,0 +1,52 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPin(t *testing.T) {
+	tests := map[string][2]string{
+		"ripgrep@14.1.0":                      {"ripgrep", "14.1.0"},
+		"golang.org/x/tools/gopls@v0.15.x":    {"golang.org/x/tools/gopls", "v0.15.x"},
+		"https://github.com/user/repo@v1.0.0": {"https://github.com/user/repo", "v1.0.0"},
+		"git@github.com:user/repo.git":        {"git@github.com:user/repo.git", ""},
+		"git@github.com:user/repo.git@v2":     {"git@github.com:user/repo.git", "v2"},
+		"ripgrep":                             {"ripgrep", ""},
+		"ripgrep@":                            {"ripgrep@", ""},
+		"@scope":                              {"@scope", ""},
+	}
+	for entry, expected := range tests {
+		name, pin := SplitPin(entry)
+		assert.Equal(t, expected, [2]string{name, pin}, entry)
+	}
+}
+
+func TestMatchPin(t *testing.T) {
+	tests := []struct {
+		pin     string
+		version string
+		match   bool
+	}{
+		{"14.1.0", "14.1.0", true},
+		{"v14.1.0", "14.1.0", true},
+		{"14.1.0", "14.1.1", false},
+		{"14.1", "14.1.0", false},
+		{"v0.15.x", "v0.15.3", true},
+		{"v0.15.x", "v0.16.0", false},
+		{"v0.15.x", "v0.15", false},
+		{"1.x", "1.2.3", true},
+		{"1.*.3", "1.9.3", true},
+		{"1.*.3", "1.9.4", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.match, MatchPin(tt.pin, tt.version), "%s %s", tt.pin, tt.version)
+	}
+
+	assert.Equal(t, "v0.15", PinPrefix("v0.15.x"))
+	assert.Equal(t, "14.1.0", PinPrefix("14.1.0"))
+	assert.Equal(t, "14.*.*", PinGlob("14.x.*"))
+	assert.True(t, IsPinPattern("1.x"))
+	assert.False(t, IsPinPattern("1.2"))
+}
@@ -2,6 +2,8 @@ package shared
 
 import (
 	"fmt"
+	"io"
+	"sync"
 
 	"github.com/pterm/pterm"
 )
@@ -122,7 +124,27 @@ var PtermSpinnerStatusMsgs map[PtermSpinnerStatus]ptermMsgs = map[PtermSpinnerSt
 }
 
 func PtermSpinner(spinnerStatus PtermSpinnerStatus, itemName string, f func() error) error {
-	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf(PtermSpinnerStatusMsgs[spinnerStatus].Start, itemName))
+	return PtermSpinnerTo(nil, spinnerStatus, itemName, f)
+}
+
+// spinnerMu serializes starting spinners, since pterm keeps track of the active
+// ones in a global slice
+var spinnerMu sync.Mutex
+
+// PtermSpinnerTo is PtermSpinner writing to w, e.g. a line of a MultiPrinter.
+// A nil w writes to stdout. With pterm output disabled f runs without a
+// spinner.
+func PtermSpinnerTo(w io.Writer, spinnerStatus PtermSpinnerStatus, itemName string, f func() error) error {
+	if !pterm.Output {
+		return f()
+	}
+	printer := pterm.DefaultSpinner
+	if w != nil {
+		printer = *printer.WithWriter(w)
+	}
+	spinnerMu.Lock()
+	spinner, _ := printer.Start(fmt.Sprintf(PtermSpinnerStatusMsgs[spinnerStatus].Start, itemName))
+	spinnerMu.Unlock()
 	spinner.SuccessPrinter = &PtermInstalled
 	spinner.FailPrinter = &PtermRemoved
 	if err := f(); err != nil {
@@ -21,6 +21,15 @@ func GetPackage(name string, packages []Package) (Package, error) {
 	return Package{}, fmt.Errorf("package %s not found", name)
 }
 
+// FullNames returns the full names of the packages
+func FullNames(packages []Package) []string {
+	names := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		names = append(names, pkg.FullName)
+	}
+	return names
+}
+
 func ConfigKeyName(m ManagerName, key string) string {
 	return fmt.Sprintf("managers.%s.%s", m, key)
 }
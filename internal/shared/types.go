@@ -1,5 +1,10 @@
 package shared
 
+import (
+	"errors"
+	"time"
+)
+
 type (
 	CommandName string
 	ManagerName string
@@ -13,17 +18,37 @@ const (
 )
 
 type Package struct {
-	Name          string
-	FullName      string
-	Version       string
-	LatestVersion string
-	RepoUrl       string
+	Name          string `json:"name" yaml:"name"`
+	FullName      string `json:"full_name" yaml:"full_name"`
+	Version       string `json:"version" yaml:"version"`
+	LatestVersion string `json:"latest_version" yaml:"latest_version"`
+	RepoUrl       string `json:"repo_url" yaml:"repo_url"`
+	Pin           string `json:"pin,omitempty" yaml:"pin,omitempty"`
+	AssetUrl      string `json:"asset_url,omitempty" yaml:"asset_url,omitempty"`
+	Checksum      string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	// InstalledAt is when the installed version was synced, as recorded in the state
+	InstalledAt *time.Time `json:"installed_at,omitempty" yaml:"installed_at,omitempty"`
+	// Hooks are set from the manifest and config before a sync
+	Hooks Hooks `json:"-" yaml:"-"`
 }
 
 type Dependency struct {
-	Name     string
-	FullName string
+	Name     string `json:"name" yaml:"name"`
+	FullName string `json:"full_name" yaml:"full_name"`
 	// Version       string
 	// LatestVersion string
 	// RepoUrl       string
 }
+
+type SyncFailure struct {
+	Manager ManagerName
+	Name    string
+	Action  PtermSpinnerStatus
+	Err     error
+}
+
+// ErrSyncPending is wrapped by the error of a SyncFailure for a change that is
+// left for later, e.g. until the package it belongs to is installed, or until
+// the user upgrades the whole system. Pending changes are not recorded in the
+// history.
+var ErrSyncPending = errors.New("pending")
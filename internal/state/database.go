@@ -10,10 +10,31 @@ import (
 )
 
 type PackageState struct {
-	ID        uint `gorm:"primarykey"`
-	CreatedAt time.Time
-	Manager   string
-	Package   string
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	Manager     string
+	Package     string
+	Name        string
+	Version     string
+	RepoUrl     string
+	InstalledAt time.Time
+}
+
+func (ps PackageState) toPackage() shared.Package {
+	pkg := shared.Package{
+		Name:     ps.Name,
+		FullName: ps.Package,
+		Version:  ps.Version,
+		RepoUrl:  ps.RepoUrl,
+	}
+	if pkg.Name == "" {
+		pkg.Name = ps.Package
+	}
+	if !ps.InstalledAt.IsZero() {
+		installedAt := ps.InstalledAt
+		pkg.InstalledAt = &installedAt
+	}
+	return pkg
 }
 
 type DependencyState struct {
@@ -27,9 +48,13 @@ type StateFace interface {
 	Begin(ctx context.Context) StateFace
 	gorm.TxCommitter
 	UpdatePackageState(ctx context.Context, manager shared.ManagerName, packages []shared.Package) error
-	GetPackageState(ctx context.Context, manager shared.ManagerName) (packages []string, err error)
+	GetPackageState(ctx context.Context, manager shared.ManagerName) (packages []shared.Package, err error)
 	UpdateDependencyState(ctx context.Context, manager shared.ManagerName, deps []shared.Dependency) error
 	GetDependencyState(ctx context.Context, manager shared.ManagerName) (dependencies []string, err error)
+	AddHistory(ctx context.Context, entries []HistoryEntry) error
+	GetHistory(ctx context.Context, manager shared.ManagerName, since time.Time) (entries []HistoryEntry, err error)
+	SetHistoryVersion(ctx context.Context, id uint, version string) error
+	Restore(ctx context.Context, ss SnapshotState) error
 }
 
 type State struct {
@@ -43,10 +68,13 @@ func (s State) Begin(ctx context.Context) StateFace {
 	}
 }
 
+// UpdatePackageState replaces the package state of the manager. Packages
+// already in the state keep their install time unless the version changed.
 func (s State) UpdatePackageState(ctx context.Context, manager shared.ManagerName, packages []shared.Package) error {
-	currentPkgs, err := s.GetPackageState(ctx, manager)
-	if err != nil {
-		return err
+	currentPkgs := []PackageState{}
+	result := s.db.WithContext(ctx).Where("manager LIKE ?", manager).Find(&currentPkgs)
+	if result.Error != nil {
+		return result.Error
 	}
 
 	pkgNames := []string{}
@@ -55,26 +83,51 @@ func (s State) UpdatePackageState(ctx context.Context, manager shared.ManagerNam
 	}
 
 	for _, pkg := range currentPkgs {
-		if !lo.Contains(pkgNames, pkg) {
-			result := s.db.WithContext(ctx).Where("manager LIKE ? AND package = ?", manager, pkg).Delete(&PackageState{Package: pkg})
+		if !lo.Contains(pkgNames, pkg.Package) {
+			result := s.db.WithContext(ctx).Delete(&pkg)
 			if result.Error != nil {
 				return result.Error
 			}
 		}
 	}
 
-	for _, pkg := range pkgNames {
-		if !lo.Contains(currentPkgs, pkg) {
-			result := s.db.WithContext(ctx).Create(&PackageState{Package: pkg, Manager: string(manager)})
+	now := time.Now()
+	for _, pkg := range lo.UniqBy(packages, func(p shared.Package) string { return p.FullName }) {
+		current, found := lo.Find(currentPkgs, func(ps PackageState) bool {
+			return ps.Package == pkg.FullName
+		})
+		if !found {
+			result := s.db.WithContext(ctx).Create(&PackageState{
+				Package:     pkg.FullName,
+				Manager:     string(manager),
+				Name:        pkg.Name,
+				Version:     pkg.Version,
+				RepoUrl:     pkg.RepoUrl,
+				InstalledAt: now,
+			})
 			if result.Error != nil {
 				return result.Error
 			}
+			continue
+		}
+
+		updated := current
+		updated.Name = lo.Ternary(pkg.Name != "", pkg.Name, current.Name)
+		updated.RepoUrl = lo.Ternary(pkg.RepoUrl != "", pkg.RepoUrl, current.RepoUrl)
+		if pkg.Version != "" && pkg.Version != current.Version {
+			updated.Version = pkg.Version
+			updated.InstalledAt = now
+		}
+		if updated != current {
+			if result := s.db.WithContext(ctx).Save(&updated); result.Error != nil {
+				return result.Error
+			}
 		}
 	}
 	return nil
 }
 
-func (s State) GetPackageState(ctx context.Context, manager shared.ManagerName) (packages []string, err error) {
+func (s State) GetPackageState(ctx context.Context, manager shared.ManagerName) (packages []shared.Package, err error) {
 	packageStates := []PackageState{}
 
 	result := s.db.WithContext(ctx).Where("manager LIKE ?", manager).Find(&packageStates)
@@ -83,7 +136,7 @@ func (s State) GetPackageState(ctx context.Context, manager shared.ManagerName)
 	}
 
 	for _, pkg := range packageStates {
-		packages = append(packages, pkg.Package)
+		packages = append(packages, pkg.toPackage())
 	}
 
 	return packages, err
@@ -144,6 +197,12 @@ func (s State) Rollback() error {
 }
 
 func NewState(db *gorm.DB) (State, error) {
-	err := db.AutoMigrate(&PackageState{}, &DependencyState{})
+	err := db.AutoMigrate(&PackageState{}, &DependencyState{}, &HistoryEntry{})
+	if err != nil {
+		return State{}, err
+	}
+
+	// Packages tracked before the install time was recorded
+	err = db.Model(&PackageState{}).Where("installed_at IS NULL").Update("installed_at", gorm.Expr("created_at")).Error
 	return State{db: db}, err
 }
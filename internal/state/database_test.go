@@ -0,0 +1,53 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageStateVersions(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+
+	require.NoError(t, s.UpdatePackageState(ctx, "go", []shared.Package{
+		{Name: "gopls", FullName: "golang.org/x/tools/gopls", Version: "v0.14.0", RepoUrl: "https://github.com/golang/tools"},
+		{Name: "dlv", FullName: "github.com/go-delve/delve/cmd/dlv", Version: "v1.21.0"},
+	}))
+
+	pkgs, err := s.GetPackageState(ctx, "go")
+	require.NoError(t, err)
+	require.Len(t, pkgs, 2)
+	gopls, err := shared.GetPackage("gopls", pkgs)
+	require.NoError(t, err)
+	assert.Equal(t, "v0.14.0", gopls.Version)
+	assert.Equal(t, "https://github.com/golang/tools", gopls.RepoUrl)
+	require.NotNil(t, gopls.InstalledAt)
+	installedAt := *gopls.InstalledAt
+
+	// Same version keeps the install time, a new version replaces it and
+	// an empty version keeps the recorded one
+	require.NoError(t, s.UpdatePackageState(ctx, "go", []shared.Package{
+		{Name: "gopls", FullName: "golang.org/x/tools/gopls", Version: "v0.14.0"},
+		{Name: "dlv", FullName: "github.com/go-delve/delve/cmd/dlv", Version: "v1.22.0"},
+	}))
+	pkgs, err = s.GetPackageState(ctx, "go")
+	require.NoError(t, err)
+	gopls, _ = shared.GetPackage("gopls", pkgs)
+	assert.Equal(t, installedAt, *gopls.InstalledAt)
+	assert.Equal(t, "https://github.com/golang/tools", gopls.RepoUrl)
+	dlv, _ := shared.GetPackage("dlv", pkgs)
+	assert.Equal(t, "v1.22.0", dlv.Version)
+
+	require.NoError(t, s.UpdatePackageState(ctx, "go", []shared.Package{
+		{FullName: "golang.org/x/tools/gopls"},
+	}))
+	pkgs, err = s.GetPackageState(ctx, "go")
+	require.NoError(t, err)
+	require.Len(t, pkgs, 1)
+	assert.Equal(t, "v0.14.0", pkgs[0].Version)
+	assert.Equal(t, "gopls", pkgs[0].Name)
+}
@@ -0,0 +1,64 @@
+package state
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+)
+
+type HistoryOutcome string
+
+const (
+	HistorySuccess HistoryOutcome = "success"
+	HistoryFailed  HistoryOutcome = "failed"
+)
+
+type HistoryObjectType string
+
+const (
+	HistoryPackage    HistoryObjectType = "package"
+	HistoryDependency HistoryObjectType = "dependency"
+)
+
+// HistoryEntry records a single install, update or remove done by a sync
+type HistoryEntry struct {
+	ID         uint                      `gorm:"primarykey" json:"-" yaml:"-"`
+	CreatedAt  time.Time                 `gorm:"index" json:"time" yaml:"time"`
+	Manager    string                    `gorm:"index" json:"manager" yaml:"manager"`
+	ObjectType HistoryObjectType         `json:"type" yaml:"type"`
+	Name       string                    `json:"name" yaml:"name"`
+	Action     shared.PtermSpinnerStatus `json:"action" yaml:"action"`
+	OldVersion string                    `json:"old_version,omitempty" yaml:"old_version,omitempty"`
+	NewVersion string                    `json:"new_version,omitempty" yaml:"new_version,omitempty"`
+	Outcome    HistoryOutcome            `json:"outcome" yaml:"outcome"`
+	Error      string                    `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (HistoryEntry) TableName() string {
+	return "history"
+}
+
+func (s State) AddHistory(ctx context.Context, entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Create(&entries).Error
+}
+
+// GetHistory returns the entries since the given time, oldest first. An empty
+// manager returns the entries of all managers.
+func (s State) GetHistory(ctx context.Context, manager shared.ManagerName, since time.Time) (entries []HistoryEntry, err error) {
+	query := s.db.WithContext(ctx).Where("created_at >= ?", since)
+	if manager != "" {
+		query = query.Where("manager = ?", manager)
+	}
+	err = query.Order("created_at, id").Find(&entries).Error
+	return
+}
+
+// SetHistoryVersion sets the new version of an entry, for installs whose
+// version is only known after the sync
+func (s State) SetHistoryVersion(ctx context.Context, id uint, version string) error {
+	return s.db.WithContext(ctx).Model(&HistoryEntry{ID: id}).Update("new_version", version).Error
+}
@@ -0,0 +1,73 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newTestState(t *testing.T) State {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	s, err := NewState(db)
+	require.NoError(t, err)
+	return s
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+
+	old := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, s.AddHistory(ctx, []HistoryEntry{
+		{CreatedAt: old, Manager: "dnf", ObjectType: HistoryPackage, Name: "htop", Action: shared.PtermSpinnerInstall, NewVersion: "3.2.2", Outcome: HistorySuccess},
+	}))
+	require.NoError(t, s.AddHistory(ctx, []HistoryEntry{
+		{Manager: "dnf", ObjectType: HistoryPackage, Name: "htop", Action: shared.PtermSpinnerUpdate, OldVersion: "3.2.2", NewVersion: "3.3.0", Outcome: HistorySuccess},
+		{Manager: "go", ObjectType: HistoryPackage, Name: "gopls", Action: shared.PtermSpinnerInstall, Outcome: HistoryFailed, Error: "exit status 1"},
+	}))
+	require.NoError(t, s.AddHistory(ctx, nil))
+
+	all, err := s.GetHistory(ctx, "", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"htop", "htop", "gopls"}, names(all))
+
+	recent, err := s.GetHistory(ctx, "", time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"htop", "gopls"}, names(recent))
+
+	dnf, err := s.GetHistory(ctx, "dnf", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, dnf, 2)
+	assert.Equal(t, "3.2.2", dnf[1].OldVersion)
+	assert.Equal(t, "3.3.0", dnf[1].NewVersion)
+
+	require.NoError(t, s.AddHistory(ctx, []HistoryEntry{
+		{Manager: "my_manager", ObjectType: HistoryPackage, Name: "tool", Action: shared.PtermSpinnerInstall, Outcome: HistorySuccess},
+	}))
+	mine, err := s.GetHistory(ctx, "my_manager", time.Time{})
+	require.NoError(t, err)
+	require.Len(t, mine, 1)
+	wildcard, err := s.GetHistory(ctx, "my%", time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, wildcard, "manager names should not be patterns")
+
+	require.NoError(t, s.SetHistoryVersion(ctx, mine[0].ID, "1.2.0"))
+	mine, err = s.GetHistory(ctx, "my_manager", time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0", mine[0].NewVersion)
+}
+
+func names(entries []HistoryEntry) []string {
+	n := []string{}
+	for _, e := range entries {
+		n = append(n, e.Name)
+	}
+	return n
+}
@@ -49,7 +49,7 @@ func Rotate(rotations int) error {
 }
 
 func copyStatefile() error {
-	timeStr := time.Now().Format("20060102T150405")
+	timeStr := time.Now().Format(snapshotTimeFormat)
 	stateRotFile := path.Join(config.DataDir, fmt.Sprintf("state.%s.db", timeStr))
 
 	data, err := os.ReadFile(config.StateFile)
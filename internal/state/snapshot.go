@@ -0,0 +1,130 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+const snapshotTimeFormat = "20060102T150405"
+
+// Snapshot is a copy of the state database made by Rotate
+type Snapshot struct {
+	Name string    `json:"name" yaml:"name"`
+	File string    `json:"file" yaml:"file"`
+	Time time.Time `json:"time" yaml:"time"`
+}
+
+// ListSnapshots returns the rotated state files in DataDir, newest first
+func ListSnapshots() ([]Snapshot, error) {
+	files, err := filepath.Glob(filepath.Join(config.DataDir, "state.*.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := []Snapshot{}
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "state."), ".db")
+		t, err := time.ParseInLocation(snapshotTimeFormat, name, time.Local)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{Name: name, File: file, Time: t})
+	}
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		return b.Time.Compare(a.Time)
+	})
+	return snapshots, nil
+}
+
+// FindSnapshot returns the snapshot with the given name. The file name of
+// the snapshot is accepted as well.
+func FindSnapshot(name string) (Snapshot, error) {
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	for _, s := range snapshots {
+		if s.Name == name || filepath.Base(s.File) == filepath.Base(name) {
+			return s, nil
+		}
+	}
+	return Snapshot{}, fmt.Errorf("snapshot '%s' not found", name)
+}
+
+// SnapshotState is the package and dependency state stored in a snapshot
+type SnapshotState struct {
+	Packages     []PackageState
+	Dependencies []DependencyState
+}
+
+// GetPackages returns the package names of the manager
+func (ss SnapshotState) GetPackages(manager shared.ManagerName) []string {
+	return lo.FilterMap(ss.Packages, func(p PackageState, _ int) (string, bool) {
+		return p.Package, p.Manager == string(manager)
+	})
+}
+
+// GetDependencies returns the dependency names of the manager
+func (ss SnapshotState) GetDependencies(manager shared.ManagerName) []string {
+	return lo.FilterMap(ss.Dependencies, func(d DependencyState, _ int) (string, bool) {
+		return d.Dependency, d.Manager == string(manager)
+	})
+}
+
+// LoadSnapshot reads the state of the snapshot. The snapshot is opened read
+// only and closed before returning.
+func LoadSnapshot(snapshot Snapshot) (ss SnapshotState, err error) {
+	if _, err = os.Stat(snapshot.File); err != nil {
+		return
+	}
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=ro", snapshot.File)), &gorm.Config{})
+	if err != nil {
+		return
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return
+	}
+	defer sqlDB.Close()
+
+	if err = db.Find(&ss.Packages).Error; err != nil {
+		return
+	}
+	err = db.Find(&ss.Dependencies).Error
+	return
+}
+
+// Restore replaces the package and dependency state with the one of a
+// snapshot. The history is kept.
+func (s State) Restore(ctx context.Context, ss SnapshotState) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&PackageState{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&DependencyState{}).Error; err != nil {
+			return err
+		}
+		if len(ss.Packages) > 0 {
+			if err := tx.Create(&ss.Packages).Error; err != nil {
+				return err
+			}
+		}
+		if len(ss.Dependencies) > 0 {
+			if err := tx.Create(&ss.Dependencies).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,59 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/lucas-ingemar/packtrak/internal/config"
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	config.DataDir = t.TempDir()
+
+	file := filepath.Join(config.DataDir, "state.20240102T030405.db")
+	db, err := gorm.Open(sqlite.Open(file), &gorm.Config{})
+	require.NoError(t, err)
+	old, err := NewState(db)
+	require.NoError(t, err)
+	require.NoError(t, old.UpdatePackageState(ctx, "dnf", []shared.Package{{FullName: "htop"}, {FullName: "vim"}}))
+	require.NoError(t, old.UpdateDependencyState(ctx, "dnf", []shared.Dependency{{FullName: "copr/repo"}}))
+	sqlDB, _ := db.DB()
+	require.NoError(t, sqlDB.Close())
+
+	snapshots, err := ListSnapshots()
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, "20240102T030405", snapshots[0].Name)
+
+	snapshot, err := FindSnapshot("state.20240102T030405.db")
+	require.NoError(t, err)
+	ss, err := LoadSnapshot(snapshot)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"htop", "vim"}, ss.GetPackages("dnf"))
+	assert.Empty(t, ss.GetPackages("go"))
+
+	s := newTestState(t)
+	require.NoError(t, s.UpdatePackageState(ctx, "dnf", []shared.Package{{FullName: "emacs"}}))
+	require.NoError(t, s.AddHistory(ctx, []HistoryEntry{{Manager: "dnf", Name: "emacs", Outcome: HistorySuccess}}))
+	require.NoError(t, s.Restore(ctx, ss))
+
+	pkgs, err := s.GetPackageState(ctx, "dnf")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"htop", "vim"}, shared.FullNames(pkgs))
+	deps, err := s.GetDependencyState(ctx, "dnf")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"copr/repo"}, deps)
+	history, err := s.GetHistory(ctx, "", snapshot.Time.AddDate(-1, 0, 0))
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	_, err = FindSnapshot("19990101T000000")
+	assert.Error(t, err)
+}
@@ -1,6 +1,13 @@
 package status
 
-import "github.com/lucas-ingemar/packtrak/internal/shared"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+)
 
 type StatusState string
 
@@ -11,12 +18,64 @@ const (
 	StatusRemoved StatusState = "removed"
 )
 
+// Status holds what every manager has to sync. A Status created with NewStatus
+// is safe for concurrent use, and copies of it share the same data.
 type Status struct {
+	mu           *sync.RWMutex
 	packages     map[shared.ManagerName]PackageStatus
 	dependencies map[shared.ManagerName]DependenciesStatus
+	stale        map[shared.ManagerName]error
+}
+
+func NewStatus() Status {
+	return Status{
+		mu:           &sync.RWMutex{},
+		packages:     map[shared.ManagerName]PackageStatus{},
+		dependencies: map[shared.ManagerName]DependenciesStatus{},
+		stale:        map[shared.ManagerName]error{},
+	}
+}
+
+// lock and rlock return the unlock function, and do nothing for a zero Status
+func (s Status) lock() func() {
+	if s.mu == nil {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (s Status) rlock() func() {
+	if s.mu == nil {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// AddStale marks the packages of the manager as read from the state, since
+// listing them failed with err
+func (s *Status) AddStale(manager shared.ManagerName, err error) {
+	defer s.lock()()
+	if s.stale == nil {
+		s.stale = map[shared.ManagerName]error{}
+	}
+	s.stale[manager] = err
+}
+
+// StaleErr returns why the packages of some managers are read from the state,
+// or nil if all were listed from the system
+func (s Status) StaleErr() error {
+	defer s.rlock()()
+	errs := []error{}
+	for manager, err := range s.stale {
+		errs = append(errs, fmt.Errorf("%s: %w", manager, err))
+	}
+	return errors.Join(errs...)
 }
 
 func (s *Status) AddDependencies(manager shared.ManagerName, status DependenciesStatus) {
+	defer s.lock()()
 	if s.dependencies == nil {
 		s.dependencies = map[shared.ManagerName]DependenciesStatus{}
 	}
@@ -24,6 +83,7 @@ func (s *Status) AddDependencies(manager shared.ManagerName, status Dependencies
 }
 
 func (s *Status) AddPackages(manager shared.ManagerName, status PackageStatus) {
+	defer s.lock()()
 	if s.packages == nil {
 		s.packages = map[shared.ManagerName]PackageStatus{}
 	}
@@ -31,6 +91,7 @@ func (s *Status) AddPackages(manager shared.ManagerName, status PackageStatus) {
 }
 
 func (s Status) CountUpdatedDependencies() (totUpdatedDeps int) {
+	defer s.rlock()()
 	for _, dep := range s.dependencies {
 		totUpdatedDeps += len(dep.Missing)
 		totUpdatedDeps += len(dep.Updated)
@@ -40,6 +101,7 @@ func (s Status) CountUpdatedDependencies() (totUpdatedDeps int) {
 }
 
 func (s Status) CountUpdatedPackages() (totUpdatedPkgs int) {
+	defer s.rlock()()
 	for _, pkg := range s.packages {
 		totUpdatedPkgs += len(pkg.Missing)
 		totUpdatedPkgs += len(pkg.Updated)
@@ -49,6 +111,7 @@ func (s Status) CountUpdatedPackages() (totUpdatedPkgs int) {
 }
 
 func (s Status) GetDependenciesByStatus(manager shared.ManagerName, status StatusState) []shared.Dependency {
+	defer s.rlock()()
 	switch status {
 	case StatusSynced:
 		return s.dependencies[manager].Synced
@@ -63,6 +126,7 @@ func (s Status) GetDependenciesByStatus(manager shared.ManagerName, status Statu
 }
 
 func (s Status) GetPackagesByStatus(manager shared.ManagerName, status StatusState) []shared.Package {
+	defer s.rlock()()
 	switch status {
 	case StatusSynced:
 		return s.packages[manager].Synced
@@ -77,14 +141,17 @@ func (s Status) GetPackagesByStatus(manager shared.ManagerName, status StatusSta
 }
 
 func (s Status) GetDependencies(manager shared.ManagerName) DependenciesStatus {
+	defer s.rlock()()
 	return s.dependencies[manager]
 }
 
 func (s Status) GetPackages(manager shared.ManagerName) PackageStatus {
+	defer s.rlock()()
 	return s.packages[manager]
 }
 
 func (s Status) GetUpdatedDependenciesState(managers []shared.ManagerName) map[shared.ManagerName][]shared.Dependency {
+	defer s.rlock()()
 	state := map[shared.ManagerName][]shared.Dependency{}
 	for _, m := range managers {
 		state[m] = []shared.Dependency{}
@@ -95,27 +162,78 @@ func (s Status) GetUpdatedDependenciesState(managers []shared.ManagerName) map[s
 	return state
 }
 
+// GetUpdatedPackageState returns the packages as they are after a sync, with
+// Version set to the version that gets installed
 func (s Status) GetUpdatedPackageState(managers []shared.ManagerName) map[shared.ManagerName][]shared.Package {
+	defer s.rlock()()
 	pkgsState := map[shared.ManagerName][]shared.Package{}
 	for _, m := range managers {
 		pkgsState[m] = []shared.Package{}
 		pkgsState[m] = append(pkgsState[m], s.packages[m].Synced...)
-		pkgsState[m] = append(pkgsState[m], s.packages[m].Updated...)
-		pkgsState[m] = append(pkgsState[m], s.packages[m].Missing...)
+		for _, pkg := range slices.Concat(s.packages[m].Updated, s.packages[m].Missing) {
+			if pkg.LatestVersion != "" {
+				pkg.Version = pkg.LatestVersion
+			}
+			pkgsState[m] = append(pkgsState[m], pkg)
+		}
 	}
 	return pkgsState
 }
 
+// Report returns the public serialization schema of the status for the
+// given managers, in the given order.
+func (s Status) Report(managers []shared.ManagerName) Report {
+	defer s.rlock()()
+	report := Report{Managers: []ManagerReport{}}
+	for _, m := range managers {
+		deps := s.dependencies[m]
+		pkgs := s.packages[m]
+		report.Managers = append(report.Managers, ManagerReport{
+			Name: m,
+			Dependencies: DependenciesStatus{
+				Synced:  orEmpty(deps.Synced),
+				Updated: orEmpty(deps.Updated),
+				Missing: orEmpty(deps.Missing),
+				Removed: orEmpty(deps.Removed),
+			},
+			Packages: PackageStatus{
+				Synced:  orEmpty(pkgs.Synced),
+				Updated: orEmpty(pkgs.Updated),
+				Missing: orEmpty(pkgs.Missing),
+				Removed: orEmpty(pkgs.Removed),
+			},
+		})
+	}
+	return report
+}
+
 type DependenciesStatus struct {
-	Synced  []shared.Dependency
-	Updated []shared.Dependency
-	Missing []shared.Dependency
-	Removed []shared.Dependency
+	Synced  []shared.Dependency `json:"synced" yaml:"synced"`
+	Updated []shared.Dependency `json:"updated" yaml:"updated"`
+	Missing []shared.Dependency `json:"missing" yaml:"missing"`
+	Removed []shared.Dependency `json:"removed" yaml:"removed"`
 }
 
 type PackageStatus struct {
-	Synced  []shared.Package
-	Updated []shared.Package
-	Missing []shared.Package
-	Removed []shared.Package
+	Synced  []shared.Package `json:"synced" yaml:"synced"`
+	Updated []shared.Package `json:"updated" yaml:"updated"`
+	Missing []shared.Package `json:"missing" yaml:"missing"`
+	Removed []shared.Package `json:"removed" yaml:"removed"`
+}
+
+type Report struct {
+	Managers []ManagerReport `json:"managers" yaml:"managers"`
+}
+
+type ManagerReport struct {
+	Name         shared.ManagerName `json:"name" yaml:"name"`
+	Dependencies DependenciesStatus `json:"dependencies" yaml:"dependencies"`
+	Packages     PackageStatus      `json:"packages" yaml:"packages"`
+}
+
+func orEmpty[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
 }
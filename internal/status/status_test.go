@@ -0,0 +1,49 @@
+package status
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/lucas-ingemar/packtrak/internal/shared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	s := Status{}
+	s.AddPackages("go", PackageStatus{
+		Updated: []shared.Package{{Name: "gopls", FullName: "golang.org/x/tools/gopls", Version: "v0.14.0", LatestVersion: "v0.15.0"}},
+	})
+
+	report := s.Report([]shared.ManagerName{"go", "dnf"})
+	assert.Len(t, report.Managers, 2, "one entry per manager")
+	assert.Equal(t, shared.ManagerName("go"), report.Managers[0].Name, "manager order")
+	assert.Equal(t, "v0.15.0", report.Managers[0].Packages.Updated[0].LatestVersion, "latest version")
+	assert.NotNil(t, report.Managers[1].Packages.Synced, "empty lists should not be nil")
+
+	b, err := json.Marshal(report.Managers[1])
+	assert.Nil(t, err, "should be no error")
+	assert.JSONEq(t, `{"name":"dnf","dependencies":{"synced":[],"updated":[],"missing":[],"removed":[]},"packages":{"synced":[],"updated":[],"missing":[],"removed":[]}}`, string(b), "serialization schema")
+}
+
+func TestStatusConcurrent(t *testing.T) {
+	s := NewStatus()
+	managers := []shared.ManagerName{"dnf", "flatpak", "git", "github", "go"}
+
+	var wg sync.WaitGroup
+	for _, m := range managers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.AddDependencies(m, DependenciesStatus{Missing: []shared.Dependency{{Name: "dep"}}})
+			s.AddPackages(m, PackageStatus{Missing: []shared.Package{{Name: "pkg"}}})
+			_ = s.CountUpdatedPackages()
+			_ = s.Report(managers)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, s.CountUpdatedPackages())
+	assert.Equal(t, 5, s.CountUpdatedDependencies())
+	assert.Nil(t, s.StaleErr())
+}